@@ -17,11 +17,15 @@ type ConfOpt[T any] struct {
 	originalURI string
 	flagName    string
 	ParserConf  mapstructure.DecoderConfig
-	parsedURL   *url.URL
-	reader      reader.ConfReader
-	decoder     decoder.ConfDecoder
-	rawData     []byte
-	parsedData  map[string]any
+	// ResolveTimeout bounds resolving the ${name:ref} references of a parse,
+	// DefaultResolveTimeout when zero
+	ResolveTimeout time.Duration
+	parsedURL      *url.URL
+	reader         reader.ConfReader
+	decoder        decoder.ConfDecoder
+	rawData        []byte
+	meta           reader.ContentMeta
+	parsedData     map[string]any
 }
 
 func New[T any](flag string, uris ...string) *ConfOpt[T] {
@@ -62,7 +66,10 @@ func (c *ConfOpt[T]) parseUri() error {
 			return fmt.Errorf("create reader: %w", err)
 		}
 	}
+	return nil
+}
 
+func (c *ConfOpt[T]) resolveDecoder() error {
 	format, err := c.getFormat()
 	if err != nil {
 		return fmt.Errorf("determine format: %w", err)
@@ -73,24 +80,47 @@ func (c *ConfOpt[T]) parseUri() error {
 	return nil
 }
 
+// getFormat resolves the decoder format. Hints from the URI take precedence
+// over content metadata reported by the reader.
 func (c *ConfOpt[T]) getFormat() (decoder.Format, error) {
 	if c.parsedURL == nil {
 		return "", fmt.Errorf("URI not parsed")
 	}
 	if ext := filepath.Ext(c.parsedURL.Path); ext != "" {
-		return decoder.FormatFromExtension(ext)
+		if format, err := decoder.FormatFromExtension(ext); err == nil {
+			return format, nil
+		}
 	}
 	if ct := c.parsedURL.Query().Get("content-type"); ct != "" {
 		return decoder.FormatFromMIME(ct)
 	}
-	return "", fmt.Errorf("cannot determine format from URI: %s", c.uri)
+	if c.meta.MIMEType != "" {
+		if format, err := decoder.FormatFromMIME(c.meta.MIMEType); err == nil {
+			return format, nil
+		}
+	}
+	if c.meta.Extension != "" {
+		if format, err := decoder.FormatFromExtension(c.meta.Extension); err == nil {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine format from URI or content metadata: %s", c.uri)
 }
 
 func (c *ConfOpt[T]) readData(ctx context.Context) error {
 	if c.reader == nil {
 		return fmt.Errorf("reader not initialized")
 	}
-	data, err := c.reader.Read(ctx)
+	var (
+		data []byte
+		meta reader.ContentMeta
+		err  error
+	)
+	if mr, ok := c.reader.(reader.MetaReader); ok {
+		data, meta, err = mr.ReadMeta(ctx)
+	} else {
+		data, err = c.reader.Read(ctx)
+	}
 	if err != nil {
 		return fmt.Errorf("read configuration: %w", err)
 	}
@@ -98,6 +128,21 @@ func (c *ConfOpt[T]) readData(ctx context.Context) error {
 		return fmt.Errorf("empty configuration data")
 	}
 	c.rawData = data
+	c.meta = meta
+	return nil
+}
+
+// readValues reads structured values that need no decoder.
+func (c *ConfOpt[T]) readValues(ctx context.Context, mr reader.MapReader) error {
+	values, err := mr.ReadMap(ctx)
+	if err != nil {
+		return fmt.Errorf("read configuration: %w", err)
+	}
+	if values == nil {
+		values = make(map[string]any)
+	}
+	c.rawData = nil
+	c.parsedData = values
 	return nil
 }
 
@@ -120,24 +165,49 @@ func (c *ConfOpt[T]) loadAndDecode(ctx context.Context) error {
 	if err := c.parseUri(); err != nil {
 		return err
 	}
+	if mr, ok := c.reader.(reader.MapReader); ok && mr.Structured() {
+		return c.readValues(ctx, mr)
+	}
 	if err := c.readData(ctx); err != nil {
 		return err
 	}
+	if err := c.resolveDecoder(); err != nil {
+		return err
+	}
 	return c.decode()
 }
 
-func (c *ConfOpt[T]) decodeToStruct(result *T) error {
+func (c *ConfOpt[T]) decodeToStruct(ctx context.Context, result *T) error {
+	data, err := c.resolveReferences(ctx)
+	if err != nil {
+		return err
+	}
+
 	c.ParserConf.Result = result
 	dec, err := mapstructure.NewDecoder(&c.ParserConf)
 	if err != nil {
 		return fmt.Errorf("create decoder: %w", err)
 	}
-	if err := dec.Decode(c.parsedData); err != nil {
+	if err := dec.Decode(data); err != nil {
 		return fmt.Errorf("decode to struct: %w", err)
 	}
 	return nil
 }
 
+// resolveReferences returns the parsed data with its ${name:ref} references
+// resolved, each one once, within the resolve timeout. The parsed data keeps
+// the references so that the next parse resolves them again.
+func (c *ConfOpt[T]) resolveReferences(ctx context.Context) (any, error) {
+	timeout := c.ResolveTimeout
+	if timeout <= 0 {
+		timeout = DefaultResolveTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return renderValueReferences(ctx, c.parsedData, make(map[string]string))
+}
+
 func (c *ConfOpt[T]) Parse() (*T, error) { return c.ParseCtx(context.Background()) }
 
 func (c *ConfOpt[T]) ParseCtx(ctx context.Context) (*T, error) {
@@ -156,19 +226,20 @@ func (c *ConfOpt[T]) parseCtx(ctx context.Context, result *T) error {
 		if c.parsedData == nil {
 			c.parsedData = make(map[string]any)
 		}
-		return c.decodeToStruct(result)
+		return c.decodeToStruct(ctx, result)
 	}
 
 	if err := c.loadAndDecode(ctx); err != nil {
 		return err
 	}
 	c.mergeFlagValues()
-	return c.decodeToStruct(result)
+	return c.decodeToStruct(ctx, result)
 }
 
 type ConfEvent[T any] struct {
 	SourceURI string    `json:"source_uri"`
 	Timestamp time.Time `json:"timestamp"`
+	Revision  string    `json:"revision,omitempty"`
 	Error     error     `json:"error,omitempty"`
 	Config    *T        `json:"config,omitempty"`
 }
@@ -212,15 +283,15 @@ func (c *ConfOpt[T]) SubscribeCtx(ctx context.Context) (<-chan *ConfEvent[T], er
 				confEvent := &ConfEvent[T]{
 					SourceURI: event.SourceURI,
 					Timestamp: event.Timestamp,
+					Revision:  event.Revision,
 					Error:     event.Error,
 				}
 				if event.IsValid() {
-					c.rawData = event.Data
-					if err := c.decode(); err != nil {
+					if err := c.applyEvent(event); err != nil {
 						confEvent.Error = err
 					} else {
 						var result T
-						if err := c.decodeToStruct(&result); err != nil {
+						if err := c.decodeToStruct(ctx, &result); err != nil {
 							confEvent.Error = err
 						} else {
 							confEvent.Config = &result
@@ -235,6 +306,23 @@ func (c *ConfOpt[T]) SubscribeCtx(ctx context.Context) (<-chan *ConfEvent[T], er
 	return confEventChan, nil
 }
 
+// applyEvent updates the parsed data from a reader event, using its structured
+// values when present and decoding its data otherwise.
+func (c *ConfOpt[T]) applyEvent(event *reader.ReadEvent) error {
+	c.rawData = event.Data
+	if !event.Meta.IsZero() {
+		c.meta = event.Meta
+	}
+	if event.Values != nil {
+		c.parsedData = event.Values
+		return nil
+	}
+	if err := c.resolveDecoder(); err != nil {
+		return err
+	}
+	return c.decode()
+}
+
 func (c *ConfOpt[T]) Close() error {
 	if c.reader != nil {
 		return c.reader.Close()
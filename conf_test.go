@@ -0,0 +1,217 @@
+package feconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/sower-proxy/feconf/decoder/json"
+	_ "github.com/sower-proxy/feconf/decoder/yaml"
+	"github.com/sower-proxy/feconf/reader"
+	_ "github.com/sower-proxy/feconf/reader/http"
+)
+
+func TestGetFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		meta    reader.ContentMeta
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "extension from URI path",
+			uri:  "http://example.com/config.yaml",
+			meta: reader.ContentMeta{MIMEType: "application/json"},
+			want: "yaml",
+		},
+		{
+			name: "content-type query overrides metadata",
+			uri:  "http://example.com/api/config?content-type=application/yaml",
+			meta: reader.ContentMeta{MIMEType: "application/json"},
+			want: "yaml",
+		},
+		{
+			name: "MIME type from metadata",
+			uri:  "http://example.com/api/config",
+			meta: reader.ContentMeta{MIMEType: "application/json", Charset: "utf-8"},
+			want: "json",
+		},
+		{
+			name: "extension from metadata",
+			uri:  "http://example.com/api/config",
+			meta: reader.ContentMeta{MIMEType: "text/plain", Extension: ".yml"},
+			want: "yaml",
+		},
+		{
+			name: "unsupported URI extension falls back to metadata",
+			uri:  "http://example.com/config.php",
+			meta: reader.ContentMeta{MIMEType: "application/json"},
+			want: "json",
+		},
+		{
+			name:    "no hints",
+			uri:     "http://example.com/api/config",
+			meta:    reader.ContentMeta{MIMEType: "text/plain"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsedURL, err := reader.ParseURI(tt.uri)
+			if err != nil {
+				t.Fatalf("ParseURI() error = %v", err)
+			}
+
+			conf := &ConfOpt[struct{}]{uri: tt.uri, parsedURL: parsedURL, meta: tt.meta}
+			format, err := conf.getFormat()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(format) != tt.want {
+				t.Errorf("getFormat() = %s, want %s", format, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFormatFromContentType(t *testing.T) {
+	resetFlags()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		fmt.Fprint(w, "host: example.com\nport: 8080\n")
+	}))
+	defer server.Close()
+
+	var config struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	if err := Load(&config, server.URL+"/api/config"); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.Host != "example.com" || config.Port != 8080 {
+		t.Errorf("Load() = %+v", config)
+	}
+}
+
+// mapReader is a structured reader stand-in that needs no decoder.
+type mapReader struct {
+	values map[string]any
+	events chan *reader.ReadEvent
+}
+
+func (m *mapReader) Read(context.Context) ([]byte, error) { return []byte("{}"), nil }
+
+func (m *mapReader) Subscribe(context.Context) (<-chan *reader.ReadEvent, error) {
+	return m.events, nil
+}
+
+func (m *mapReader) Close() error { return nil }
+
+func (m *mapReader) Structured() bool { return true }
+
+func (m *mapReader) ReadMap(context.Context) (map[string]any, error) { return m.values, nil }
+
+func TestSubscribeStructuredValues(t *testing.T) {
+	resetFlags()
+
+	fake := &mapReader{
+		values: map[string]any{"server": map[string]any{"host": "example.com", "port": 8080}},
+		events: make(chan *reader.ReadEvent, 1),
+	}
+	_ = reader.RegisterReader("maptest", func(string) (reader.ConfReader, error) { return fake, nil })
+
+	type Config struct {
+		Server struct {
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		} `json:"server"`
+	}
+	conf := New[Config]("", "maptest://values")
+	events, err := conf.Subscribe()
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	event := <-events
+	if !event.IsValid() || event.Config.Server.Host != "example.com" || event.Config.Server.Port != 8080 {
+		t.Fatalf("initial event = %+v", event)
+	}
+
+	fake.events <- reader.NewReadEvent("maptest://values", []byte("{}"), nil).
+		WithValues(map[string]any{"server": map[string]any{"host": "updated", "port": "9090"}}).
+		WithRevision("7")
+	select {
+	case event := <-events:
+		if !event.IsValid() {
+			t.Fatalf("event error = %v", event.Error)
+		}
+		if event.Config.Server.Host != "updated" || event.Config.Server.Port != 9090 {
+			t.Errorf("event config = %+v", event.Config)
+		}
+		if event.Revision != "7" {
+			t.Errorf("event revision = %q, want 7", event.Revision)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+}
+
+func TestParseResolvesReferencesOncePerParse(t *testing.T) {
+	resetFlags()
+
+	var calls atomic.Int32
+	_ = reader.RegisterResolver("parseref", func(ctx context.Context, ref string) (string, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("resolver context has no deadline")
+		}
+		if ref == "slow" {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		calls.Add(1)
+		return "<" + ref + ">", nil
+	})
+
+	var body atomic.Value
+	body.Store("user: ${parseref:db#user}\ndsn: ${parseref:db#user}@db\nliteral: $${parseref:db#user}\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body.Load())
+	}))
+	defer server.Close()
+
+	type Config struct {
+		User    string `json:"user"`
+		DSN     string `json:"dsn"`
+		Literal string `json:"literal"`
+	}
+	conf := New[Config]("", server.URL+"/config.yaml")
+	defer conf.Close()
+
+	for parse := 1; parse <= 2; parse++ {
+		config, err := conf.Parse()
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if config.User != "<db#user>" || config.DSN != "<db#user>@db" || config.Literal != "${parseref:db#user}" {
+			t.Errorf("Parse() = %+v", config)
+		}
+		if got := calls.Load(); got != int32(parse) {
+			t.Errorf("resolver calls after parse %d = %d, want %d", parse, got, parse)
+		}
+	}
+
+	body.Store("user: ${parseref:slow}\n")
+	conf.ResolveTimeout = 50 * time.Millisecond
+	if _, err := conf.Parse(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Parse() error = %v, want deadline exceeded", err)
+	}
+}
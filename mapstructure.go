@@ -1,6 +1,7 @@
 package feconf
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -8,8 +9,10 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-viper/mapstructure/v2"
+	"github.com/sower-proxy/feconf/reader"
 	"gopkg.in/yaml.v3"
 )
 
@@ -148,6 +151,27 @@ func HookFuncEnvRender() mapstructure.DecodeHookFuncType {
 	}
 }
 
+// DefaultResolveTimeout bounds resolving the ${name:ref} references of one
+// parse, or of one value in HookFuncReferenceRender
+var DefaultResolveTimeout = 30 * time.Second
+
+// HookFuncReferenceRender resolves ${name:ref} references with the resolver
+// registered for name, e.g. ${vault:secret/data/db#password}. References
+// without a registered resolver are kept as is, and $${name:ref} escapes one.
+// ConfOpt resolves references before decoding, once per parse; the hook is
+// meant for decoder configs used on their own.
+func HookFuncReferenceRender() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data any) (any, error) {
+		if f.Kind() != reflect.String {
+			return data, nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultResolveTimeout)
+		defer cancel()
+		return renderReferences(ctx, data.(string), nil)
+	}
+}
+
 // HookFuncStringToSlice supports structured slice literals such as JSON/YAML
 // flow sequences while preserving the existing CSV fallback hook behavior.
 func HookFuncStringToSlice() mapstructure.DecodeHookFuncType {
@@ -171,6 +195,9 @@ func HookFuncStringToSlice() mapstructure.DecodeHookFuncType {
 
 var envRe = regexp.MustCompile(`\$\{([a-zA-Z0-9_]+)(?::-([^}]*))?\}`)
 
+// referenceRe matches ${name:ref}, but not the ${NAME:-default} of envRe
+var referenceRe = regexp.MustCompile(`\$\{([a-zA-Z][a-zA-Z0-9_]*):([^}-][^}]*)\}`)
+
 func looksLikeSliceLiteralCandidate(raw string) bool {
 	return strings.HasPrefix(raw, "[")
 }
@@ -232,6 +259,103 @@ func renderEnv(value string) string {
 	return result
 }
 
+// renderValueReferences returns a copy of data with the references in its
+// string values resolved. Resolved references are memoised in resolved.
+func renderValueReferences(ctx context.Context, data any, resolved map[string]string) (any, error) {
+	switch value := data.(type) {
+	case string:
+		return renderReferences(ctx, value, resolved)
+	case map[string]any:
+		if value == nil {
+			return value, nil
+		}
+		result := make(map[string]any, len(value))
+		for key, item := range value {
+			rendered, err := renderValueReferences(ctx, item, resolved)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = rendered
+		}
+		return result, nil
+	case []any:
+		if value == nil {
+			return value, nil
+		}
+		result := make([]any, len(value))
+		for i, item := range value {
+			rendered, err := renderValueReferences(ctx, item, resolved)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = rendered
+		}
+		return result, nil
+	case []map[string]any:
+		if value == nil {
+			return value, nil
+		}
+		result := make([]map[string]any, len(value))
+		for i, item := range value {
+			rendered, err := renderValueReferences(ctx, item, resolved)
+			if err != nil {
+				return nil, err
+			}
+			result[i], _ = rendered.(map[string]any)
+		}
+		return result, nil
+	default:
+		return data, nil
+	}
+}
+
+// renderReferences resolves the references in value. A non-nil resolved map
+// memoises the values of references already resolved.
+func renderReferences(ctx context.Context, value string, resolved map[string]string) (string, error) {
+	idxPairs := referenceRe.FindAllStringSubmatchIndex(value, -1)
+	if len(idxPairs) == 0 {
+		return value, nil
+	}
+
+	var result strings.Builder
+	lastEnd := 0
+	for _, idx := range idxPairs {
+		start, end := idx[0], idx[1]
+
+		// Keep escaped references ($${name:ref}) without one $
+		if start > 0 && value[start-1] == '$' {
+			result.WriteString(value[lastEnd:start-1] + value[start:end])
+			lastEnd = end
+			continue
+		}
+
+		if cached, ok := resolved[value[start:end]]; ok {
+			result.WriteString(value[lastEnd:start] + cached)
+			lastEnd = end
+			continue
+		}
+		name, ref := value[idx[2]:idx[3]], value[idx[4]:idx[5]]
+		resolver, ok := reader.GetResolver(name)
+		if !ok {
+			result.WriteString(value[lastEnd:end])
+			lastEnd = end
+			continue
+		}
+		secret, err := resolver(ctx, ref)
+		if err != nil {
+			return "", fmt.Errorf("resolve reference ${%s:%s}: %w", name, ref, err)
+		}
+		if resolved != nil {
+			resolved[value[start:end]] = secret
+		}
+		result.WriteString(value[lastEnd:start] + secret)
+		lastEnd = end
+	}
+
+	result.WriteString(value[lastEnd:])
+	return result.String(), nil
+}
+
 // HookFuncDefault 默认值钩子，当其他钩子都无法处理时提供默认值
 func HookFuncDefault() mapstructure.DecodeHookFuncType {
 	return func(f reflect.Type, t reflect.Type, data any) (any, error) {
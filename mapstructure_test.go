@@ -1,12 +1,15 @@
 package feconf
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"reflect"
 	"testing"
 
 	"github.com/go-viper/mapstructure/v2"
+	"github.com/sower-proxy/feconf/reader"
 )
 
 func TestStringToBoolHook(t *testing.T) {
@@ -1020,3 +1023,68 @@ func TestDefaultHookWithStruct(t *testing.T) {
 		})
 	}
 }
+
+func TestReferenceRenderHook(t *testing.T) {
+	_ = reader.RegisterResolver("testref", func(ctx context.Context, ref string) (string, error) {
+		if ref == "missing" {
+			return "", fmt.Errorf("not found")
+		}
+		return "<" + ref + ">", nil
+	})
+	hook := HookFuncReferenceRender()
+
+	tests := []struct {
+		name    string
+		data    any
+		want    any
+		wantErr bool
+	}{
+		{
+			name: "whole value",
+			data: "${testref:secret/data/db#password}",
+			want: "<secret/data/db#password>",
+		},
+		{
+			name: "embedded references",
+			data: "postgres://${testref:user}:${testref:password}@db:5432",
+			want: "postgres://<user>:<password>@db:5432",
+		},
+		{
+			name: "escaped reference",
+			data: "$${testref:user}",
+			want: "${testref:user}",
+		},
+		{
+			name: "unregistered resolver",
+			data: "${unknown:user}",
+			want: "${unknown:user}",
+		},
+		{
+			name: "environment default syntax",
+			data: "${testref:-default}",
+			want: "${testref:-default}",
+		},
+		{
+			name: "non-string value",
+			data: 42,
+			want: 42,
+		},
+		{
+			name:    "resolver error",
+			data:    "${testref:missing}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hook(reflect.TypeOf(tt.data), reflect.TypeOf(""), tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReferenceRenderHook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ReferenceRenderHook() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,514 @@
+// Package apollo provides a configuration reader implementation for the
+// Apollo config center. It reads a namespace from the config services
+// discovered through the meta server, and subscribes to changes with the
+// notification long polling API.
+package apollo
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+)
+
+const (
+	// SchemeApollo represents Apollo URI scheme
+	SchemeApollo reader.Scheme = "apollo"
+
+	// DefaultPort is the meta server port
+	DefaultPort = "8080"
+	// DefaultCluster is the cluster read when the URI names none
+	DefaultCluster = "default"
+	// DefaultNamespace is the namespace read when the URI names none
+	DefaultNamespace = "application"
+
+	// EnvSecret and EnvSecretFile name the environment variables of the
+	// access key secret used when the URI sets no secret_file, the first as
+	// in the Apollo clients
+	EnvSecret     = "APOLLO_ACCESS_KEY_SECRET"
+	EnvSecretFile = "APOLLO_ACCESS_KEY_SECRET_FILE"
+
+	configsPath       = "/configs/"
+	notificationsPath = "/notifications/v2"
+
+	// contentKey holds the document of namespaces in other formats than
+	// properties
+	contentKey = "content"
+)
+
+var (
+	// DefaultTimeout for Apollo requests
+	DefaultTimeout = 10 * time.Second
+	// DefaultListenTimeout of notification long polls, which the config
+	// service holds for 60 seconds
+	DefaultListenTimeout = 90 * time.Second
+	// DefaultRetryDelay before retrying a failed long poll
+	DefaultRetryDelay = 1 * time.Second
+)
+
+// init registers the Apollo reader
+func init() {
+	_ = reader.RegisterReader(SchemeApollo, func(uri string) (reader.ConfReader, error) {
+		return NewApolloReader(uri)
+	})
+}
+
+// ApolloConfig holds Apollo reader configuration
+type ApolloConfig struct {
+	MetaURLs      []string // Base URLs of the meta servers
+	Discovery     bool     // Discover config services, or use MetaURLs as them
+	AppID         string
+	Cluster       string
+	Namespace     string // Namespace name, with the extension of its format
+	Secret        string // Access key secret signing the requests
+	SecretFile    string // File holding the secret, read on every request
+	Label         string // Label of grayscale releases
+	IP            string // Client IP of grayscale releases
+	Timeout       time.Duration
+	ListenTimeout time.Duration
+	RetryDelay    time.Duration
+}
+
+// ApolloReader implements ConfReader for Apollo configuration
+type ApolloReader struct {
+	uri         string
+	config      *ApolloConfig
+	client      *http.Client
+	services    *serviceList
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+	mu          sync.RWMutex
+	closed      bool
+}
+
+// release is a release of the namespace returned by the config service
+type release struct {
+	Configurations map[string]string `json:"configurations"`
+	ReleaseKey     string            `json:"releaseKey"`
+}
+
+// notification is the latest change of a namespace known to the client or
+// reported by the config service
+type notification struct {
+	NamespaceName  string `json:"namespaceName"`
+	NotificationID int64  `json:"notificationId"`
+}
+
+// NewApolloReader creates a new Apollo reader.
+// URI format: apollo://meta-server:port[,meta-server:port...]/appId[/cluster[/namespace]][?secret_file=...&label=...]
+// Namespaces without an extension, or with .properties, are read as values
+// nested by the dots of their keys. Other namespaces, e.g. app.yaml, are read
+// as documents decoded by their extension.
+func NewApolloReader(uri string) (*ApolloReader, error) {
+	u, err := reader.ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != string(SchemeApollo) {
+		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s", u.Scheme, SchemeApollo)
+	}
+
+	config, err := parseApolloURI(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Apollo URI: %w", err)
+	}
+
+	var tlsConfig *tls.Config
+	if u.Query().Get("tls_insecure") == "true" {
+		tlsConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true,
+		}
+	}
+
+	client := &http.Client{
+		Timeout:   config.Timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	closeCtx, closeCancel := context.WithCancel(context.Background())
+	return &ApolloReader{
+		uri:         uri,
+		config:      config,
+		client:      client,
+		services:    newServiceList(config, client),
+		closeCtx:    closeCtx,
+		closeCancel: closeCancel,
+	}, nil
+}
+
+func parseApolloURI(u *url.URL) (*ApolloConfig, error) {
+	hosts := reader.Hosts(u)
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("meta server must be specified in host")
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if parts[0] == "" || len(parts) > 3 {
+		return nil, fmt.Errorf("invalid Apollo URI path, expected: apollo://host:port/{appId}[/{cluster}[/{namespace}]]")
+	}
+	config := &ApolloConfig{
+		AppID:     parts[0],
+		Cluster:   DefaultCluster,
+		Namespace: DefaultNamespace,
+	}
+	if len(parts) > 1 && parts[1] != "" {
+		config.Cluster = parts[1]
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		config.Namespace = parts[2]
+	}
+
+	query := u.Query()
+	config.SecretFile = query.Get("secret_file")
+	config.Label = query.Get("label")
+	config.IP = query.Get("ip")
+
+	serverScheme := query.Get("server_scheme")
+	if serverScheme == "" {
+		serverScheme = "http"
+	}
+	if serverScheme != "http" && serverScheme != "https" {
+		return nil, fmt.Errorf("unsupported server_scheme: %s", serverScheme)
+	}
+	for _, host := range hosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, DefaultPort)
+		}
+		config.MetaURLs = append(config.MetaURLs, serverScheme+"://"+host)
+	}
+
+	// URIs are reported as the source of every event, so the secret itself
+	// is only taken from the environment. A secret file in the URI takes
+	// precedence over the environment, where a secret takes precedence over
+	// a file.
+	if query.Has("secret") {
+		return nil, fmt.Errorf("secret must not be set in the URI, use %s or secret_file", EnvSecret)
+	}
+	if config.SecretFile == "" {
+		config.Secret = os.Getenv(EnvSecret)
+		if config.Secret == "" {
+			config.SecretFile = os.Getenv(EnvSecretFile)
+		}
+	}
+
+	switch discovery := query.Get("discovery"); discovery {
+	case "", "true":
+		config.Discovery = true
+	case "false":
+	default:
+		return nil, fmt.Errorf("invalid discovery format: %s", discovery)
+	}
+
+	var err error
+	if config.Timeout, err = reader.ParseDuration(query, "timeout", DefaultTimeout); err != nil {
+		return nil, err
+	}
+	if config.ListenTimeout, err = reader.ParseDuration(query, "listen_timeout", DefaultListenTimeout); err != nil {
+		return nil, err
+	}
+	if config.RetryDelay, err = reader.ParseDuration(query, "retry_delay", DefaultRetryDelay); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// properties reports whether the namespace is in the properties format
+func (a *ApolloReader) properties() bool {
+	ext := path.Ext(a.config.Namespace)
+	return ext == "" || ext == ".properties"
+}
+
+// namespaceName returns the namespace name of the API, which has no
+// .properties extension
+func (a *ApolloReader) namespaceName() string {
+	return strings.TrimSuffix(a.config.Namespace, ".properties")
+}
+
+// Read reads configuration data from Apollo
+func (a *ApolloReader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := a.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads configuration data and reports the extension of the
+// namespace, or JSON for properties namespaces
+func (a *ApolloReader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
+	}
+
+	r, err := a.fetch(ctx, "")
+	if err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
+	content := a.content(r)
+	return content.data, content.meta, content.err
+}
+
+// Structured implements reader.MapReader. Properties namespaces are read as
+// values.
+func (a *ApolloReader) Structured() bool {
+	return a.properties()
+}
+
+// ReadMap implements reader.MapReader
+func (a *ApolloReader) ReadMap(ctx context.Context) (map[string]any, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	r, err := a.fetch(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	content := a.content(r)
+	return content.values, content.err
+}
+
+// Subscribe reads the namespace and then long polls its notifications,
+// reading the namespace again whenever its notification ID changes.
+func (a *ApolloReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	r, err := a.fetch(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	eventChan := make(chan *reader.ReadEvent, 1)
+	ctx, cancel := reader.WithClose(ctx, a.closeCtx)
+	go func() {
+		defer cancel()
+		a.watch(ctx, eventChan, r)
+	}()
+
+	return eventChan, nil
+}
+
+// Close closes the reader and cleans up resources
+func (a *ApolloReader) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return nil
+	}
+
+	a.closed = true
+	a.closeCancel()
+	a.client.CloseIdleConnections()
+	return nil
+}
+
+// watch reports the current release, then long polls the notifications of
+// the namespace and reports its release whenever it changes
+func (a *ApolloReader) watch(ctx context.Context, eventChan chan<- *reader.ReadEvent, r *release) {
+	defer close(eventChan)
+
+	reporter := reader.NewReporter(eventChan)
+	reporter.Report(ctx, a.content(r).event(a.uri))
+
+	// The first poll returns the current notification ID without waiting
+	releaseKey := r.ReleaseKey
+	notificationID := int64(-1)
+	for ctx.Err() == nil {
+		id, err := a.poll(ctx, notificationID)
+		var next *release
+		if err == nil && id != notificationID {
+			next, err = a.fetch(ctx, releaseKey)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			reporter.Report(ctx, content{err: err}.event(a.uri))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(a.config.RetryDelay):
+			}
+			continue
+		}
+
+		notificationID = id
+		if next != nil {
+			r, releaseKey = next, next.ReleaseKey
+		}
+		// Unchanged releases are only reported again after an error
+		reporter.Report(ctx, a.content(r).event(a.uri))
+	}
+}
+
+// fetch reads the release of the namespace. With the key of the last
+// release, nil is returned while it is still current.
+func (a *ApolloReader) fetch(ctx context.Context, releaseKey string) (*release, error) {
+	query := url.Values{}
+	if releaseKey != "" {
+		query.Set("releaseKey", releaseKey)
+	}
+	if a.config.Label != "" {
+		query.Set("label", a.config.Label)
+	}
+	if a.config.IP != "" {
+		query.Set("ip", a.config.IP)
+	}
+	requestPath := configsPath + url.PathEscape(a.config.AppID) + "/" + url.PathEscape(a.config.Cluster) + "/" + url.PathEscape(a.namespaceName())
+
+	resp, body, err := a.do(ctx, a.client, func(service string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, service+requestPath+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Apollo config request: %w", err)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Apollo namespace '%s': %w", a.config.Namespace, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("apollo namespace '%s' of app '%s' not found in cluster '%s'", a.config.Namespace, a.config.AppID, a.config.Cluster)
+	default:
+		return nil, fmt.Errorf("failed to get Apollo namespace '%s': unexpected HTTP status %d: %s", a.config.Namespace, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var r release
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode Apollo config response: %w", err)
+	}
+	return &r, nil
+}
+
+// poll long polls the notifications of the namespace after notificationID
+// and returns the latest notification ID, which is unchanged when the poll
+// timed out on the config service
+func (a *ApolloReader) poll(ctx context.Context, notificationID int64) (int64, error) {
+	notifications, err := json.Marshal([]notification{{NamespaceName: a.namespaceName(), NotificationID: notificationID}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode notifications: %w", err)
+	}
+	query := url.Values{}
+	query.Set("appId", a.config.AppID)
+	query.Set("cluster", a.config.Cluster)
+	query.Set("notifications", string(notifications))
+	if a.config.Label != "" {
+		query.Set("label", a.config.Label)
+	}
+	if a.config.IP != "" {
+		query.Set("ip", a.config.IP)
+	}
+
+	client := *a.client
+	client.Timeout = a.config.ListenTimeout
+	resp, body, err := a.do(ctx, &client, func(service string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, service+notificationsPath+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Apollo notifications request: %w", err)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to poll Apollo notifications: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return notificationID, nil
+	default:
+		return 0, fmt.Errorf("failed to poll Apollo notifications: unexpected HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var changed []notification
+	if err := json.Unmarshal(body, &changed); err != nil {
+		return 0, fmt.Errorf("failed to decode Apollo notifications: %w", err)
+	}
+	for _, n := range changed {
+		if strings.EqualFold(n.NamespaceName, a.namespaceName()) {
+			return n.NotificationID, nil
+		}
+	}
+	return notificationID, nil
+}
+
+// content is the configuration read from a release
+type content struct {
+	data       []byte
+	meta       reader.ContentMeta
+	values     map[string]any
+	releaseKey string
+	err        error
+}
+
+// content returns the document of the namespace, or the values of a
+// properties namespace
+func (a *ApolloReader) content(r *release) content {
+	if !a.properties() {
+		document, ok := r.Configurations[contentKey]
+		if !ok {
+			return content{err: fmt.Errorf("apollo namespace '%s' has no content", a.config.Namespace)}
+		}
+		return content{
+			data:       []byte(document),
+			meta:       reader.ContentMeta{Extension: path.Ext(a.config.Namespace)},
+			releaseKey: r.ReleaseKey,
+		}
+	}
+
+	values, err := reader.ExpandDotted(r.Configurations)
+	if err != nil {
+		return content{err: err}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return content{err: fmt.Errorf("failed to encode values: %w", err)}
+	}
+	return content{
+		data:       data,
+		meta:       reader.ContentMeta{MIMEType: "application/json", Extension: ".json"},
+		values:     values,
+		releaseKey: r.ReleaseKey,
+	}
+}
+
+// event builds a read event of the content, with the release key as its
+// revision
+func (c content) event(uri string) *reader.ReadEvent {
+	if c.err != nil {
+		return reader.NewReadEvent(uri, nil, c.err)
+	}
+	return reader.NewReadEvent(uri, c.data, nil).
+		WithMeta(c.meta).
+		WithValues(c.values).
+		WithRevision(c.releaseKey)
+}
@@ -0,0 +1,486 @@
+package apollo
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+// fakeApollo is a stand-in for an Apollo meta server and config service
+type fakeApollo struct {
+	*httptest.Server
+	t          *testing.T
+	secret     string
+	services   []string
+	mu         sync.Mutex
+	namespaces map[string]*fakeNamespace
+	nextID     int64
+	changed    chan struct{}
+	polls      []string
+}
+
+// fakeNamespace is the latest release of a namespace
+type fakeNamespace struct {
+	configurations map[string]string
+	releaseKey     string
+	notificationID int64
+}
+
+func newFakeApollo(t *testing.T) *fakeApollo {
+	t.Helper()
+
+	f := &fakeApollo{t: t, namespaces: make(map[string]*fakeNamespace), changed: make(chan struct{})}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeApollo) uri(path, query string) string {
+	return "apollo://" + strings.TrimPrefix(f.URL, "http://") + path + "?retry_delay=10ms&" + query
+}
+
+// publish releases the configurations of the namespace, e.g. app/default/application
+func (f *fakeApollo) publish(namespace string, configurations map[string]string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	releaseKey := "release-" + strconv.FormatInt(f.nextID, 10)
+	f.namespaces[namespace] = &fakeNamespace{configurations: configurations, releaseKey: releaseKey, notificationID: f.nextID}
+	f.notify()
+	return releaseKey
+}
+
+func (f *fakeApollo) delete(namespace string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	delete(f.namespaces, namespace)
+	f.notify()
+}
+
+func (f *fakeApollo) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// notificationsPolled returns the notifications parameters of the long polls
+func (f *fakeApollo) notificationsPolled() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.polls...)
+}
+
+func (f *fakeApollo) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == servicesPath {
+		services := f.services
+		if services == nil {
+			services = []string{f.URL + "/"}
+		}
+		var instances []map[string]string
+		for _, service := range services {
+			instances = append(instances, map[string]string{"appName": "APOLLO-CONFIGSERVICE", "homepageUrl": service})
+		}
+		_ = json.NewEncoder(w).Encode(instances)
+		return
+	}
+
+	if f.secret != "" {
+		timestamp := r.Header.Get(timestampHeader)
+		mac := hmac.New(sha1.New, []byte(f.secret))
+		mac.Write([]byte(timestamp + "\n" + r.URL.RequestURI()))
+		appID := r.URL.Query().Get("appId")
+		if appID == "" {
+			appID = strings.Split(strings.TrimPrefix(r.URL.Path, configsPath), "/")[0]
+		}
+		want := "Apollo " + appID + ":" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if r.Header.Get(authorizationHeader) != want {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, configsPath):
+		f.handleConfigs(w, r)
+	case r.URL.Path == notificationsPath:
+		f.handleNotifications(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeApollo) handleConfigs(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ns, ok := f.namespaces[strings.TrimPrefix(r.URL.Path, configsPath)]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Query().Get("releaseKey") == ns.releaseKey {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(release{Configurations: ns.configurations, ReleaseKey: ns.releaseKey})
+}
+
+// handleNotifications holds the poll until a namespace has a notification
+// ID above the one of the client, for up to a second
+func (f *fakeApollo) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var requested []notification
+	if err := json.Unmarshal([]byte(query.Get("notifications")), &requested); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.polls = append(f.polls, query.Get("notifications"))
+	f.mu.Unlock()
+
+	timeout := time.After(time.Second)
+	for {
+		f.mu.Lock()
+		var changed []notification
+		for _, n := range requested {
+			key := query.Get("appId") + "/" + query.Get("cluster") + "/" + n.NamespaceName
+			id := f.nextID
+			if ns, ok := f.namespaces[key]; ok {
+				id = ns.notificationID
+			}
+			if id > n.NotificationID {
+				changed = append(changed, notification{NamespaceName: n.NamespaceName, NotificationID: id})
+			}
+		}
+		wait := f.changed
+		f.mu.Unlock()
+
+		if len(changed) > 0 {
+			_ = json.NewEncoder(w).Encode(changed)
+			return
+		}
+		select {
+		case <-wait:
+		case <-timeout:
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func TestParseApolloURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		env     map[string]string
+		want    ApolloConfig
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			uri:  "apollo://meta.example.com/order-service",
+			want: ApolloConfig{
+				MetaURLs: []string{"http://meta.example.com:8080"}, Discovery: true,
+				AppID: "order-service", Cluster: DefaultCluster, Namespace: DefaultNamespace,
+				Timeout: DefaultTimeout, ListenTimeout: DefaultListenTimeout, RetryDelay: DefaultRetryDelay,
+			},
+		},
+		{
+			name: "full",
+			uri:  "apollo://meta1:8080,meta2:8081/order-service/sh/app.yaml?secret_file=/run/secrets/apollo&label=canary&ip=10.0.0.1&server_scheme=https&discovery=false&listen_timeout=2m",
+			env:  map[string]string{EnvSecret: "env-secret"},
+			want: ApolloConfig{
+				MetaURLs: []string{"https://meta1:8080", "https://meta2:8081"},
+				AppID:    "order-service", Cluster: "sh", Namespace: "app.yaml",
+				SecretFile: "/run/secrets/apollo", Label: "canary", IP: "10.0.0.1",
+				Timeout: DefaultTimeout, ListenTimeout: 2 * time.Minute, RetryDelay: DefaultRetryDelay,
+			},
+		},
+		{
+			name: "secret from environment",
+			uri:  "apollo://meta.example.com/order-service",
+			env:  map[string]string{EnvSecret: "env-secret", EnvSecretFile: "/run/secrets/apollo"},
+			want: ApolloConfig{
+				MetaURLs: []string{"http://meta.example.com:8080"}, Discovery: true,
+				AppID: "order-service", Cluster: DefaultCluster, Namespace: DefaultNamespace, Secret: "env-secret",
+				Timeout: DefaultTimeout, ListenTimeout: DefaultListenTimeout, RetryDelay: DefaultRetryDelay,
+			},
+		},
+		{
+			name: "secret file from environment",
+			uri:  "apollo://meta.example.com/order-service",
+			env:  map[string]string{EnvSecretFile: "/run/secrets/apollo"},
+			want: ApolloConfig{
+				MetaURLs: []string{"http://meta.example.com:8080"}, Discovery: true,
+				AppID: "order-service", Cluster: DefaultCluster, Namespace: DefaultNamespace, SecretFile: "/run/secrets/apollo",
+				Timeout: DefaultTimeout, ListenTimeout: DefaultListenTimeout, RetryDelay: DefaultRetryDelay,
+			},
+		},
+		{name: "secret in URI", uri: "apollo://meta.example.com/app?secret=s3cret", wantErr: true},
+		{name: "missing app", uri: "apollo://meta.example.com/", wantErr: true},
+		{name: "too many segments", uri: "apollo://meta.example.com/app/default/application/extra", wantErr: true},
+		{name: "invalid discovery", uri: "apollo://meta.example.com/app?discovery=maybe", wantErr: true},
+		{name: "invalid server_scheme", uri: "apollo://meta.example.com/app?server_scheme=ftp", wantErr: true},
+		{name: "invalid retry_delay", uri: "apollo://meta.example.com/app?retry_delay=0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvSecret, "")
+			t.Setenv(EnvSecretFile, "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			u, err := reader.ParseURI(tt.uri)
+			if err != nil {
+				t.Fatalf("Failed to parse URI: %v", err)
+			}
+			config, err := parseApolloURI(u)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got config %+v", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse Apollo URI: %v", err)
+			}
+			if !reflect.DeepEqual(*config, tt.want) {
+				t.Errorf("Expected config %+v, got %+v", tt.want, *config)
+			}
+		})
+	}
+}
+
+func TestApolloReaderReadProperties(t *testing.T) {
+	server := newFakeApollo(t)
+	server.publish("app/default/application", map[string]string{
+		"db.host": "localhost",
+		"db.port": "5432",
+		"name":    "app",
+	})
+
+	r, err := NewApolloReader(server.uri("/app/default/application.properties", ""))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if !r.Structured() {
+		t.Error("Expected properties namespace to be structured")
+	}
+	values, err := r.ReadMap(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to read map: %v", err)
+	}
+	want := map[string]any{
+		"db":   map[string]any{"host": "localhost", "port": "5432"},
+		"name": "app",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Expected values %v, got %v", want, values)
+	}
+
+	data, meta, err := r.ReadMeta(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != `{"db":{"host":"localhost","port":"5432"},"name":"app"}` || meta.Extension != ".json" {
+		t.Errorf("Expected JSON values, got %s with %+v", data, meta)
+	}
+
+	missing, err := NewApolloReader(server.uri("/app/default/missing", ""))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer missing.Close()
+	if _, err := missing.Read(t.Context()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Failed to close reader: %v", err)
+	}
+	if _, err := r.Read(t.Context()); err == nil {
+		t.Error("Expected error after close")
+	}
+}
+
+func TestApolloReaderReadDocument(t *testing.T) {
+	server := newFakeApollo(t)
+	server.publish("app/sh/app.yaml", map[string]string{contentKey: "server:\n  port: 8080\n"})
+
+	r, err := NewApolloReader(server.uri("/app/sh/app.yaml", "discovery=false"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if r.Structured() {
+		t.Error("Expected YAML namespace to be read as a document")
+	}
+	data, meta, err := r.ReadMeta(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != "server:\n  port: 8080\n" || meta.Extension != ".yaml" {
+		t.Errorf("Expected YAML document, got %q with %+v", data, meta)
+	}
+}
+
+func TestApolloReaderDiscovery(t *testing.T) {
+	configService := newFakeApollo(t)
+	configService.publish("app/default/application", map[string]string{"name": "app"})
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	meta := newFakeApollo(t)
+	meta.services = []string{down.URL, configService.URL}
+
+	r, err := NewApolloReader(meta.uri("/app", ""))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	values, err := r.ReadMap(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to read map: %v", err)
+	}
+	if values["name"] != "app" {
+		t.Errorf("Expected values of the discovered config service, got %v", values)
+	}
+
+	// The service that could not be reached is tried last
+	services, err := r.services.candidates(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to get services: %v", err)
+	}
+	if !reflect.DeepEqual(services, []string{configService.URL, down.URL}) {
+		t.Errorf("Expected unreachable service last, got %v", services)
+	}
+}
+
+func TestApolloReaderSubscribe(t *testing.T) {
+	server := newFakeApollo(t)
+	first := server.publish("app/default/app.json", map[string]string{contentKey: `{"version":1}`})
+
+	r, err := NewApolloReader(server.uri("/app/default/app.json", "discovery=false"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	events, err := r.Subscribe(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	event := readertest.NextEvent(t, events)
+	if string(event.Data) != `{"version":1}` || event.Revision != first {
+		t.Errorf("Expected first release, got %s at %s", event.Data, event.Revision)
+	}
+
+	second := server.publish("app/default/app.json", map[string]string{contentKey: `{"version":2}`})
+	event = readertest.NextEvent(t, events)
+	if string(event.Data) != `{"version":2}` || event.Revision != second {
+		t.Errorf("Expected second release %s, got %s at %s", second, event.Data, event.Revision)
+	}
+
+	// Polls after the first one send the tracked notification ID
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.ContainsFunc(server.notificationsPolled(), func(poll string) bool {
+		return !strings.Contains(poll, `"notificationId":-1`) && strings.Contains(poll, `"namespaceName":"app.json"`)
+	}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected polls with the notification ID, got %v", server.notificationsPolled())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.delete("app/default/app.json")
+	event = readertest.NextEvent(t, events)
+	if event.Error == nil || !strings.Contains(event.Error.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", event.Error)
+	}
+
+	third := server.publish("app/default/app.json", map[string]string{contentKey: `{"version":3}`})
+	event = readertest.NextEvent(t, events)
+	if string(event.Data) != `{"version":3}` || event.Revision != third {
+		t.Errorf("Expected third release %s, got %s at %s", third, event.Data, event.Revision)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Failed to close reader: %v", err)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected event channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Timeout waiting for event channel to close")
+	}
+}
+
+func TestApolloReaderSecret(t *testing.T) {
+	server := newFakeApollo(t)
+	server.secret = "app-secret"
+	server.publish("app/default/application", map[string]string{"name": "app"})
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("app-secret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	t.Setenv(EnvSecret, "wrong")
+	r, err := NewApolloReader(server.uri("/app", "discovery=false&secret_file="+secretFile))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	events, err := r.Subscribe(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to subscribe with signed requests: %v", err)
+	}
+	if event := readertest.NextEvent(t, events); event.Error != nil {
+		t.Fatalf("Expected release, got %v", event.Error)
+	}
+
+	server.publish("app/default/application", map[string]string{"name": "renamed"})
+	if event := readertest.NextEvent(t, events); !reflect.DeepEqual(event.Values, map[string]any{"name": "renamed"}) {
+		t.Errorf("Expected signed notification polls, got %v: %v", event.Values, event.Error)
+	}
+
+	unsigned, err := NewApolloReader(server.uri("/app", "discovery=false"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer unsigned.Close()
+	if _, err := unsigned.Read(t.Context()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package apollo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	servicesPath = "/services/config"
+
+	authorizationHeader = "Authorization"
+	timestampHeader     = "Timestamp"
+)
+
+// serviceList tracks the config services of an Apollo cluster. They are
+// discovered from the meta servers, or are the meta servers themselves when
+// discovery is disabled, and are discovered again once none can be reached.
+type serviceList struct {
+	config *ApolloConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	services []string
+}
+
+func newServiceList(config *ApolloConfig, client *http.Client) *serviceList {
+	return &serviceList{config: config, client: client}
+}
+
+// candidates returns the base URLs of the config services to try in order
+func (s *serviceList) candidates(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.services) > 0 {
+		return append([]string(nil), s.services...), nil
+	}
+	if !s.config.Discovery {
+		s.services = append([]string(nil), s.config.MetaURLs...)
+		return append([]string(nil), s.services...), nil
+	}
+
+	var err error
+	for _, meta := range s.config.MetaURLs {
+		var services []string
+		if services, err = s.discover(ctx, meta); err == nil {
+			s.services = services
+			return append([]string(nil), services...), nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// markDown moves a config service that cannot be reached to the end of the
+// list, and drops the list for discovery once all of them failed
+func (s *serviceList) markDown(service string, all bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if all {
+		s.services = nil
+		return
+	}
+	for i, current := range s.services {
+		if current == service {
+			s.services = append(append(s.services[:i:i], s.services[i+1:]...), service)
+			return
+		}
+	}
+}
+
+// discover lists the config services registered with a meta server
+func (s *serviceList) discover(ctx context.Context, meta string) ([]string, error) {
+	query := url.Values{}
+	query.Set("appId", s.config.AppID)
+	if s.config.IP != "" {
+		query.Set("ip", s.config.IP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta+servicesPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Apollo discovery request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover Apollo config services from '%s': %w", meta, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to discover Apollo config services from '%s': unexpected HTTP status %d: %s", meta, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var instances []struct {
+		HomepageURL string `json:"homepageUrl"`
+	}
+	if err := json.Unmarshal(body, &instances); err != nil {
+		return nil, fmt.Errorf("failed to decode Apollo discovery response: %w", err)
+	}
+	services := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		if instance.HomepageURL != "" {
+			services = append(services, strings.TrimSuffix(instance.HomepageURL, "/"))
+		}
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no Apollo config services registered with '%s'", meta)
+	}
+	return services, nil
+}
+
+// do sends the request built by newRequest for a config service base URL,
+// moving on to the next service when one cannot be reached
+func (a *ApolloReader) do(ctx context.Context, client *http.Client, newRequest func(service string) (*http.Request, error)) (*http.Response, []byte, error) {
+	services, err := a.services.candidates(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, service := range services {
+		var resp *http.Response
+		var body []byte
+		resp, body, err = a.doService(client, service, newRequest)
+		if err == nil {
+			return resp, body, nil
+		}
+		if ctx.Err() != nil || !isConnError(err) {
+			return nil, nil, err
+		}
+		a.services.markDown(service, i == len(services)-1)
+	}
+	return nil, nil, err
+}
+
+func (a *ApolloReader) doService(client *http.Client, service string, newRequest func(service string) (*http.Request, error)) (*http.Response, []byte, error) {
+	req, err := newRequest(service)
+	if err != nil {
+		return nil, nil, err
+	}
+	secret, err := a.secret()
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		req.Header.Set(authorizationHeader, "Apollo "+a.config.AppID+":"+signature(timestamp, req.URL.RequestURI(), secret))
+		req.Header.Set(timestampHeader, timestamp)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp, body, nil
+}
+
+// secret returns the access key secret, reading the secret file on every
+// request so that rotated secrets are picked up
+func (a *ApolloReader) secret() (string, error) {
+	if a.config.Secret != "" || a.config.SecretFile == "" {
+		return a.config.Secret, nil
+	}
+	data, err := os.ReadFile(a.config.SecretFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// signature signs a request to the config service with the access key
+// secret of the app: HMAC-SHA1 of the timestamp and the path with query
+func signature(timestamp, pathWithQuery, secret string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + pathWithQuery))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// isConnError reports whether err is a transport error of a request, after
+// which the next service is tried
+func isConnError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
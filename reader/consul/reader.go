@@ -0,0 +1,515 @@
+// Package consul provides a configuration reader implementation for the
+// Consul KV store. It reads a single key, or all keys under a prefix as nested
+// values, and subscribes to changes with blocking queries.
+package consul
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+)
+
+const (
+	// SchemeConsul represents Consul URI scheme
+	SchemeConsul reader.Scheme = "consul"
+
+	// DefaultPort is the Consul HTTP API port
+	DefaultPort = "8500"
+
+	// EnvToken and EnvTokenFile name the environment variables of the ACL
+	// token used when the URI sets none, as in the Consul CLI
+	EnvToken     = "CONSUL_HTTP_TOKEN"
+	EnvTokenFile = "CONSUL_HTTP_TOKEN_FILE"
+
+	kvPath      = "/v1/kv/"
+	indexHeader = "X-Consul-Index"
+	tokenHeader = "X-Consul-Token"
+)
+
+var (
+	// DefaultTimeout for Consul requests, added to the wait of blocking queries
+	DefaultTimeout = 10 * time.Second
+	// DefaultWait is the maximum duration of a blocking query
+	DefaultWait = 5 * time.Minute
+	// DefaultRetryDelay before retrying a failed blocking query
+	DefaultRetryDelay = 1 * time.Second
+)
+
+// init registers the Consul reader
+func init() {
+	_ = reader.RegisterReader(SchemeConsul, func(uri string) (reader.ConfReader, error) {
+		return NewConsulReader(uri)
+	})
+}
+
+// ConsulConfig holds Consul reader configuration
+type ConsulConfig struct {
+	Address    string // Base URL of the HTTP API, e.g. http://127.0.0.1:8500
+	Key        string // Key, or key prefix when Recurse is set
+	Recurse    bool   // Read all keys under Key as nested values
+	Datacenter string
+	Namespace  string
+	Token      string
+	TokenFile  string // File holding the token, read on every request
+	Timeout    time.Duration
+	Wait       time.Duration
+	RetryDelay time.Duration
+}
+
+// Option configures a Consul reader
+type Option func(*ConsulConfig)
+
+// WithToken sends token in the X-Consul-Token header, taking precedence over
+// token_file and the environment
+func WithToken(token string) Option {
+	return func(c *ConsulConfig) {
+		c.Token = token
+		c.TokenFile = ""
+	}
+}
+
+// WithTokenFile reads the ACL token from path on every request, taking
+// precedence over token_file and the environment
+func WithTokenFile(path string) Option {
+	return func(c *ConsulConfig) {
+		c.Token = ""
+		c.TokenFile = path
+	}
+}
+
+// ConsulReader implements ConfReader for Consul KV configuration
+type ConsulReader struct {
+	uri         string
+	config      *ConsulConfig
+	client      *http.Client
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+	mu          sync.RWMutex
+	closed      bool
+}
+
+// kvPair is an entry of the KV API
+type kvPair struct {
+	Key         string `json:"Key"`
+	Value       []byte `json:"Value"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+// NewConsulReader creates a new Consul reader.
+// URI format: consul://host:port/path/to/key[?recurse=true&datacenter=dc1&namespace=team&token_file=...]
+// With recurse=true, the keys under path/to/key/ are read as values nested by
+// their path segments. The ACL token never comes from the URI, see WithToken.
+func NewConsulReader(uri string, opts ...Option) (*ConsulReader, error) {
+	u, err := reader.ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != string(SchemeConsul) {
+		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s", u.Scheme, SchemeConsul)
+	}
+
+	config, err := parseConsulURI(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Consul URI: %w", err)
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	var tlsConfig *tls.Config
+	if u.Query().Get("tls_insecure") == "true" {
+		tlsConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true,
+		}
+	}
+
+	closeCtx, closeCancel := context.WithCancel(context.Background())
+	return &ConsulReader{
+		uri:    uri,
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		closeCtx:    closeCtx,
+		closeCancel: closeCancel,
+	}, nil
+}
+
+func parseConsulURI(u *url.URL) (*ConsulConfig, error) {
+	host := u.Host
+	if host == "" {
+		return nil, fmt.Errorf("agent address must be specified in host")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, DefaultPort)
+	}
+
+	query := u.Query()
+
+	serverScheme := query.Get("server_scheme")
+	if serverScheme == "" {
+		serverScheme = "http"
+	}
+	if serverScheme != "http" && serverScheme != "https" {
+		return nil, fmt.Errorf("unsupported server_scheme: %s", serverScheme)
+	}
+
+	config := &ConsulConfig{
+		Address:    serverScheme + "://" + host,
+		Key:        strings.TrimPrefix(u.Path, "/"),
+		Datacenter: query.Get("datacenter"),
+		Namespace:  query.Get("namespace"),
+		TokenFile:  query.Get("token_file"),
+	}
+
+	if recurseStr := query.Get("recurse"); recurseStr != "" {
+		recurse, err := strconv.ParseBool(recurseStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurse format: %w", err)
+		}
+		config.Recurse = recurse
+	}
+	if config.Recurse {
+		if config.Key != "" && !strings.HasSuffix(config.Key, "/") {
+			config.Key += "/"
+		}
+	} else if config.Key == "" || strings.HasSuffix(config.Key, "/") {
+		return nil, fmt.Errorf("key must be specified in path")
+	}
+
+	// URIs end up in logs, so the token itself is only taken from the
+	// environment. A token file in the URI takes precedence over the
+	// environment, where a token takes precedence over a file.
+	if query.Has("token") {
+		return nil, fmt.Errorf("token must not be set in the URI, use %s or token_file", EnvToken)
+	}
+	if config.TokenFile == "" {
+		config.Token = os.Getenv(EnvToken)
+		if config.Token == "" {
+			config.TokenFile = os.Getenv(EnvTokenFile)
+		}
+	}
+
+	var err error
+	if config.Timeout, err = reader.ParseDuration(query, "timeout", DefaultTimeout); err != nil {
+		return nil, err
+	}
+	if config.Wait, err = reader.ParseDuration(query, "wait", DefaultWait); err != nil {
+		return nil, err
+	}
+	if config.RetryDelay, err = reader.ParseDuration(query, "retry_delay", DefaultRetryDelay); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Read reads configuration data from Consul
+func (c *ConsulReader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := c.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads configuration data and reports the extension of the key, or
+// JSON for recursive reads
+func (c *ConsulReader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
+	}
+
+	pairs, _, err := c.get(ctx, 0)
+	if err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
+	content := c.content(pairs)
+	return content.data, content.meta, content.err
+}
+
+// Structured implements reader.MapReader. Recursive reads are read as values.
+func (c *ConsulReader) Structured() bool {
+	return c.config.Recurse
+}
+
+// ReadMap implements reader.MapReader
+func (c *ConsulReader) ReadMap(ctx context.Context) (map[string]any, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	pairs, _, err := c.get(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	content := c.content(pairs)
+	return content.values, content.err
+}
+
+// Subscribe reads the key or prefix and then watches it with blocking
+// queries from the returned X-Consul-Index.
+func (c *ConsulReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	pairs, index, err := c.get(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	eventChan := make(chan *reader.ReadEvent, 1)
+	ctx, cancel := reader.WithClose(ctx, c.closeCtx)
+	go func() {
+		defer cancel()
+		c.watch(ctx, eventChan, pairs, index)
+	}()
+
+	return eventChan, nil
+}
+
+// Close closes the reader and cleans up resources
+func (c *ConsulReader) Close() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.closeCancel()
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// watch reports the current content, then blocks on changes after index and
+// reports the content whenever it changes
+func (c *ConsulReader) watch(ctx context.Context, eventChan chan<- *reader.ReadEvent, pairs []kvPair, index uint64) {
+	defer close(eventChan)
+
+	reporter := reader.NewReporter(eventChan)
+	reporter.Report(ctx, c.content(pairs).event(c.uri))
+
+	index = nextIndex(0, index)
+	for ctx.Err() == nil {
+		next, nextIdx, err := c.get(ctx, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			reporter.Report(ctx, content{err: err}.event(c.uri))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.config.RetryDelay):
+			}
+			continue
+		}
+
+		index = nextIndex(index, nextIdx)
+		reporter.Report(ctx, c.content(next).event(c.uri))
+	}
+}
+
+// nextIndex returns the index of the next blocking query following the
+// Consul rules: an index that went backwards, e.g. after a snapshot restore,
+// restarts with a non-blocking query and an index is never below 1, which
+// would not block.
+func nextIndex(previous, index uint64) uint64 {
+	switch {
+	case index < previous:
+		return 0
+	case index == 0:
+		return 1
+	default:
+		return index
+	}
+}
+
+// get reads the key, or the keys under the prefix. A positive index makes it
+// a blocking query returning after a change past index or the wait time. A
+// missing key is not an error, so the X-Consul-Index of the response is
+// still returned.
+func (c *ConsulReader) get(ctx context.Context, index uint64) ([]kvPair, uint64, error) {
+	query := url.Values{}
+	if c.config.Recurse {
+		query.Set("recurse", "true")
+	}
+	if c.config.Datacenter != "" {
+		query.Set("dc", c.config.Datacenter)
+	}
+	if c.config.Namespace != "" {
+		query.Set("ns", c.config.Namespace)
+	}
+
+	client := c.client
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", strconv.FormatInt(c.config.Wait.Milliseconds(), 10)+"ms")
+		// Consul adds up to wait/16 of jitter to blocking queries
+		blocking := *c.client
+		blocking.Timeout = c.config.Timeout + c.config.Wait + c.config.Wait/16
+		client = &blocking
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.Address+kvPath+escapeKey(c.config.Key)+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create Consul request: %w", err)
+	}
+	token, err := c.token()
+	if err != nil {
+		return nil, 0, err
+	}
+	if token != "" {
+		req.Header.Set(tokenHeader, token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get Consul key '%s': %w", c.config.Key, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var respIndex uint64
+	if value := resp.Header.Get(indexHeader); value != "" {
+		if respIndex, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, 0, fmt.Errorf("invalid %s header: %w", indexHeader, err)
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, respIndex, nil
+	default:
+		return nil, 0, fmt.Errorf("failed to get Consul key '%s': unexpected HTTP status %d: %s", c.config.Key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var pairs []kvPair
+	if err := json.Unmarshal(body, &pairs); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode Consul response: %w", err)
+	}
+	return pairs, respIndex, nil
+}
+
+// token returns the ACL token, reading the token file on every request so
+// that rotated tokens are picked up
+func (c *ConsulReader) token() (string, error) {
+	if c.config.Token != "" || c.config.TokenFile == "" {
+		return c.config.Token, nil
+	}
+	data, err := os.ReadFile(c.config.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// escapeKey escapes each segment of a key for the request path
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// content is the configuration read from the keys
+type content struct {
+	data    []byte
+	meta    reader.ContentMeta
+	values  map[string]any
+	version uint64
+	err     error
+}
+
+// content returns the value of the key, or the values under the prefix. The
+// version is the highest modify index of the keys read.
+func (c *ConsulReader) content(pairs []kvPair) content {
+	if !c.config.Recurse {
+		for _, pair := range pairs {
+			if pair.Key == c.config.Key {
+				return content{
+					data:    pair.Value,
+					meta:    reader.ContentMeta{Extension: filepath.Ext(pair.Key)},
+					version: pair.ModifyIndex,
+				}
+			}
+		}
+		return content{err: fmt.Errorf("key '%s' not found", c.config.Key)}
+	}
+
+	flat := make(map[string]string, len(pairs))
+	var version uint64
+	for _, pair := range pairs {
+		// Keys ending with a slash are folders
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		flat[strings.TrimPrefix(pair.Key, c.config.Key)] = string(pair.Value)
+		version = max(version, pair.ModifyIndex)
+	}
+	if len(flat) == 0 {
+		return content{err: fmt.Errorf("no keys found with prefix '%s'", c.config.Key)}
+	}
+
+	values, err := reader.ExpandPaths(flat)
+	if err != nil {
+		return content{err: err}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return content{err: fmt.Errorf("failed to encode values: %w", err)}
+	}
+	return content{
+		data:    data,
+		meta:    reader.ContentMeta{MIMEType: "application/json", Extension: ".json"},
+		values:  values,
+		version: version,
+	}
+}
+
+// event builds a read event of the content, with the modify index as its
+// revision
+func (c content) event(uri string) *reader.ReadEvent {
+	if c.err != nil {
+		return reader.NewReadEvent(uri, nil, c.err)
+	}
+	return reader.NewReadEvent(uri, c.data, nil).
+		WithMeta(c.meta).
+		WithValues(c.values).
+		WithRevision(strconv.FormatUint(c.version, 10))
+}
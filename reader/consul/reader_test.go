@@ -0,0 +1,452 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+// fakeConsul is a stand-in for the KV API of a Consul agent
+type fakeConsul struct {
+	*httptest.Server
+	t       *testing.T
+	token   string
+	mu      sync.Mutex
+	index   uint64
+	kv      map[string]kvPair
+	changed chan struct{}
+	queries []map[string]string
+}
+
+func newFakeConsul(t *testing.T) *fakeConsul {
+	t.Helper()
+
+	f := &fakeConsul{t: t, index: 1, kv: make(map[string]kvPair), changed: make(chan struct{})}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeConsul) uri(path string) string {
+	return "consul://" + strings.TrimPrefix(f.URL, "http://") + path
+}
+
+func (f *fakeConsul) put(key, value string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.index++
+	f.kv[key] = kvPair{Key: key, Value: []byte(value), ModifyIndex: f.index}
+	f.notify()
+	return f.index
+}
+
+func (f *fakeConsul) delete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.index++
+	delete(f.kv, key)
+	f.notify()
+}
+
+// restore replaces the store as a snapshot restore does, resetting the index
+func (f *fakeConsul) restore(index uint64, kv map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.index = index
+	f.kv = make(map[string]kvPair)
+	for key, value := range kv {
+		f.kv[key] = kvPair{Key: key, Value: []byte(value), ModifyIndex: index}
+	}
+	f.notify()
+}
+
+func (f *fakeConsul) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// requests returns the query parameters of the requests received, only of
+// blocking queries if blocking is set
+func (f *fakeConsul) requests(blocking bool) []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var queries []map[string]string
+	for _, query := range f.queries {
+		if _, ok := query["index"]; ok || !blocking {
+			queries = append(queries, query)
+		}
+	}
+	return queries
+}
+
+func (f *fakeConsul) handle(w http.ResponseWriter, r *http.Request) {
+	if f.token != "" && r.Header.Get(tokenHeader) != f.token {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	query := make(map[string]string)
+	for name := range r.URL.Query() {
+		query[name] = r.URL.Query().Get(name)
+	}
+	f.mu.Lock()
+	f.queries = append(f.queries, query)
+	index, changed := f.index, f.changed
+	f.mu.Unlock()
+
+	if value, ok := query["index"]; ok {
+		wait, err := time.ParseDuration(query["wait"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if want, _ := strconv.ParseUint(value, 10, 64); want >= index {
+			select {
+			case <-changed:
+			case <-time.After(wait):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+
+	f.mu.Lock()
+	key := strings.TrimPrefix(r.URL.Path, kvPath)
+	var pairs []kvPair
+	for name, pair := range f.kv {
+		if name == key || (query["recurse"] == "true" && strings.HasPrefix(name, key)) {
+			pairs = append(pairs, pair)
+		}
+	}
+	w.Header().Set(indexHeader, strconv.FormatUint(f.index, 10))
+	f.mu.Unlock()
+
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func TestParseConsulURI(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+
+	tests := []struct {
+		name      string
+		uri       string
+		env       map[string]string
+		want      ConsulConfig
+		wantError bool
+	}{
+		{
+			name: "single key with default port",
+			uri:  "consul://localhost/config/app.yaml",
+			want: ConsulConfig{Address: "http://localhost:8500", Key: "config/app.yaml"},
+		},
+		{
+			name: "recurse with datacenter and namespace",
+			uri:  "consul://localhost:8500/config/app?recurse=true&datacenter=dc2&namespace=team&server_scheme=https",
+			want: ConsulConfig{Address: "https://localhost:8500", Key: "config/app/", Recurse: true, Datacenter: "dc2", Namespace: "team"},
+		},
+		{
+			name: "token file from URI",
+			uri:  "consul://localhost:8500/config/app.yaml?token_file=" + tokenFile,
+			env:  map[string]string{EnvToken: "env-token"},
+			want: ConsulConfig{Address: "http://localhost:8500", Key: "config/app.yaml", TokenFile: tokenFile},
+		},
+		{
+			name: "token from environment",
+			uri:  "consul://localhost:8500/config/app.yaml",
+			env:  map[string]string{EnvToken: "env-token", EnvTokenFile: tokenFile},
+			want: ConsulConfig{Address: "http://localhost:8500", Key: "config/app.yaml", Token: "env-token"},
+		},
+		{
+			name: "token file from environment",
+			uri:  "consul://localhost:8500/config/app.yaml",
+			env:  map[string]string{EnvTokenFile: tokenFile},
+			want: ConsulConfig{Address: "http://localhost:8500", Key: "config/app.yaml", TokenFile: tokenFile},
+		},
+		{
+			name:      "token in URI",
+			uri:       "consul://localhost:8500/config/app.yaml?token=secret",
+			wantError: true,
+		},
+		{
+			name:      "missing key",
+			uri:       "consul://localhost:8500/",
+			wantError: true,
+		},
+		{
+			name:      "folder without recurse",
+			uri:       "consul://localhost:8500/config/",
+			wantError: true,
+		},
+		{
+			name:      "invalid wait",
+			uri:       "consul://localhost:8500/config/app.yaml?wait=forever",
+			wantError: true,
+		},
+		{
+			name:      "invalid server scheme",
+			uri:       "consul://localhost:8500/config/app.yaml?server_scheme=ftp",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvToken, "")
+			t.Setenv(EnvTokenFile, "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			r, err := NewConsulReader(tt.uri)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("NewConsulReader() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			defer r.Close()
+
+			got := *r.config
+			got.Timeout, got.Wait, got.RetryDelay = 0, 0, 0
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("config = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextIndex(t *testing.T) {
+	tests := []struct {
+		previous, index, want uint64
+	}{
+		{previous: 0, index: 5, want: 5},
+		{previous: 5, index: 7, want: 7},
+		{previous: 5, index: 5, want: 5},
+		{previous: 5, index: 3, want: 0},
+		{previous: 0, index: 0, want: 1},
+	}
+	for _, tt := range tests {
+		if got := nextIndex(tt.previous, tt.index); got != tt.want {
+			t.Errorf("nextIndex(%d, %d) = %d, want %d", tt.previous, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestConsulReaderRead(t *testing.T) {
+	server := newFakeConsul(t)
+	server.token = "secret"
+	server.put("config/app.yaml", "name: app\n")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	r, err := NewConsulReader(server.uri("/config/app.yaml?datacenter=dc2&namespace=team&token_file=" + tokenFile))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	data, meta, err := r.ReadMeta(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != "name: app\n" {
+		t.Errorf("Expected 'name: app', got '%s'", data)
+	}
+	if meta.Extension != ".yaml" {
+		t.Errorf("Expected extension .yaml, got %s", meta.Extension)
+	}
+	if query := server.requests(false)[0]; query["dc"] != "dc2" || query["ns"] != "team" {
+		t.Errorf("Expected dc and ns query parameters, got %v", query)
+	}
+
+	t.Setenv(EnvToken, "wrong")
+	denied, err := NewConsulReader(server.uri("/config/app.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer denied.Close()
+	if _, err := denied.Read(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected 403 error with wrong token, got %v", err)
+	}
+
+	withToken, err := NewConsulReader(server.uri("/config/app.yaml"), WithToken("secret"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer withToken.Close()
+	if _, err := withToken.Read(context.Background()); err != nil {
+		t.Errorf("Expected WithToken to override the environment, got %v", err)
+	}
+
+	t.Setenv(EnvToken, "secret")
+	missing, err := NewConsulReader(server.uri("/config/missing.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer missing.Close()
+	if _, err := missing.Read(context.Background()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestConsulReaderReadMap(t *testing.T) {
+	server := newFakeConsul(t)
+	server.put("config/app/", "")
+	server.put("config/app/name", "app")
+	server.put("config/app/database/host", "localhost")
+	server.put("config/app/database/port", "5432")
+	server.put("config/application", "not under the prefix")
+
+	r, err := NewConsulReader(server.uri("/config/app?recurse=true"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if !r.Structured() {
+		t.Error("Expected recursive reader to be structured")
+	}
+	values, err := r.ReadMap(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read values: %v", err)
+	}
+	want := map[string]any{
+		"name": "app",
+		"database": map[string]any{
+			"host": "localhost",
+			"port": "5432",
+		},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("ReadMap() = %v, want %v", values, want)
+	}
+
+	server.put("config/app/name/first", "conflict")
+	if _, err := r.ReadMap(context.Background()); err == nil || !strings.Contains(err.Error(), "conflicts") {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+}
+
+func TestConsulReaderSubscribe(t *testing.T) {
+	server := newFakeConsul(t)
+	index := server.put("config/app.yaml", "version: 1\n")
+
+	r, err := NewConsulReader(server.uri("/config/app.yaml?wait=1s"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	events, err := r.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if event := readertest.NextEvent(t, events); event.Revision != fmt.Sprint(index) {
+		t.Errorf("Expected revision %d, got %s", index, event.Revision)
+	}
+
+	// Writes of other keys and unchanged values wake the query but report nothing
+	server.put("config/other.yaml", "other")
+	server.put("config/app.yaml", "version: 1\n")
+	readertest.ExpectNoEvent(t, events, 200*time.Millisecond)
+
+	index = server.put("config/app.yaml", "version: 2\n")
+	event := readertest.NextEvent(t, events)
+	if string(event.Data) != "version: 2\n" {
+		t.Errorf("Expected 'version: 2', got '%s' (%v)", event.Data, event.Error)
+	}
+	if event.Revision != fmt.Sprint(index) {
+		t.Errorf("Expected revision %d, got %s", index, event.Revision)
+	}
+
+	queries := server.requests(true)
+	if len(queries) == 0 {
+		t.Fatal("Expected blocking queries")
+	}
+	if queries[0]["wait"] != "1000ms" {
+		t.Errorf("Expected wait 1000ms, got %s", queries[0]["wait"])
+	}
+
+	server.delete("config/app.yaml")
+	if event := readertest.NextEvent(t, events); event.Error == nil {
+		t.Error("Expected error after key was deleted")
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Failed to close reader: %v", err)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected event channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Timeout waiting for event channel to close")
+	}
+}
+
+func TestConsulReaderIndexReset(t *testing.T) {
+	server := newFakeConsul(t)
+	server.restore(100, map[string]string{"config/app/name": "app"})
+
+	r, err := NewConsulReader(server.uri("/config/app?recurse=true&wait=1s"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	events, err := r.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	readertest.NextEvent(t, events)
+
+	// A snapshot restore moves the index backwards
+	server.restore(10, map[string]string{"config/app/name": "restored"})
+	event := readertest.NextEvent(t, events)
+	if !reflect.DeepEqual(event.Values, map[string]any{"name": "restored"}) {
+		t.Errorf("Expected restored values, got %v (%v)", event.Values, event.Error)
+	}
+
+	index := server.put("config/app/name", "updated")
+	event = readertest.NextEvent(t, events)
+	if !reflect.DeepEqual(event.Values, map[string]any{"name": "updated"}) {
+		t.Errorf("Expected updated values, got %v (%v)", event.Values, event.Error)
+	}
+	if event.Revision != fmt.Sprint(index) {
+		t.Errorf("Expected revision %d, got %s", index, event.Revision)
+	}
+
+	// The query after the reset does not block, so it cannot miss changes
+	// made between the old and the new index
+	var indexes []string
+	for _, query := range server.requests(false) {
+		indexes = append(indexes, query["index"])
+	}
+	if len(indexes) < 3 || indexes[1] != "100" || indexes[2] != "" {
+		t.Errorf("Expected a non-blocking query after the blocking query from index 100, got %q", indexes)
+	}
+}
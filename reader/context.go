@@ -0,0 +1,14 @@
+package reader
+
+import "context"
+
+// WithClose derives a context from ctx that is also cancelled when closeCtx,
+// the context of a reader cancelled on Close, is done
+func WithClose(ctx, closeCtx context.Context) (context.Context, context.CancelFunc) {
+	derived, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(closeCtx, cancel)
+	return derived, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,472 @@
+// Package etcd provides a configuration reader implementation for etcd v3.
+// It reads a single key, or all keys under a prefix as nested values, and
+// subscribes to changes with the native watch API.
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// SchemeEtcd represents etcd URI scheme
+	SchemeEtcd reader.Scheme = "etcd"
+	// SchemeEtcds represents etcd TLS URI scheme
+	SchemeEtcds reader.Scheme = "etcds"
+
+	// DefaultPort is the etcd client port
+	DefaultPort = "2379"
+)
+
+var (
+	// DefaultTimeout for connecting to etcd
+	DefaultTimeout = 5 * time.Second
+	// DefaultRetryDelay before re-establishing a failed watch, doubled on
+	// every consecutive failure
+	DefaultRetryDelay = 1 * time.Second
+	// DefaultMaxRetryDelay caps the delay between watch attempts
+	DefaultMaxRetryDelay = 30 * time.Second
+)
+
+// init registers etcd readers
+func init() {
+	for _, scheme := range []reader.Scheme{SchemeEtcd, SchemeEtcds} {
+		_ = reader.RegisterReader(scheme, func(uri string) (reader.ConfReader, error) {
+			return NewEtcdReader(uri)
+		})
+	}
+}
+
+// EtcdConfig holds etcd client configuration
+type EtcdConfig struct {
+	Endpoints  []string
+	Username   string
+	Password   string
+	Key        string // Key, or key prefix when Prefix is set
+	Prefix     bool   // Read all keys under Key as nested values
+	Timeout    time.Duration
+	RetryDelay time.Duration
+	TLSConfig  *tls.Config
+}
+
+// EtcdReader implements ConfReader for etcd-based configuration
+type EtcdReader struct {
+	uri         string
+	config      *EtcdConfig
+	client      *clientv3.Client
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+	mu          sync.RWMutex
+	closed      bool
+}
+
+// NewEtcdReader creates a new etcd reader.
+// URI format: etcd[s]://[user:password@]host:port[,host:port...]/key[?prefix=true]
+// The key is the path without its leading slash; keys starting with a slash
+// are written as //key. With prefix=true, the keys under /key/ are read as values nested by their
+// path segments.
+func NewEtcdReader(uri string) (*EtcdReader, error) {
+	config, err := newEtcdConfig(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   config.Endpoints,
+		DialTimeout: config.Timeout,
+		Username:    config.Username,
+		Password:    config.Password,
+		TLS:         config.TLSConfig,
+		Logger:      zap.NewNop(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create etcd client: %w", err)
+	}
+
+	closeCtx, closeCancel := context.WithCancel(context.Background())
+	return &EtcdReader{
+		uri:         uri,
+		config:      config,
+		client:      client,
+		closeCtx:    closeCtx,
+		closeCancel: closeCancel,
+	}, nil
+}
+
+func newEtcdConfig(uri string) (*EtcdConfig, error) {
+	u, err := reader.ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != string(SchemeEtcd) && u.Scheme != string(SchemeEtcds) {
+		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s or %s", u.Scheme, SchemeEtcd, SchemeEtcds)
+	}
+
+	config := &EtcdConfig{
+		Timeout:    DefaultTimeout,
+		RetryDelay: DefaultRetryDelay,
+	}
+	if err := parseEtcdURI(u, config); err != nil {
+		return nil, fmt.Errorf("failed to parse etcd URI: %w", err)
+	}
+
+	return config, nil
+}
+
+func parseEtcdURI(u *url.URL, config *EtcdConfig) error {
+	scheme := "http://"
+	if u.Scheme == string(SchemeEtcds) {
+		scheme = "https://"
+	}
+	for _, host := range reader.Hosts(u) {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, DefaultPort)
+		}
+		config.Endpoints = append(config.Endpoints, scheme+host)
+	}
+	if len(config.Endpoints) == 0 {
+		return fmt.Errorf("etcd endpoint must be specified in host")
+	}
+
+	// Extract credentials from userinfo
+	if u.User != nil {
+		config.Username = u.User.Username()
+		config.Password, _ = u.User.Password()
+	}
+
+	// Extract key from path. A key with a leading slash is written with a
+	// doubled slash, e.g. etcd://host//config/app.yaml
+	config.Key = strings.TrimPrefix(u.Path, "/")
+	if config.Key == "" {
+		return fmt.Errorf("etcd key must be specified in path")
+	}
+
+	// Parse query parameters
+	query := u.Query()
+
+	if prefixStr := query.Get("prefix"); prefixStr != "" {
+		prefix, err := strconv.ParseBool(prefixStr)
+		if err != nil {
+			return fmt.Errorf("invalid prefix format: %w", err)
+		}
+		config.Prefix = prefix
+	}
+	if config.Prefix && !strings.HasSuffix(config.Key, "/") {
+		config.Key += "/"
+	}
+
+	// Parse timeout
+	var err error
+	if config.Timeout, err = reader.ParseDuration(query, "timeout", config.Timeout); err != nil {
+		return err
+	}
+
+	// Parse retry delay
+	if config.RetryDelay, err = reader.ParseDuration(query, "retry_delay", config.RetryDelay); err != nil {
+		return err
+	}
+
+	// Configure TLS for etcds scheme
+	if u.Scheme == string(SchemeEtcds) {
+		tlsConfig, err := parseTLSConfig(query)
+		if err != nil {
+			return err
+		}
+		config.TLSConfig = tlsConfig
+	}
+
+	return nil
+}
+
+// parseTLSConfig builds the TLS configuration from the tls_ca, tls_cert,
+// tls_key, tls_server_name and tls_insecure query parameters
+func parseTLSConfig(query url.Values) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: query.Get("tls_server_name"),
+	}
+
+	// Parse TLS insecure option
+	if insecureStr := query.Get("tls_insecure"); insecureStr == "true" {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if caFile := query.Get("tls_ca"); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certFile, keyFile := query.Get("tls_cert"), query.Get("tls_key")
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("both client certificate and key must be specified")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Read reads configuration data from etcd
+func (e *EtcdReader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := e.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads configuration data and reports the extension of the key, or
+// JSON for prefixes
+func (e *EtcdReader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closed {
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
+	}
+
+	kvs, _, err := e.fetch(ctx)
+	if err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
+	content := e.content(kvs)
+	return content.data, content.meta, content.err
+}
+
+// Structured implements reader.MapReader. Prefixes are read as values.
+func (e *EtcdReader) Structured() bool {
+	return e.config.Prefix
+}
+
+// ReadMap implements reader.MapReader
+func (e *EtcdReader) ReadMap(ctx context.Context) (map[string]any, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	kvs, _, err := e.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	content := e.content(kvs)
+	return content.values, content.err
+}
+
+// Subscribe reads the key or prefix and watches it from the revision read,
+// resuming the watch from the last seen revision after disconnects.
+func (e *EtcdReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	kvs, revision, err := e.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	eventChan := make(chan *reader.ReadEvent, 1)
+	go e.watch(ctx, eventChan, kvs, revision)
+
+	return eventChan, nil
+}
+
+// Close closes the reader and cleans up resources
+func (e *EtcdReader) Close() error {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil
+	}
+
+	e.closed = true
+	e.closeCancel()
+
+	return e.client.Close()
+}
+
+// fetch reads the key, or the keys under the prefix, and returns them by key
+// with the revision of the store they were read at
+func (e *EtcdReader) fetch(ctx context.Context) (map[string]*mvccpb.KeyValue, int64, error) {
+	var opts []clientv3.OpOption
+	if e.config.Prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+
+	resp, err := e.client.Get(ctx, e.config.Key, opts...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get key '%s': %w", e.config.Key, err)
+	}
+
+	kvs := make(map[string]*mvccpb.KeyValue, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = kv
+	}
+	return kvs, resp.Header.Revision, nil
+}
+
+// watch reports the current content, then applies watched changes to kvs and
+// reports the content whenever it changes.
+func (e *EtcdReader) watch(ctx context.Context, eventChan chan<- *reader.ReadEvent, kvs map[string]*mvccpb.KeyValue, revision int64) {
+	defer close(eventChan)
+
+	ctx, cancel := reader.WithClose(ctx, e.closeCtx)
+	defer cancel()
+
+	reporter := reader.NewReporter(eventChan)
+	reporter.Report(ctx, e.content(kvs).event(e.uri))
+
+	opts := []clientv3.OpOption{clientv3.WithPrevKV()}
+	if e.config.Prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+
+	delay := e.config.RetryDelay
+	for ctx.Err() == nil {
+		compacted := false
+		// Each watch has its own context, cancelled before watching again so
+		// the abandoned watch stream is released. Leader loss ends the watch
+		// instead of stalling on an isolated member.
+		watchCtx, cancelWatch := context.WithCancel(ctx)
+		watchChan := e.client.Watch(clientv3.WithRequireLeader(watchCtx), e.config.Key,
+			append(opts, clientv3.WithRev(revision+1))...)
+		for resp := range watchChan {
+			if resp.CompactRevision != 0 {
+				compacted = true
+				break
+			}
+			if resp.Err() != nil {
+				break
+			}
+
+			for _, event := range resp.Events {
+				if event.Type == clientv3.EventTypeDelete {
+					delete(kvs, string(event.Kv.Key))
+				} else {
+					kvs[string(event.Kv.Key)] = event.Kv
+				}
+			}
+			revision = resp.Header.Revision
+			delay = e.config.RetryDelay
+			reporter.Report(ctx, e.content(kvs).event(e.uri))
+		}
+		cancelWatch()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, DefaultMaxRetryDelay)
+
+		// Changes since the last seen revision were compacted, read the
+		// current content again
+		if compacted {
+			fresh, freshRevision, err := e.fetch(ctx)
+			if err != nil {
+				reporter.Report(ctx, content{err: err}.event(e.uri))
+				continue
+			}
+			kvs, revision = fresh, freshRevision
+			reporter.Report(ctx, e.content(kvs).event(e.uri))
+		}
+	}
+}
+
+// content is the configuration read from the keys
+type content struct {
+	data    []byte
+	meta    reader.ContentMeta
+	values  map[string]any
+	version int64
+	err     error
+}
+
+// content returns the value of the key, or the values under the prefix. The
+// version is the highest mod revision of the keys read.
+func (e *EtcdReader) content(kvs map[string]*mvccpb.KeyValue) content {
+	if !e.config.Prefix {
+		kv, ok := kvs[e.config.Key]
+		if !ok {
+			return content{err: fmt.Errorf("key '%s' not found", e.config.Key)}
+		}
+		return content{
+			data:    kv.Value,
+			meta:    reader.ContentMeta{Extension: filepath.Ext(e.config.Key)},
+			version: kv.ModRevision,
+		}
+	}
+
+	if len(kvs) == 0 {
+		return content{err: fmt.Errorf("no keys found with prefix '%s'", e.config.Key)}
+	}
+
+	flat := make(map[string]string, len(kvs))
+	var version int64
+	for key, kv := range kvs {
+		flat[strings.TrimPrefix(key, e.config.Key)] = string(kv.Value)
+		version = max(version, kv.ModRevision)
+	}
+	values, err := reader.ExpandPaths(flat)
+	if err != nil {
+		return content{err: err}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return content{err: fmt.Errorf("failed to encode values: %w", err)}
+	}
+	return content{
+		data:    data,
+		meta:    reader.ContentMeta{MIMEType: "application/json", Extension: ".json"},
+		values:  values,
+		version: version,
+	}
+}
+
+// event builds a read event of the content, with the mod revision as its
+// revision
+func (c content) event(uri string) *reader.ReadEvent {
+	if c.err != nil {
+		return reader.NewReadEvent(uri, nil, c.err)
+	}
+	return reader.NewReadEvent(uri, c.data, nil).
+		WithMeta(c.meta).
+		WithValues(c.values).
+		WithRevision(strconv.FormatInt(c.version, 10))
+}
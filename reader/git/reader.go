@@ -0,0 +1,491 @@
+// Package git provides a configuration reader implementation for files in Git
+// repositories. It keeps a shallow clone of a branch or tag in a cache
+// directory and subscribes to changes by polling the commit of the ref.
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/sower-proxy/feconf/reader"
+)
+
+const (
+	// SchemeGitHTTPS represents Git over HTTPS URI scheme
+	SchemeGitHTTPS reader.Scheme = "git+https"
+	// SchemeGitHTTP represents Git over HTTP URI scheme
+	SchemeGitHTTP reader.Scheme = "git+http"
+	// SchemeGitFile represents local Git repository URI scheme
+	SchemeGitFile reader.Scheme = "git+file"
+
+	// EnvPassword and EnvPasswordFile name the environment variables of the
+	// password or access token used when the URI sets no password_file
+	EnvPassword     = "GIT_PASSWORD"
+	EnvPasswordFile = "GIT_PASSWORD_FILE"
+
+	remoteName = "origin"
+)
+
+var (
+	// DefaultTimeout for listing and fetching the remote repository
+	DefaultTimeout = 30 * time.Second
+	// DefaultPollInterval between checks of the commit of the ref
+	DefaultPollInterval = 1 * time.Minute
+)
+
+// cacheLocks serializes fetches into the same cache directory
+var cacheLocks sync.Map
+
+// init registers Git readers
+func init() {
+	for _, scheme := range []reader.Scheme{SchemeGitHTTPS, SchemeGitHTTP, SchemeGitFile} {
+		_ = reader.RegisterReader(scheme, func(uri string) (reader.ConfReader, error) {
+			return NewGitReader(uri)
+		})
+	}
+}
+
+// GitConfig holds Git reader configuration
+type GitConfig struct {
+	Repository   string // Remote URL without credentials, e.g. https://host/repo.git
+	Path         string // Path of the file in the repository
+	Ref          string // Branch, tag or full ref name, or empty for the remote HEAD
+	CacheDir     string // Directory of the bare shallow clone
+	Username     string
+	Password     string
+	PasswordFile string // File holding the password, read on every request
+	CABundle     []byte
+	TLSInsecure  bool
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// GitReader implements ConfReader for configuration files in Git repositories
+type GitReader struct {
+	uri         string
+	config      *GitConfig
+	repo        *gogit.Repository
+	cacheLock   *sync.Mutex
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+	mu          sync.RWMutex
+	closed      bool
+}
+
+// NewGitReader creates a new Git reader.
+// URI format: git+https://[user@]host/path/repo.git//path/to/config.yaml[?ref=main&poll_interval=1m&password_file=...]
+// or git+file:///path/to/repo.git//config.yaml. The double slash separates
+// the repository from the path of the file in it.
+func NewGitReader(uri string) (*GitReader, error) {
+	u, err := reader.ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	switch reader.Scheme(u.Scheme) {
+	case SchemeGitHTTPS, SchemeGitHTTP, SchemeGitFile:
+	default:
+		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s, %s or %s", u.Scheme, SchemeGitHTTPS, SchemeGitHTTP, SchemeGitFile)
+	}
+
+	config, err := parseGitURI(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Git URI: %w", err)
+	}
+
+	repo, err := openCache(config)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, _ := cacheLocks.LoadOrStore(config.CacheDir, &sync.Mutex{})
+	closeCtx, closeCancel := context.WithCancel(context.Background())
+	return &GitReader{
+		uri:         uri,
+		config:      config,
+		repo:        repo,
+		cacheLock:   lock.(*sync.Mutex),
+		closeCtx:    closeCtx,
+		closeCancel: closeCancel,
+	}, nil
+}
+
+func parseGitURI(u *url.URL) (*GitConfig, error) {
+	repoPath, filePath, found := strings.Cut(u.Path, "//")
+	filePath = strings.Trim(filePath, "/")
+	if !found || repoPath == "" || filePath == "" {
+		return nil, fmt.Errorf("path must be {repository}//{file}")
+	}
+	if u.Scheme != string(SchemeGitFile) && u.Host == "" {
+		return nil, fmt.Errorf("host must be specified")
+	}
+
+	remote := url.URL{
+		Scheme: strings.TrimPrefix(u.Scheme, "git+"),
+		Host:   u.Host,
+		Path:   repoPath,
+	}
+	query := u.Query()
+	config := &GitConfig{
+		Repository:   remote.String(),
+		Path:         path.Clean(filePath),
+		Ref:          query.Get("ref"),
+		CacheDir:     query.Get("cache_dir"),
+		PasswordFile: query.Get("password_file"),
+		TLSInsecure:  query.Get("tls_insecure") == "true",
+	}
+
+	// URIs are reported as the source of every event, so the password itself
+	// is only taken from the environment, as for the Consul token
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return nil, fmt.Errorf("password must not be set in the URI, use %s or password_file", EnvPassword)
+		}
+		config.Username = u.User.Username()
+	}
+	if config.PasswordFile == "" {
+		config.Password = os.Getenv(EnvPassword)
+		if config.Password == "" {
+			config.PasswordFile = os.Getenv(EnvPasswordFile)
+		}
+	}
+
+	if caFile := query.Get("tls_ca"); caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls_ca: %w", err)
+		}
+		config.CABundle = ca
+	}
+
+	if config.CacheDir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			cacheDir = os.TempDir()
+		}
+		sum := sha256.Sum256([]byte(config.Repository))
+		config.CacheDir = filepath.Join(cacheDir, "feconf", "git", hex.EncodeToString(sum[:8]))
+	}
+
+	var err error
+	if config.Timeout, err = reader.ParseDuration(query, "timeout", DefaultTimeout); err != nil {
+		return nil, err
+	}
+	if config.PollInterval, err = reader.ParseDuration(query, "poll_interval", DefaultPollInterval); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// openCache opens the bare repository in the cache directory, initializing
+// it and its remote on first use
+func openCache(config *GitConfig) (*gogit.Repository, error) {
+	repo, err := gogit.PlainOpen(config.CacheDir)
+	if errors.Is(err, gogit.ErrRepositoryNotExists) {
+		repo, err = gogit.PlainInit(config.CacheDir, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache '%s': %w", config.CacheDir, err)
+	}
+
+	remote, err := repo.Remote(remoteName)
+	if err == nil && len(remote.Config().URLs) == 1 && remote.Config().URLs[0] == config.Repository {
+		return repo, nil
+	}
+	if err == nil {
+		if err := repo.DeleteRemote(remoteName); err != nil {
+			return nil, fmt.Errorf("failed to update cache remote: %w", err)
+		}
+	}
+	if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{config.Repository},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create cache remote: %w", err)
+	}
+	return repo, nil
+}
+
+// Read reads configuration data from Git
+func (g *GitReader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := g.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads configuration data and reports the extension of the file
+func (g *GitReader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.closed {
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
+	}
+
+	content := g.read(ctx, plumbing.ZeroHash)
+	return content.data, content.meta, content.err
+}
+
+// Subscribe reads the file and then polls the commit of the ref, reading
+// the file again whenever the commit changes. Commits that do not change the
+// file are not reported.
+func (g *GitReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	current := g.read(ctx, plumbing.ZeroHash)
+	if current.err != nil {
+		return nil, current.err
+	}
+
+	eventChan := make(chan *reader.ReadEvent, 1)
+	ctx, cancel := reader.WithClose(ctx, g.closeCtx)
+	go func() {
+		defer cancel()
+		g.watch(ctx, eventChan, current)
+	}()
+
+	return eventChan, nil
+}
+
+// Close closes the reader and cleans up resources. The cache directory is
+// kept for the next reader of the repository.
+func (g *GitReader) Close() error {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+
+	g.closed = true
+	g.closeCancel()
+	return nil
+}
+
+// watch reports the current content, then polls the ref and reports the
+// content whenever it changes
+func (g *GitReader) watch(ctx context.Context, eventChan chan<- *reader.ReadEvent, current content) {
+	defer close(eventChan)
+
+	reporter := reader.NewReporter(eventChan)
+	reporter.Report(ctx, current.event(g.uri))
+
+	commit := current.commit
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(g.config.PollInterval):
+		}
+
+		next := g.read(ctx, commit)
+		if ctx.Err() != nil {
+			return
+		}
+		if next.err == nil {
+			if next.commit == commit {
+				continue
+			}
+			commit = next.commit
+		}
+		reporter.Report(ctx, next.event(g.uri))
+	}
+}
+
+// read resolves the ref and reads the file at its commit. When the commit is
+// still unchanged, content without data is returned without fetching.
+func (g *GitReader) read(ctx context.Context, unchanged plumbing.Hash) content {
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout)
+	defer cancel()
+
+	refName, hash, err := g.resolve(ctx)
+	if err != nil {
+		return content{err: err}
+	}
+	if hash == unchanged {
+		return content{commit: hash}
+	}
+
+	commit, err := g.fetch(ctx, refName, hash)
+	if err != nil {
+		return content{err: err}
+	}
+
+	file, err := commit.File(g.config.Path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return content{err: fmt.Errorf("file '%s' not found at %s", g.config.Path, hash)}
+	}
+	if err != nil {
+		return content{err: fmt.Errorf("failed to read file '%s' at %s: %w", g.config.Path, hash, err)}
+	}
+	r, err := file.Reader()
+	if err != nil {
+		return content{err: fmt.Errorf("failed to read file '%s' at %s: %w", g.config.Path, hash, err)}
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return content{err: fmt.Errorf("failed to read file '%s' at %s: %w", g.config.Path, hash, err)}
+	}
+
+	return content{
+		data:   data,
+		meta:   reader.ContentMeta{Extension: filepath.Ext(g.config.Path)},
+		commit: hash,
+	}
+}
+
+// resolve lists the refs of the remote and returns the name of the
+// configured ref and its commit, peeling annotated tags
+func (g *GitReader) resolve(ctx context.Context) (plumbing.ReferenceName, plumbing.Hash, error) {
+	remote, err := g.repo.Remote(remoteName)
+	if err != nil {
+		return "", plumbing.ZeroHash, fmt.Errorf("failed to get cache remote: %w", err)
+	}
+	auth, err := g.auth()
+	if err != nil {
+		return "", plumbing.ZeroHash, err
+	}
+	refs, err := remote.ListContext(ctx, &gogit.ListOptions{
+		Auth:            auth,
+		InsecureSkipTLS: g.config.TLSInsecure,
+		CABundle:        g.config.CABundle,
+		PeelingOption:   gogit.AppendPeeled,
+	})
+	if err != nil {
+		return "", plumbing.ZeroHash, fmt.Errorf("failed to list refs of '%s': %w", g.config.Repository, err)
+	}
+
+	hashes := make(map[plumbing.ReferenceName]plumbing.Hash, len(refs))
+	var head plumbing.ReferenceName
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			head = ref.Target()
+			continue
+		}
+		hashes[ref.Name()] = ref.Hash()
+	}
+
+	candidates := []plumbing.ReferenceName{head}
+	switch {
+	case g.config.Ref == "":
+		if head == "" {
+			return "", plumbing.ZeroHash, fmt.Errorf("remote '%s' has no HEAD, ref must be specified", g.config.Repository)
+		}
+	case strings.HasPrefix(g.config.Ref, "refs/"):
+		candidates = []plumbing.ReferenceName{plumbing.ReferenceName(g.config.Ref)}
+	default:
+		candidates = []plumbing.ReferenceName{
+			plumbing.NewBranchReferenceName(g.config.Ref),
+			plumbing.NewTagReferenceName(g.config.Ref),
+		}
+	}
+
+	for _, name := range candidates {
+		if peeled, ok := hashes[name+"^{}"]; ok {
+			return name, peeled, nil
+		}
+		if hash, ok := hashes[name]; ok {
+			return name, hash, nil
+		}
+	}
+	return "", plumbing.ZeroHash, fmt.Errorf("ref '%s' not found in '%s'", g.config.Ref, g.config.Repository)
+}
+
+// fetch returns the commit, fetching the ref with depth 1 unless the commit
+// is already in the cache
+func (g *GitReader) fetch(ctx context.Context, refName plumbing.ReferenceName, hash plumbing.Hash) (*object.Commit, error) {
+	g.cacheLock.Lock()
+	defer g.cacheLock.Unlock()
+
+	if commit, err := g.repo.CommitObject(hash); err == nil {
+		return commit, nil
+	}
+
+	auth, err := g.auth()
+	if err != nil {
+		return nil, err
+	}
+	err = g.repo.FetchContext(ctx, &gogit.FetchOptions{
+		RemoteName:      remoteName,
+		RefSpecs:        []gitconfig.RefSpec{gitconfig.RefSpec("+" + refName + ":" + refName)},
+		Depth:           1,
+		Tags:            gogit.NoTags,
+		Force:           true,
+		Auth:            auth,
+		InsecureSkipTLS: g.config.TLSInsecure,
+		CABundle:        g.config.CABundle,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to fetch '%s' from '%s': %w", refName.Short(), g.config.Repository, err)
+	}
+
+	commit, err := g.repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+	return commit, nil
+}
+
+// auth returns the basic credentials, e.g. a user and an access token,
+// reading the password file on every request so that rotated tokens are
+// picked up
+func (g *GitReader) auth() (transport.AuthMethod, error) {
+	password := g.config.Password
+	if password == "" && g.config.PasswordFile != "" {
+		data, err := os.ReadFile(g.config.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password file: %w", err)
+		}
+		password = strings.TrimSpace(string(data))
+	}
+	if g.config.Username == "" && password == "" {
+		return nil, nil
+	}
+	return &githttp.BasicAuth{Username: g.config.Username, Password: password}, nil
+}
+
+// content is the configuration read from the file at a commit
+type content struct {
+	data   []byte
+	meta   reader.ContentMeta
+	commit plumbing.Hash
+	err    error
+}
+
+// event builds a read event of the content, with the commit SHA as its
+// revision
+func (c content) event(uri string) *reader.ReadEvent {
+	if c.err != nil {
+		return reader.NewReadEvent(uri, nil, c.err)
+	}
+	return reader.NewReadEvent(uri, c.data, nil).
+		WithMeta(c.meta).
+		WithRevision(c.commit.String())
+}
@@ -0,0 +1,313 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenRefreshBefore renews OAuth2 tokens this long before they expire
+const DefaultTokenRefreshBefore = 30 * time.Second
+
+// errTokenExpired ends a long-lived connection so it reconnects with a fresh token
+var errTokenExpired = errors.New("access token expired")
+
+// Token is a credential sent in the Authorization header
+type Token struct {
+	AccessToken string
+	// TokenType is the authorization scheme, Bearer when empty
+	TokenType string
+	// Expiry is when the token stops being valid, zero if unknown
+	Expiry time.Time
+}
+
+// authorization returns the Authorization header value
+func (t *Token) authorization() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// TokenSource supplies tokens for HTTP and streaming requests. Implementations
+// must be safe for concurrent use and return a token that is currently valid.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// WithTokenSource authenticates every request with tokens from ts
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *HTTPConfig) { c.TokenSource = ts }
+}
+
+// staticTokenSource always returns the same token
+type staticTokenSource struct {
+	token *Token
+}
+
+// StaticTokenSource returns a TokenSource for a fixed bearer token
+func StaticTokenSource(accessToken string) TokenSource {
+	return &staticTokenSource{token: &Token{AccessToken: accessToken}}
+}
+
+// Token returns the fixed token
+func (s *staticTokenSource) Token(context.Context) (*Token, error) {
+	return s.token, nil
+}
+
+// fileTokenSource reads a bearer token from a file and re-reads it after it
+// changes, as Kubernetes does when rotating projected service account tokens
+type fileTokenSource struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	token   *Token
+}
+
+// NewFileTokenSource returns a TokenSource backed by the token file at path
+func NewFileTokenSource(path string) TokenSource {
+	return &fileTokenSource{path: path}
+}
+
+// Token returns the current file content as a bearer token
+func (f *fileTokenSource) Token(context.Context) (*Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("token file access error: %w", err)
+	}
+	if f.token != nil && info.ModTime().Equal(f.modTime) {
+		return f.token, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+	accessToken := strings.TrimSpace(string(data))
+	if accessToken == "" {
+		return nil, fmt.Errorf("empty token file: %s", f.path)
+	}
+
+	f.token = &Token{AccessToken: accessToken}
+	f.modTime = info.ModTime()
+	return f.token, nil
+}
+
+// ClientCredentialsConfig describes an OAuth2 client credentials grant
+type ClientCredentialsConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// ClientSecretFile holds the client secret instead of ClientSecret. It is
+	// read on every token request, so a rotated secret is picked up.
+	ClientSecretFile string
+	Scopes           []string
+	// RefreshBefore renews the token this long before it expires, capped at
+	// half of the token lifetime
+	RefreshBefore time.Duration
+}
+
+// clientCredentialsTokenSource fetches and caches OAuth2 client credentials tokens
+type clientCredentialsTokenSource struct {
+	config    ClientCredentialsConfig
+	client    *http.Client
+	mu        sync.Mutex
+	token     *Token
+	refreshAt time.Time
+}
+
+// NewClientCredentialsTokenSource returns a TokenSource using the OAuth2
+// client credentials flow. A nil client uses http.DefaultClient.
+func NewClientCredentialsTokenSource(config ClientCredentialsConfig, client *http.Client) TokenSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if config.RefreshBefore <= 0 {
+		config.RefreshBefore = DefaultTokenRefreshBefore
+	}
+	return &clientCredentialsTokenSource{config: config, client: client}
+}
+
+// Token returns the cached token, requesting a new one when it is about to expire
+func (c *clientCredentialsTokenSource) Token(ctx context.Context) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != nil && time.Now().Before(c.refreshAt) {
+		return c.token, nil
+	}
+
+	token, err := c.requestToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.token = token
+	c.refreshAt = token.Expiry
+	if !token.Expiry.IsZero() {
+		refreshBefore := min(c.config.RefreshBefore, time.Until(token.Expiry)/2)
+		c.refreshAt = token.Expiry.Add(-refreshBefore)
+	} else {
+		// Without expires_in the token is reused until the process restarts
+		c.refreshAt = time.Now().Add(100 * 365 * 24 * time.Hour)
+	}
+
+	return c.token, nil
+}
+
+// requestToken performs the token endpoint request
+func (c *clientCredentialsTokenSource) requestToken(ctx context.Context) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(c.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	clientSecret := c.config.ClientSecret
+	if c.config.ClientSecretFile != "" {
+		if clientSecret, err = readSecretFile(c.config.ClientSecretFile); err != nil {
+			return nil, err
+		}
+	}
+	req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(clientSecret))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		if result.Error != "" {
+			return nil, fmt.Errorf("token request failed with status %d: %s %s", resp.StatusCode, result.Error, result.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token request failed with status: %d %s", resp.StatusCode, resp.Status)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("empty access token in token response")
+	}
+
+	token := &Token{
+		AccessToken: result.AccessToken,
+		TokenType:   result.TokenType,
+	}
+	if result.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// authorize sets the Authorization header from the token source, if configured
+func (h *HTTPReader) authorize(ctx context.Context, header http.Header) (*Token, error) {
+	if h.config.TokenSource == nil {
+		return nil, nil
+	}
+
+	token, err := h.config.TokenSource.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	header.Set("Authorization", token.authorization())
+
+	return token, nil
+}
+
+// expireAfter calls fn when token expires and returns a function that cancels it
+func expireAfter(token *Token, fn func()) (stop func() bool) {
+	if token == nil || token.Expiry.IsZero() {
+		return func() bool { return false }
+	}
+	return time.AfterFunc(time.Until(token.Expiry), fn).Stop
+}
+
+// parseTokenSource builds a token source from URI query parameters. Secrets
+// are read from files so they do not appear in the URI.
+func parseTokenSource(query url.Values, config *HTTPConfig) error {
+	tokenFile := query.Get("token_file")
+	tokenURL := query.Get("oauth2_token_url")
+
+	switch {
+	case tokenFile != "" && tokenURL != "":
+		return fmt.Errorf("token_file and oauth2_token_url are mutually exclusive")
+	case tokenFile != "":
+		config.TokenSource = NewFileTokenSource(tokenFile)
+	case tokenURL != "":
+		clientID := query.Get("oauth2_client_id")
+		if clientID == "" {
+			return fmt.Errorf("oauth2_client_id is required with oauth2_token_url")
+		}
+
+		secretFile := query.Get("oauth2_client_secret_file")
+		if secretFile != "" {
+			if _, err := readSecretFile(secretFile); err != nil {
+				return err
+			}
+		}
+
+		var scopes []string
+		if scopeStr := query.Get("oauth2_scopes"); scopeStr != "" {
+			for scope := range strings.SplitSeq(scopeStr, ",") {
+				if scope = strings.TrimSpace(scope); scope != "" {
+					scopes = append(scopes, scope)
+				}
+			}
+		}
+
+		// The client is set to the one of the reader once its transport is
+		// built, so token requests use the same TLS settings
+		config.TokenSource = &clientCredentialsTokenSource{config: ClientCredentialsConfig{
+			TokenURL:         tokenURL,
+			ClientID:         clientID,
+			ClientSecretFile: secretFile,
+			Scopes:           scopes,
+			RefreshBefore:    DefaultTokenRefreshBefore,
+		}}
+	}
+
+	return nil
+}
+
+// readSecretFile reads a secret from a file, trimming surrounding whitespace
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read oauth2_client_secret_file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
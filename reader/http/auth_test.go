@@ -0,0 +1,293 @@
+package http
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+// newTokenServer starts an OAuth2 token endpoint issuing token-1, token-2, ...
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != "client" || clientSecret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client", "error_description": "bad credentials"}`)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		if r.Form.Get("grant_type") != "client_credentials" {
+			t.Errorf("Unexpected grant_type: %s", r.Form.Get("grant_type"))
+		}
+		if r.Form.Get("scope") != "config.read config.watch" {
+			t.Errorf("Unexpected scope: %s", r.Form.Get("scope"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": %d}`, issued.Add(1), expiresIn)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &issued
+}
+
+// newAuthEchoServer responds with the Authorization header of each request
+func newAuthEchoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestHTTPReader_FileTokenSource(t *testing.T) {
+	server := newAuthEchoServer(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeFile(t, tokenFile, []byte("first\n"))
+
+	httpReader, err := NewHTTPReader(server.URL + "?token_file=" + url.QueryEscape(tokenFile))
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	data, err := httpReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != "Bearer first" {
+		t.Errorf("Expected Bearer first, got %s", string(data))
+	}
+
+	writeFile(t, tokenFile, []byte("second-token"))
+
+	data, err = httpReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read after rotation: %v", err)
+	}
+	if string(data) != "Bearer second-token" {
+		t.Errorf("Expected rotated token, got %s", string(data))
+	}
+}
+
+func TestHTTPReader_StaticTokenSourceOverridesBasicAuth(t *testing.T) {
+	server := newAuthEchoServer(t)
+
+	uri := strings.Replace(server.URL, "http://", "http://user:pass@", 1)
+	httpReader, err := NewHTTPReader(uri, WithTokenSource(StaticTokenSource("static")))
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	data, err := httpReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != "Bearer static" {
+		t.Errorf("Expected Bearer static, got %s", string(data))
+	}
+}
+
+func TestClientCredentialsTokenSource(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 1)
+
+	ts := NewClientCredentialsTokenSource(ClientCredentialsConfig{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"config.read", "config.watch"},
+	}, nil)
+
+	token, err := ts.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get token: %v", err)
+	}
+	if token.AccessToken != "token-1" || token.authorization() != "Bearer token-1" {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	// Cached until half of the one second lifetime has passed
+	if token, _ = ts.Token(context.Background()); token.AccessToken != "token-1" {
+		t.Errorf("Expected cached token, got %s", token.AccessToken)
+	}
+
+	time.Sleep(600 * time.Millisecond)
+	if token, _ = ts.Token(context.Background()); token.AccessToken != "token-2" {
+		t.Errorf("Expected refreshed token, got %s", token.AccessToken)
+	}
+	if issued.Load() != 2 {
+		t.Errorf("Expected 2 token requests, got %d", issued.Load())
+	}
+
+	bad := NewClientCredentialsTokenSource(ClientCredentialsConfig{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "client",
+		ClientSecret: "wrong",
+	}, nil)
+	if _, err := bad.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("Expected invalid_client error, got %v", err)
+	}
+}
+
+func TestHTTPReader_OAuth2SSERefresh(t *testing.T) {
+	tokenServer, _ := newTokenServer(t, 1)
+
+	authorizations := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations <- r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: config-update\ndata: auth: %s\n\n", r.Header.Get("Authorization"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	writeFile(t, secretFile, []byte("s3cret\n"))
+
+	query := url.Values{}
+	query.Set("oauth2_token_url", tokenServer.URL)
+	query.Set("oauth2_client_id", "client")
+	query.Set("oauth2_client_secret_file", secretFile)
+	query.Set("oauth2_scopes", "config.read,config.watch")
+
+	httpReader, err := NewHTTPReader(server.URL + "?" + query.Encode())
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := httpReader.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	for _, want := range []string{"auth: Bearer token-1", "auth: Bearer token-2"} {
+		event := readertest.NextEvent(t, events)
+		if !event.IsValid() {
+			t.Fatalf("Expected valid event, got error: %v", event.Error)
+		}
+		if string(event.Data) != want {
+			t.Errorf("Expected %q, got %q", want, string(event.Data))
+		}
+	}
+
+	if got := <-authorizations; got != "Bearer token-1" {
+		t.Errorf("Expected first connection with token-1, got %s", got)
+	}
+	if got := <-authorizations; got != "Bearer token-2" {
+		t.Errorf("Expected reconnect with token-2, got %s", got)
+	}
+}
+
+func TestParseTokenSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantTS  bool
+		wantErr bool
+	}{
+		{name: "no token", query: ""},
+		{name: "token file", query: "token_file=/var/run/secrets/token", wantTS: true},
+		{name: "client credentials", query: "oauth2_token_url=https://idp/token&oauth2_client_id=app", wantTS: true},
+		{name: "missing client id", query: "oauth2_token_url=https://idp/token", wantErr: true},
+		{name: "missing secret file", query: "oauth2_token_url=https://idp/token&oauth2_client_id=app&oauth2_client_secret_file=/nonexistent", wantErr: true},
+		{name: "mutually exclusive", query: "token_file=/token&oauth2_token_url=https://idp/token&oauth2_client_id=app", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("Failed to parse query: %v", err)
+			}
+
+			config := &HTTPConfig{Timeout: DefaultTimeout}
+			err = parseTokenSource(query, config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTokenSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (config.TokenSource != nil) != tt.wantTS {
+				t.Errorf("Expected token source %v, got %v", tt.wantTS, config.TokenSource)
+			}
+		})
+	}
+}
+
+func TestHTTPReader_OAuth2TokenEndpointTLS(t *testing.T) {
+	var issued atomic.Int32
+	var secret atomic.Value
+	secret.Store("s3cret")
+	tokenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, clientSecret, _ := r.BasicAuth(); clientSecret != secret.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 1}`, issued.Add(1))
+	}))
+	defer tokenServer.Close()
+	server := newAuthEchoServer(t)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeFile(t, caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tokenServer.Certificate().Raw}))
+	secretFile := filepath.Join(dir, "secret")
+	writeFile(t, secretFile, []byte("s3cret\n"))
+
+	query := url.Values{}
+	query.Set("oauth2_token_url", tokenServer.URL)
+	query.Set("oauth2_client_id", "client")
+	query.Set("oauth2_client_secret_file", secretFile)
+	query.Set("tls_ca", caFile)
+
+	httpReader, err := NewHTTPReader(server.URL + "?" + query.Encode())
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	// The token endpoint is verified with the CA bundle of the reader
+	data, err := httpReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != "Bearer token-1" {
+		t.Errorf("Expected Bearer token-1, got %s", string(data))
+	}
+
+	// A rotated client secret is read when the token is refreshed
+	secret.Store("rotated")
+	writeFile(t, secretFile, []byte("rotated\n"))
+	time.Sleep(600 * time.Millisecond)
+	data, err = httpReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read with rotated secret: %v", err)
+	}
+	if string(data) != "Bearer token-2" {
+		t.Errorf("Expected Bearer token-2, got %s", string(data))
+	}
+}
@@ -1,7 +1,7 @@
 package http
 
 import (
-	"bufio"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"encoding/base64"
@@ -38,6 +38,12 @@ func init() {
 	_ = reader.RegisterReader(SchemeHTTPS, func(uri string) (reader.ConfReader, error) {
 		return NewHTTPReader(uri)
 	})
+	_ = reader.RegisterReader(SchemeWS, func(uri string) (reader.ConfReader, error) {
+		return NewHTTPReader(uri)
+	})
+	_ = reader.RegisterReader(SchemeWSS, func(uri string) (reader.ConfReader, error) {
+		return NewHTTPReader(uri)
+	})
 }
 
 // HTTPConfig holds HTTP client configuration
@@ -47,27 +53,50 @@ type HTTPConfig struct {
 	RetryDelay    time.Duration
 	Headers       map[string]string
 	TLSConfig     *tls.Config
+	// SSEEvent is the event type whose payloads are applied as configuration
+	SSEEvent string
+	// SSEIdleTimeout reconnects when the stream stays silent for this long,
+	// zero disables idle detection
+	SSEIdleTimeout time.Duration
+	// Transport selects how Subscribe receives updates: sse or websocket
+	Transport string
+	// PingInterval between WebSocket keepalive pings
+	PingInterval time.Duration
+	// CAFile is a PEM bundle used to verify the server, reloaded when rotated
+	CAFile string
+	// CertFile and KeyFile hold the PEM client certificate for mTLS, reloaded when rotated
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the TLS server name used for SNI and verification
+	ServerName string
+	// MinTLSVersion overrides the minimum TLS version
+	MinTLSVersion uint16
+	// TokenSource supplies the Authorization header, overriding basic auth
+	TokenSource TokenSource
 }
 
 // HTTPReader implements ConfReader for HTTP-based configuration
 type HTTPReader struct {
-	uri       string
-	parsedURL *url.URL
-	client    *http.Client
-	config    *HTTPConfig
-	mu        sync.RWMutex
-	closed    bool
+	uri         string
+	parsedURL   *url.URL
+	client      *http.Client
+	config      *HTTPConfig
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+	mu          sync.RWMutex
+	closed      bool
 }
 
 // NewHTTPReader creates a new HTTP reader
-func NewHTTPReader(uri string) (*HTTPReader, error) {
+func NewHTTPReader(uri string, opts ...Option) (*HTTPReader, error) {
 	u, err := reader.ParseURI(uri)
 	if err != nil {
 		return nil, err
 	}
 
-	if u.Scheme != string(SchemeHTTP) && u.Scheme != string(SchemeHTTPS) {
-		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s or %s", u.Scheme, SchemeHTTP, SchemeHTTPS)
+	if u.Scheme != string(SchemeHTTP) && u.Scheme != string(SchemeHTTPS) && !isWebSocketScheme(u.Scheme) {
+		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s, %s, %s or %s",
+			u.Scheme, SchemeHTTP, SchemeHTTPS, SchemeWS, SchemeWSS)
 	}
 
 	config := &HTTPConfig{
@@ -75,6 +104,9 @@ func NewHTTPReader(uri string) (*HTTPReader, error) {
 		RetryAttempts: DefaultRetryAttempts,
 		RetryDelay:    DefaultRetryDelay,
 		Headers:       make(map[string]string),
+		SSEEvent:      DefaultSSEEvent,
+		Transport:     TransportSSE,
+		PingInterval:  DefaultPingInterval,
 	}
 
 	// Parse query parameters for configuration
@@ -82,6 +114,14 @@ func NewHTTPReader(uri string) (*HTTPReader, error) {
 		return nil, fmt.Errorf("failed to parse query configuration: %w", err)
 	}
 
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if err := buildTLSConfig(config, u.Hostname()); err != nil {
+		return nil, fmt.Errorf("failed to build TLS configuration: %w", err)
+	}
+
 	transport := &http.Transport{
 		TLSClientConfig: config.TLSConfig,
 	}
@@ -90,24 +130,38 @@ func NewHTTPReader(uri string) (*HTTPReader, error) {
 		Timeout:   config.Timeout,
 		Transport: transport,
 	}
+	if ts, ok := config.TokenSource.(*clientCredentialsTokenSource); ok && ts.client == nil {
+		ts.client = client
+	}
 
+	closeCtx, closeCancel := context.WithCancel(context.Background())
 	return &HTTPReader{
-		uri:       uri,
-		parsedURL: u,
-		client:    client,
-		config:    config,
+		uri:         uri,
+		parsedURL:   u,
+		client:      client,
+		config:      config,
+		closeCtx:    closeCtx,
+		closeCancel: closeCancel,
 	}, nil
 }
 
 // Read reads configuration data from HTTP endpoint
 func (h *HTTPReader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := h.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads configuration data and the response content metadata
+func (h *HTTPReader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	if h.closed {
-		return nil, fmt.Errorf("reader is closed")
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
 	}
 
+	ctx, cancel := reader.WithClose(ctx, h.closeCtx)
+	defer cancel()
 	return h.fetchWithRetry(ctx)
 }
 
@@ -121,8 +175,16 @@ func (h *HTTPReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, e
 	}
 
 	eventChan := make(chan *reader.ReadEvent, 1)
+	ctx, cancel := reader.WithClose(ctx, h.closeCtx)
 
-	go h.subscribeSSE(ctx, eventChan)
+	go func() {
+		defer cancel()
+		if h.config.Transport == TransportWebSocket {
+			h.subscribeWebSocket(ctx, eventChan)
+			return
+		}
+		h.subscribeSSE(ctx, eventChan)
+	}()
 
 	return eventChan, nil
 }
@@ -141,194 +203,95 @@ func (h *HTTPReader) Close() error {
 	}
 
 	h.closed = true
+	if h.closeCancel != nil {
+		h.closeCancel()
+	}
 	h.client.CloseIdleConnections()
 
 	return nil
 }
 
 // fetchWithRetry performs HTTP GET with retry mechanism
-func (h *HTTPReader) fetchWithRetry(ctx context.Context) ([]byte, error) {
-	var lastErr error
+func (h *HTTPReader) fetchWithRetry(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	fetch := h.fetch
+	if isWebSocketScheme(h.parsedURL.Scheme) {
+		fetch = h.fetchWebSocket
+	}
 
+	var lastErr error
 	for attempt := 0; attempt < h.config.RetryAttempts; attempt++ {
 		if attempt > 0 {
 			select {
 			case <-ctx.Done():
-				return nil, ctx.Err()
+				return nil, reader.ContentMeta{}, ctx.Err()
 			case <-time.After(h.config.RetryDelay):
 			}
 		}
 
-		data, err := h.fetch(ctx)
+		data, meta, err := fetch(ctx)
 		if err == nil {
-			return data, nil
+			return data, meta, nil
 		}
 
 		lastErr = err
 	}
 
-	return nil, fmt.Errorf("failed after %d attempts: %w", h.config.RetryAttempts, lastErr)
+	return nil, reader.ContentMeta{}, fmt.Errorf("failed after %d attempts: %w", h.config.RetryAttempts, lastErr)
 }
 
 // fetch performs single HTTP GET request
-func (h *HTTPReader) fetch(ctx context.Context) ([]byte, error) {
+func (h *HTTPReader) fetch(ctx context.Context) ([]byte, reader.ContentMeta, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.parsedURL.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, reader.ContentMeta{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set custom headers
 	for key, value := range h.config.Headers {
 		req.Header.Set(key, value)
 	}
+	if _, err := h.authorize(ctx, req.Header); err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, reader.ContentMeta{}, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP request failed with status: %d %s", resp.StatusCode, resp.Status)
-	}
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, reader.ContentMeta{}, fmt.Errorf("HTTP request failed with status: %d %s", resp.StatusCode, resp.Status)
 	}
 
-	return data, nil
-}
-
-// subscribeSSE handles Server-Sent Events subscription
-func (h *HTTPReader) subscribeSSE(ctx context.Context, eventChan chan<- *reader.ReadEvent) {
-	defer close(eventChan)
-
-	// Create SSE request
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.parsedURL.String(), nil)
-	if err != nil {
-		confEvent := reader.NewReadEvent(h.uri, nil, fmt.Errorf("failed to create SSE request: %w", err))
-		select {
-		case eventChan <- confEvent:
-		case <-ctx.Done():
+	meta := responseMeta(resp)
+	body := io.Reader(resp.Body)
+	if !resp.Uncompressed && meta.Encoding == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, reader.ContentMeta{}, fmt.Errorf("failed to create gzip reader: %w", err)
 		}
-		return
+		defer gz.Close()
+		body = gz
 	}
 
-	// Set SSE headers
-	req.Header.Set("Accept", "text/event-stream")
-	req.Header.Set("Cache-Control", "no-cache")
-
-	// Set custom headers
-	for key, value := range h.config.Headers {
-		req.Header.Set(key, value)
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, reader.ContentMeta{}, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
-
-		resp, err := h.client.Do(req)
-		if err != nil {
-			confEvent := reader.NewReadEvent(h.uri, nil, fmt.Errorf("SSE connection failed: %w", err))
-			select {
-			case eventChan <- confEvent:
-			case <-ctx.Done():
-				return
-			}
-
-			// Wait before retry
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(h.config.RetryDelay):
-				continue
-			}
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			_ = resp.Body.Close()
-			confEvent := reader.NewReadEvent(h.uri, nil, fmt.Errorf("SSE request failed with status: %d %s", resp.StatusCode, resp.Status))
-			select {
-			case eventChan <- confEvent:
-			case <-ctx.Done():
-				return
-			}
-
-			// Wait before retry
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(h.config.RetryDelay):
-				continue
-			}
-		}
-
-		// Process SSE stream
-		h.processSSEStream(ctx, resp, eventChan)
-		_ = resp.Body.Close()
-
-		// Wait before reconnection
-		select {
-		case <-ctx.Done():
-			return
-		case <-time.After(h.config.RetryDelay):
-		}
-	}
+	return data, meta, nil
 }
 
-// processSSEStream processes Server-Sent Events stream
-func (h *HTTPReader) processSSEStream(ctx context.Context, resp *http.Response, eventChan chan<- *reader.ReadEvent) {
-	scanner := bufio.NewScanner(resp.Body)
-	var eventData strings.Builder
-
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
-
-		line := scanner.Text()
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, ":") {
-			continue
-		}
-
-		// Handle data lines
-		if strings.HasPrefix(line, "data:") {
-			data := strings.TrimPrefix(line, "data:")
-			data = strings.TrimSpace(data)
-			if eventData.Len() > 0 {
-				eventData.WriteString("\n")
-			}
-			eventData.WriteString(data)
-		} else if line == "" {
-			// Empty line indicates end of event
-			if eventData.Len() > 0 {
-				confEvent := reader.NewReadEvent(h.uri, []byte(eventData.String()), nil)
-				select {
-				case eventChan <- confEvent:
-				case <-ctx.Done():
-					return
-				}
-				eventData.Reset()
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		confEvent := reader.NewReadEvent(h.uri, nil, fmt.Errorf("SSE stream error: %w", err))
-		select {
-		case eventChan <- confEvent:
-		case <-ctx.Done():
-			return
-		}
+// responseMeta extracts content metadata from HTTP response headers
+func responseMeta(resp *http.Response) reader.ContentMeta {
+	meta := reader.ParseContentType(resp.Header.Get("Content-Type"))
+	meta.Encoding = strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if resp.Uncompressed {
+		// Transport already removed the header and decompressed the body
+		meta.Encoding = "gzip"
 	}
+	return meta
 }
 
 // parseQueryConfig parses query parameters into HTTP configuration
@@ -336,12 +299,9 @@ func parseQueryConfig(u *url.URL, config *HTTPConfig) error {
 	query := u.Query()
 
 	// Parse timeout
-	if timeoutStr := query.Get("timeout"); timeoutStr != "" {
-		timeout, err := time.ParseDuration(timeoutStr)
-		if err != nil {
-			return fmt.Errorf("invalid timeout format: %w", err)
-		}
-		config.Timeout = timeout
+	var err error
+	if config.Timeout, err = reader.ParseNonNegativeDuration(query, "timeout", config.Timeout); err != nil {
+		return err
 	}
 
 	// Parse retry attempts
@@ -357,12 +317,33 @@ func parseQueryConfig(u *url.URL, config *HTTPConfig) error {
 	}
 
 	// Parse retry delay
-	if delayStr := query.Get("retry_delay"); delayStr != "" {
-		delay, err := time.ParseDuration(delayStr)
-		if err != nil {
-			return fmt.Errorf("invalid retry_delay format: %w", err)
-		}
-		config.RetryDelay = delay
+	if config.RetryDelay, err = reader.ParseNonNegativeDuration(query, "retry_delay", config.RetryDelay); err != nil {
+		return err
+	}
+
+	// Parse SSE event type
+	if eventStr := query.Get("sse_event"); eventStr != "" {
+		config.SSEEvent = eventStr
+	}
+
+	// Parse SSE idle timeout
+	if config.SSEIdleTimeout, err = reader.ParseNonNegativeDuration(query, "sse_idle_timeout", config.SSEIdleTimeout); err != nil {
+		return err
+	}
+
+	// Parse subscription transport
+	if err := parseTransport(u, config); err != nil {
+		return err
+	}
+
+	// Parse WebSocket ping interval
+	if config.PingInterval, err = reader.ParseDuration(query, "ping_interval", config.PingInterval); err != nil {
+		return err
+	}
+
+	// Parse token authentication
+	if err := parseTokenSource(query, config); err != nil {
+		return err
 	}
 
 	// Parse headers (format: header_name=value)
@@ -386,6 +367,19 @@ func parseQueryConfig(u *url.URL, config *HTTPConfig) error {
 		}
 	}
 
+	// Parse TLS files and settings
+	config.CAFile = query.Get("tls_ca")
+	config.CertFile = query.Get("tls_cert")
+	config.KeyFile = query.Get("tls_key")
+	config.ServerName = query.Get("tls_server_name")
+	if versionStr := query.Get("tls_min_version"); versionStr != "" {
+		version, err := parseTLSVersion(versionStr)
+		if err != nil {
+			return err
+		}
+		config.MinTLSVersion = version
+	}
+
 	// Parse TLS configuration
 	if insecureStr := query.Get("tls_insecure"); insecureStr == "true" {
 		if config.TLSConfig == nil {
@@ -399,7 +393,7 @@ func parseQueryConfig(u *url.URL, config *HTTPConfig) error {
 			}
 		}
 		config.TLSConfig.InsecureSkipVerify = true
-	} else if u.Scheme == string(SchemeHTTPS) && config.TLSConfig == nil {
+	} else if (u.Scheme == string(SchemeHTTPS) || u.Scheme == string(SchemeWSS)) && config.TLSConfig == nil {
 		// For HTTPS connections, ensure minimum TLS version
 		config.TLSConfig = &tls.Config{
 			MinVersion: tls.VersionTLS12,
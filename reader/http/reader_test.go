@@ -1,6 +1,7 @@
 package http
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/base64"
 	"fmt"
@@ -163,6 +164,43 @@ func TestHTTPReader_Read(t *testing.T) {
 	}
 }
 
+func TestHTTPReader_ReadMeta(t *testing.T) {
+	testData := "key: value\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "Application/YAML; charset=UTF-8")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		fmt.Fprint(gz, testData)
+		_ = gz.Close()
+	}))
+	defer server.Close()
+
+	httpReader, err := NewHTTPReader(server.URL + "/api/config")
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	data, meta, err := httpReader.ReadMeta(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+
+	if string(data) != testData {
+		t.Errorf("Expected data %q, got %q", testData, string(data))
+	}
+	if meta.MIMEType != "application/yaml" {
+		t.Errorf("Expected MIME type application/yaml, got %s", meta.MIMEType)
+	}
+	if meta.Charset != "utf-8" {
+		t.Errorf("Expected charset utf-8, got %s", meta.Charset)
+	}
+	if meta.Encoding != "gzip" {
+		t.Errorf("Expected encoding gzip, got %s", meta.Encoding)
+	}
+}
+
 func TestHTTPReader_ReadWithAuth(t *testing.T) {
 	testData := `{"authenticated": true}`
 	expectedUser := "testuser"
@@ -0,0 +1,271 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+)
+
+const (
+	// DefaultSSEEvent is the SSE event type that carries configuration updates
+	DefaultSSEEvent = "config-update"
+
+	// maxSSELineSize bounds a single SSE line, large enough for inline config payloads
+	maxSSELineSize = 4 << 20
+)
+
+// sseEvent represents a dispatched Server-Sent Event
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// sseParser accumulates SSE fields as described by the WHATWG event stream
+// interpretation rules and dispatches an event on each blank line
+type sseParser struct {
+	eventType   string
+	data        strings.Builder
+	lastEventID string
+	retry       time.Duration
+}
+
+// parseLine processes a single stream line and returns the dispatched event, if any
+func (p *sseParser) parseLine(line string) *sseEvent {
+	if line == "" {
+		return p.dispatch()
+	}
+
+	// Comment lines are used as keepalive heartbeats
+	if strings.HasPrefix(line, ":") {
+		return nil
+	}
+
+	field, value, found := strings.Cut(line, ":")
+	if found {
+		value = strings.TrimPrefix(value, " ")
+	}
+
+	switch field {
+	case "event":
+		p.eventType = value
+	case "data":
+		p.data.WriteString(value)
+		p.data.WriteByte('\n')
+	case "id":
+		if !strings.ContainsRune(value, 0) {
+			p.lastEventID = value
+		}
+	case "retry":
+		if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+			p.retry = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	return nil
+}
+
+// dispatch emits the buffered event and resets the per-event state
+func (p *sseParser) dispatch() *sseEvent {
+	defer func() {
+		p.eventType = ""
+		p.data.Reset()
+	}()
+
+	if p.data.Len() == 0 {
+		return nil
+	}
+
+	eventType := p.eventType
+	if eventType == "" {
+		eventType = "message"
+	}
+
+	return &sseEvent{
+		id:    p.lastEventID,
+		event: eventType,
+		data:  strings.TrimSuffix(p.data.String(), "\n"),
+	}
+}
+
+// scanSSELines splits an event stream into lines terminated by CRLF, LF or CR
+func scanSSELines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// A trailing CR may be followed by LF in the next read
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// subscribeSSE handles Server-Sent Events subscription with reconnection
+func (h *HTTPReader) subscribeSSE(ctx context.Context, eventChan chan<- *reader.ReadEvent) {
+	defer close(eventChan)
+
+	// Streams stay open indefinitely, so the client-wide timeout must not apply
+	client := *h.client
+	client.Timeout = 0
+
+	parser := &sseParser{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		err := h.streamSSE(ctx, &client, parser, eventChan)
+		if errors.Is(err, errTokenExpired) {
+			continue
+		}
+		if err != nil {
+			if !h.sendEvent(ctx, eventChan, reader.NewReadEvent(h.uri, nil, err)) {
+				return
+			}
+		}
+
+		// Wait before reconnection, honouring the server-provided retry delay
+		delay := h.config.RetryDelay
+		if parser.retry > 0 {
+			delay = parser.retry
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// streamSSE opens one SSE connection and processes it until it ends
+func (h *HTTPReader) streamSSE(ctx context.Context, client *http.Client, parser *sseParser, eventChan chan<- *reader.ReadEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.parsedURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create SSE request: %w", err)
+	}
+
+	// Set SSE headers
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if parser.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", parser.lastEventID)
+	}
+
+	// Set custom headers
+	for key, value := range h.config.Headers {
+		req.Header.Set(key, value)
+	}
+	token, err := h.authorize(ctx, req.Header)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("SSE connection failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SSE request failed with status: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	// Reconnect with a refreshed token once the current one expires
+	var expired atomic.Bool
+	stop := expireAfter(token, func() {
+		expired.Store(true)
+		_ = resp.Body.Close()
+	})
+	defer stop()
+
+	err = h.processSSEStream(ctx, resp, parser, eventChan)
+	if expired.Load() && ctx.Err() == nil {
+		return errTokenExpired
+	}
+	return err
+}
+
+// processSSEStream processes Server-Sent Events stream
+func (h *HTTPReader) processSSEStream(ctx context.Context, resp *http.Response, parser *sseParser, eventChan chan<- *reader.ReadEvent) error {
+	// Close the body when no line, including heartbeat comments, arrives in time
+	var idle atomic.Bool
+	var idleTimer *time.Timer
+	if h.config.SSEIdleTimeout > 0 {
+		idleTimer = time.AfterFunc(h.config.SSEIdleTimeout, func() {
+			idle.Store(true)
+			_ = resp.Body.Close()
+		})
+		defer idleTimer.Stop()
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+	scanner.Split(scanSSELines)
+
+	first := true
+	for scanner.Scan() {
+		if idleTimer != nil {
+			idleTimer.Reset(h.config.SSEIdleTimeout)
+		}
+
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		event := parser.parseLine(line)
+		if event == nil || event.event != h.config.SSEEvent {
+			continue
+		}
+
+		if !h.sendEvent(ctx, eventChan, reader.NewReadEvent(h.uri, []byte(event.data), nil)) {
+			return nil
+		}
+	}
+
+	if idle.Load() {
+		return fmt.Errorf("SSE stream idle for %s", h.config.SSEIdleTimeout)
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("SSE stream error: %w", err)
+	}
+
+	return nil
+}
+
+// sendEvent delivers an event unless the context is cancelled first
+func (h *HTTPReader) sendEvent(ctx context.Context, eventChan chan<- *reader.ReadEvent, event *reader.ReadEvent) bool {
+	select {
+	case eventChan <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
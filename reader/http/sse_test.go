@@ -0,0 +1,178 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+func TestSSEParser(t *testing.T) {
+	stream := strings.Join([]string{
+		": keepalive",
+		"retry: 250",
+		"id: 1",
+		"event: config-update",
+		"data: server:",
+		"data:   port: 8080",
+		"",
+		"data:no space",
+		"",
+		"id: 2",
+		"event: config-update",
+		"",
+		"event: config-update",
+		"data",
+		"data: ",
+		"",
+		"",
+	}, "\r\n")
+
+	scanner := bufio.NewScanner(strings.NewReader(stream))
+	scanner.Split(scanSSELines)
+
+	parser := &sseParser{}
+	var events []*sseEvent
+	for scanner.Scan() {
+		if event := parser.parseLine(scanner.Text()); event != nil {
+			events = append(events, event)
+		}
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d: %+v", len(events), events)
+	}
+
+	if events[0].event != "config-update" || events[0].id != "1" || events[0].data != "server:\n  port: 8080" {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+	if events[1].event != "message" || events[1].data != "no space" {
+		t.Errorf("Unexpected second event: %+v", events[1])
+	}
+	if events[2].id != "2" || events[2].data != "\n" {
+		t.Errorf("Unexpected third event: %+v", events[2])
+	}
+	if parser.retry != 250*time.Millisecond {
+		t.Errorf("Expected retry 250ms, got %v", parser.retry)
+	}
+}
+
+func TestScanSSELines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("a\rb\nc\r\nd"))
+	scanner.Split(scanSSELines)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if strings.Join(lines, ",") != "a,b,c,d" {
+		t.Errorf("Expected lines a,b,c,d, got %q", lines)
+	}
+}
+
+func TestHTTPReader_SubscribeSSE(t *testing.T) {
+	var connections atomic.Int32
+	lastEventIDs := make(chan string, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Expected SSE Accept header, got %s", r.Header.Get("Accept"))
+		}
+		lastEventIDs <- r.Header.Get("Last-Event-ID")
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch connections.Add(1) {
+		case 1:
+			fmt.Fprint(w, "retry: 10\n\n")
+			fmt.Fprint(w, ": heartbeat\n\n")
+			fmt.Fprint(w, "event: status\ndata: ignored\n\n")
+			fmt.Fprint(w, "id: 7\nevent: config-update\ndata: key: value\ndata: other: 1\n\n")
+		default:
+			fmt.Fprint(w, "id: 8\nevent: config-update\ndata: key: updated\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	httpReader, err := NewHTTPReader(server.URL + "?retry_delay=1s")
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := httpReader.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	expected := []string{"key: value\nother: 1", "key: updated"}
+	for _, want := range expected {
+		event := readertest.NextEvent(t, events)
+		if !event.IsValid() {
+			t.Fatalf("Expected valid event, got error: %v", event.Error)
+		}
+		if string(event.Data) != want {
+			t.Errorf("Expected data %q, got %q", want, string(event.Data))
+		}
+	}
+
+	if id := <-lastEventIDs; id != "" {
+		t.Errorf("Expected no Last-Event-ID on first connection, got %s", id)
+	}
+	if id := <-lastEventIDs; id != "7" {
+		t.Errorf("Expected Last-Event-ID 7 on reconnect, got %s", id)
+	}
+}
+
+func TestHTTPReader_SubscribeSSEIdleTimeout(t *testing.T) {
+	var connections atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if connections.Add(1) == 1 {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, "event: config-update\ndata: key: value\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	httpReader, err := NewHTTPReader(server.URL + "?sse_idle_timeout=50ms&retry_delay=10ms")
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := httpReader.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	event := readertest.NextEvent(t, events)
+	if event.Error == nil || !strings.Contains(event.Error.Error(), "idle") {
+		t.Fatalf("Expected idle timeout error, got %+v", event)
+	}
+
+	event = readertest.NextEvent(t, events)
+	if !event.IsValid() || string(event.Data) != "key: value" {
+		t.Errorf("Expected update after reconnect, got %+v", event)
+	}
+}
@@ -0,0 +1,239 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option configures an HTTPReader programmatically. Options are applied after
+// URI query parameters and take precedence over them.
+type Option func(*HTTPConfig)
+
+// WithCAFile verifies the server against the PEM CA bundle at path
+func WithCAFile(path string) Option {
+	return func(c *HTTPConfig) { c.CAFile = path }
+}
+
+// WithClientCertificate presents the PEM client certificate and key for mTLS
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(c *HTTPConfig) {
+		c.CertFile = certFile
+		c.KeyFile = keyFile
+	}
+}
+
+// WithServerName overrides the TLS server name used for SNI and verification
+func WithServerName(name string) Option {
+	return func(c *HTTPConfig) { c.ServerName = name }
+}
+
+// WithMinTLSVersion sets the minimum accepted TLS version, e.g. tls.VersionTLS13
+func WithMinTLSVersion(version uint16) Option {
+	return func(c *HTTPConfig) { c.MinTLSVersion = version }
+}
+
+// WithTLSConfig uses cfg as the base TLS configuration
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *HTTPConfig) { c.TLSConfig = cfg.Clone() }
+}
+
+// tlsVersions maps tls_min_version query values to TLS versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSVersion parses a TLS version such as 1.2 or 1.3
+func parseTLSVersion(value string) (uint16, error) {
+	version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(value), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version: %s, expected one of 1.0, 1.1, 1.2, 1.3", value)
+	}
+	return version, nil
+}
+
+// buildTLSConfig applies CA bundle, client certificate, SNI and version settings
+// on top of the base TLS configuration
+func buildTLSConfig(config *HTTPConfig, host string) error {
+	if config.CAFile == "" && config.CertFile == "" && config.KeyFile == "" &&
+		config.ServerName == "" && config.MinTLSVersion == 0 {
+		return nil
+	}
+
+	if config.TLSConfig == nil {
+		config.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	tlsConfig := config.TLSConfig
+
+	if config.MinTLSVersion != 0 {
+		tlsConfig.MinVersion = config.MinTLSVersion
+	}
+	if config.ServerName != "" {
+		tlsConfig.ServerName = config.ServerName
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return fmt.Errorf("both client certificate and key must be specified")
+	}
+
+	files := &tlsFiles{
+		caFile:     config.CAFile,
+		certFile:   config.CertFile,
+		keyFile:    config.KeyFile,
+		serverName: host,
+	}
+	if config.ServerName != "" {
+		files.serverName = config.ServerName
+	}
+
+	if config.CertFile != "" {
+		if _, err := files.clientCertificate(nil); err != nil {
+			return err
+		}
+		tlsConfig.GetClientCertificate = files.clientCertificate
+	}
+
+	if config.CAFile != "" {
+		if _, err := files.rootCAs(); err != nil {
+			return err
+		}
+		if !tlsConfig.InsecureSkipVerify {
+			// The standard verifier uses a fixed RootCAs pool. Verification is
+			// done in VerifyConnection instead so a rotated bundle takes effect
+			// on the next handshake.
+			tlsConfig.InsecureSkipVerify = true
+			tlsConfig.VerifyConnection = files.verifyConnection
+		}
+	}
+
+	return nil
+}
+
+// tlsFiles loads TLS material from disk and reloads it when the files change
+type tlsFiles struct {
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string
+
+	mu          sync.Mutex
+	caModTime   time.Time
+	certModTime time.Time
+	keyModTime  time.Time
+	pool        *x509.CertPool
+	cert        *tls.Certificate
+}
+
+// clientCertificate returns the client key pair, reloading it after rotation
+func (f *tlsFiles) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	certModTime, err := modTime(f.certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyModTime, err := modTime(f.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.cert != nil && certModTime.Equal(f.certModTime) && keyModTime.Equal(f.keyModTime) {
+		return f.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		if f.cert != nil {
+			// Keep the previous pair while a rotation is only half written
+			return f.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	f.cert = &cert
+	f.certModTime = certModTime
+	f.keyModTime = keyModTime
+	return f.cert, nil
+}
+
+// rootCAs returns the CA pool, reloading it after rotation
+func (f *tlsFiles) rootCAs() (*x509.CertPool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	caModTime, err := modTime(f.caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.pool != nil && caModTime.Equal(f.caModTime) {
+		return f.pool, nil
+	}
+
+	data, err := os.ReadFile(f.caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		if f.pool != nil {
+			return f.pool, nil
+		}
+		return nil, fmt.Errorf("no valid certificates found in CA file %s", f.caFile)
+	}
+
+	f.pool = pool
+	f.caModTime = caModTime
+	return f.pool, nil
+}
+
+// verifyConnection verifies the server certificate chain against the current CA pool
+func (f *tlsFiles) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("server presented no certificates")
+	}
+
+	roots, err := f.rootCAs()
+	if err != nil {
+		return err
+	}
+
+	serverName := cs.ServerName
+	if serverName == "" {
+		// No SNI is sent for IP addresses
+		serverName = f.serverName
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+		return fmt.Errorf("failed to verify server certificate: %w", err)
+	}
+
+	return nil
+}
+
+// modTime returns the modification time of path, following symlinks
+func modTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("TLS file access error: %w", err)
+	}
+	return info.ModTime(), nil
+}
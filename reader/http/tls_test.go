@@ -0,0 +1,281 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert holds a generated certificate and its PEM encodings
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert creates a certificate signed by parent, or self-signed when parent is nil
+func newTestCert(t *testing.T, serial int64, parent *testCert, isCA bool, dnsNames ...string) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("test-%d", serial)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		DNSNames:              dnsNames,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeFile writes data and bumps the modification time so rotation is detected
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	future := time.Now().Add(time.Duration(len(data)) * time.Millisecond)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to touch %s: %v", path, err)
+	}
+}
+
+// newMTLSServer starts a TLS server that requires client certificates signed by
+// clientCA and responds with the client certificate serial number
+func newMTLSServer(t *testing.T, serverCert, clientCA *testCert) *httptest.Server {
+	t.Helper()
+
+	keyPair, err := tls.X509KeyPair(serverCert.certPEM, serverCert.keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load server key pair: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA.cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"serial": %s}`, r.TLS.PeerCertificates[0].SerialNumber)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestHTTPReader_MTLS(t *testing.T) {
+	serverCA := newTestCert(t, 1, nil, true)
+	clientCA := newTestCert(t, 2, nil, true)
+	serverCert := newTestCert(t, 3, serverCA, false, "config.internal")
+	clientCert := newTestCert(t, 4, clientCA, false)
+	otherCA := newTestCert(t, 5, nil, true)
+
+	server := newMTLSServer(t, serverCert, clientCA)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	writeFile(t, caFile, serverCA.certPEM)
+	writeFile(t, certFile, clientCert.certPEM)
+	writeFile(t, keyFile, clientCert.keyPEM)
+
+	t.Run("query options", func(t *testing.T) {
+		query := url.Values{}
+		query.Set("tls_ca", caFile)
+		query.Set("tls_cert", certFile)
+		query.Set("tls_key", keyFile)
+		query.Set("tls_min_version", "1.3")
+		query.Set("retry_attempts", "1")
+
+		httpReader, err := NewHTTPReader(server.URL + "?" + query.Encode())
+		if err != nil {
+			t.Fatalf("Failed to create HTTP reader: %v", err)
+		}
+		defer httpReader.Close()
+
+		if httpReader.config.TLSConfig.MinVersion != tls.VersionTLS13 {
+			t.Errorf("Expected TLS 1.3 minimum, got %x", httpReader.config.TLSConfig.MinVersion)
+		}
+
+		data, err := httpReader.Read(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		if string(data) != `{"serial": 4}` {
+			t.Errorf("Unexpected data: %s", string(data))
+		}
+	})
+
+	t.Run("programmatic options with server name", func(t *testing.T) {
+		httpReader, err := NewHTTPReader(server.URL+"?retry_attempts=1",
+			WithCAFile(caFile),
+			WithClientCertificate(certFile, keyFile),
+			WithServerName("config.internal"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create HTTP reader: %v", err)
+		}
+		defer httpReader.Close()
+
+		if _, err := httpReader.Read(context.Background()); err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+	})
+
+	t.Run("wrong server name", func(t *testing.T) {
+		httpReader, err := NewHTTPReader(server.URL+"?retry_attempts=1",
+			WithCAFile(caFile),
+			WithClientCertificate(certFile, keyFile),
+			WithServerName("other.internal"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create HTTP reader: %v", err)
+		}
+		defer httpReader.Close()
+
+		if _, err := httpReader.Read(context.Background()); err == nil {
+			t.Fatal("Expected verification error for wrong server name")
+		}
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		httpReader, err := NewHTTPReader(server.URL+"?retry_attempts=1", WithCAFile(caFile))
+		if err != nil {
+			t.Fatalf("Failed to create HTTP reader: %v", err)
+		}
+		defer httpReader.Close()
+
+		if _, err := httpReader.Read(context.Background()); err == nil {
+			t.Fatal("Expected handshake error without client certificate")
+		}
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		rotatingCA := filepath.Join(dir, "rotating-ca.pem")
+		writeFile(t, rotatingCA, otherCA.certPEM)
+
+		httpReader, err := NewHTTPReader(server.URL+"?retry_attempts=1",
+			WithCAFile(rotatingCA),
+			WithClientCertificate(certFile, keyFile),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create HTTP reader: %v", err)
+		}
+		defer httpReader.Close()
+
+		if _, err := httpReader.Read(context.Background()); err == nil {
+			t.Fatal("Expected verification error with untrusted CA")
+		}
+
+		// Rotate the CA bundle and the client certificate on disk
+		writeFile(t, rotatingCA, append(otherCA.certPEM, serverCA.certPEM...))
+		rotatedCert := newTestCert(t, 6, clientCA, false)
+		writeFile(t, certFile, rotatedCert.certPEM)
+		writeFile(t, keyFile, rotatedCert.keyPEM)
+
+		data, err := httpReader.Read(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to read after rotation: %v", err)
+		}
+		if string(data) != `{"serial": 6}` {
+			t.Errorf("Expected rotated client certificate, got %s", string(data))
+		}
+	})
+}
+
+func TestHTTPReader_TLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalidCA := filepath.Join(dir, "invalid.pem")
+	writeFile(t, invalidCA, []byte("not a certificate"))
+
+	tests := []struct {
+		name string
+		uri  string
+		opts []Option
+	}{
+		{
+			name: "unsupported TLS version",
+			uri:  "https://example.com/config?tls_min_version=2.0",
+		},
+		{
+			name: "missing CA file",
+			uri:  "https://example.com/config?tls_ca=" + filepath.Join(dir, "missing.pem"),
+		},
+		{
+			name: "invalid CA file",
+			opts: []Option{WithCAFile(invalidCA)},
+			uri:  "https://example.com/config",
+		},
+		{
+			name: "certificate without key",
+			uri:  "https://example.com/config",
+			opts: []Option{WithClientCertificate(invalidCA, "")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPReader(tt.uri, tt.opts...); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sower-proxy/feconf/reader"
+)
+
+const (
+	// SchemeWS represents WebSocket URI scheme
+	SchemeWS reader.Scheme = "ws"
+	// SchemeWSS represents secure WebSocket URI scheme
+	SchemeWSS reader.Scheme = "wss"
+
+	// TransportSSE subscribes to updates with Server-Sent Events
+	TransportSSE = "sse"
+	// TransportWebSocket subscribes to updates over a WebSocket connection
+	TransportWebSocket = "websocket"
+
+	// DefaultPingInterval between WebSocket keepalive pings
+	DefaultPingInterval = 30 * time.Second
+	// DefaultMaxRetryDelay caps the reconnection backoff
+	DefaultMaxRetryDelay = 30 * time.Second
+
+	// maxWebSocketMessageSize bounds a single configuration payload
+	maxWebSocketMessageSize = 16 << 20
+)
+
+// isWebSocketScheme reports whether scheme is ws or wss
+func isWebSocketScheme(scheme string) bool {
+	return scheme == string(SchemeWS) || scheme == string(SchemeWSS)
+}
+
+// webSocketURL returns the WebSocket endpoint for the reader URI
+func (h *HTTPReader) webSocketURL() string {
+	u := *h.parsedURL
+	switch u.Scheme {
+	case string(SchemeHTTP):
+		u.Scheme = string(SchemeWS)
+	case string(SchemeHTTPS):
+		u.Scheme = string(SchemeWSS)
+	}
+	// Credentials are already sent through the Authorization header
+	u.User = nil
+	return u.String()
+}
+
+// dialWebSocket opens a WebSocket connection with the configured headers and
+// TLS settings and returns the token used to authenticate it, if any
+func (h *HTTPReader) dialWebSocket(ctx context.Context) (*websocket.Conn, *Token, error) {
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: h.config.Timeout,
+		TLSClientConfig:  h.config.TLSConfig,
+	}
+
+	header := http.Header{}
+	for key, value := range h.config.Headers {
+		header.Set(key, value)
+	}
+	token, err := h.authorize(ctx, header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, h.webSocketURL(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, nil, fmt.Errorf("WebSocket handshake failed with status: %d %s: %w", resp.StatusCode, resp.Status, err)
+		}
+		return nil, nil, fmt.Errorf("WebSocket connection failed: %w", err)
+	}
+	conn.SetReadLimit(maxWebSocketMessageSize)
+
+	return conn, token, nil
+}
+
+// fetchWebSocket connects and returns the first message as configuration data
+func (h *HTTPReader) fetchWebSocket(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	conn, _, err := h.dialWebSocket(ctx)
+	if err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if err := conn.SetReadDeadline(time.Now().Add(h.config.Timeout)); err != nil {
+		return nil, reader.ContentMeta{}, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, reader.ContentMeta{}, ctx.Err()
+		}
+		return nil, reader.ContentMeta{}, fmt.Errorf("failed to read WebSocket message: %w", err)
+	}
+
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+
+	return data, reader.ContentMeta{}, nil
+}
+
+// subscribeWebSocket keeps a WebSocket open and reconnects with exponential backoff
+func (h *HTTPReader) subscribeWebSocket(ctx context.Context, eventChan chan<- *reader.ReadEvent) {
+	defer close(eventChan)
+
+	delay := h.config.RetryDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		connected, err := h.streamWebSocket(ctx, eventChan)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = h.config.RetryDelay
+		}
+		if errors.Is(err, errTokenExpired) {
+			continue
+		}
+		if err != nil {
+			if !h.sendEvent(ctx, eventChan, reader.NewReadEvent(h.uri, nil, err)) {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > DefaultMaxRetryDelay {
+			delay = DefaultMaxRetryDelay
+		}
+	}
+}
+
+// streamWebSocket reads messages from one connection until it fails, reporting
+// whether the connection was established
+func (h *HTTPReader) streamWebSocket(ctx context.Context, eventChan chan<- *reader.ReadEvent) (bool, error) {
+	conn, token, err := h.dialWebSocket(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// Reconnect with a refreshed token once the current one expires
+	var expired atomic.Bool
+	stop := expireAfter(token, func() {
+		expired.Store(true)
+		_ = conn.Close()
+	})
+	defer stop()
+
+	pingInterval := h.config.PingInterval
+	pongWait := 2 * pingInterval
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	// Send keepalive pings and close the connection on cancellation
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+				_ = conn.Close()
+				return
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.config.Timeout)); err != nil {
+					_ = conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			if expired.Load() && ctx.Err() == nil {
+				return true, errTokenExpired
+			}
+			if ctx.Err() != nil || websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return true, nil
+			}
+			return true, fmt.Errorf("WebSocket read error: %w", err)
+		}
+
+		// Any frame from the server proves the connection is alive
+		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		if messageType != websocket.TextMessage && messageType != websocket.BinaryMessage {
+			continue
+		}
+
+		if !h.sendEvent(ctx, eventChan, reader.NewReadEvent(h.uri, data, nil)) {
+			return true, nil
+		}
+	}
+}
+
+// parseTransport resolves the subscription transport from scheme and query
+func parseTransport(u *url.URL, config *HTTPConfig) error {
+	if isWebSocketScheme(u.Scheme) {
+		config.Transport = TransportWebSocket
+		return nil
+	}
+
+	switch transport := u.Query().Get("transport"); transport {
+	case "":
+	case TransportSSE, TransportWebSocket:
+		config.Transport = transport
+	default:
+		return fmt.Errorf("unsupported transport: %s, expected: %s or %s", transport, TransportSSE, TransportWebSocket)
+	}
+
+	return nil
+}
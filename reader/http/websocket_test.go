@@ -0,0 +1,221 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+// newWebSocketServer starts a test server that hands each connection to handle
+func newWebSocketServer(t *testing.T, handle func(conn *websocket.Conn, r *http.Request)) *httptest.Server {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade connection: %v", err)
+			return
+		}
+		defer conn.Close()
+		handle(conn, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestHTTPReader_WebSocketConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		uri       string
+		transport string
+		wantErr   bool
+	}{
+		{
+			name:      "ws scheme",
+			uri:       "ws://example.com/config",
+			transport: TransportWebSocket,
+		},
+		{
+			name:      "wss scheme",
+			uri:       "wss://example.com/config",
+			transport: TransportWebSocket,
+		},
+		{
+			name:      "http with websocket transport",
+			uri:       "http://example.com/config?transport=websocket&ping_interval=5s",
+			transport: TransportWebSocket,
+		},
+		{
+			name:      "http default transport",
+			uri:       "http://example.com/config",
+			transport: TransportSSE,
+		},
+		{
+			name:    "unsupported transport",
+			uri:     "http://example.com/config?transport=grpc",
+			wantErr: true,
+		},
+		{
+			name:    "invalid ping interval",
+			uri:     "ws://example.com/config?ping_interval=0s",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpReader, err := NewHTTPReader(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHTTPReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer httpReader.Close()
+
+			if httpReader.config.Transport != tt.transport {
+				t.Errorf("Expected transport %s, got %s", tt.transport, httpReader.config.Transport)
+			}
+			if strings.HasPrefix(tt.uri, "wss") && httpReader.config.TLSConfig == nil {
+				t.Error("Expected TLS config for wss scheme")
+			}
+		})
+	}
+}
+
+func TestHTTPReader_WebSocketRead(t *testing.T) {
+	server := newWebSocketServer(t, func(conn *websocket.Conn, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "user" || password != "pass" {
+			t.Errorf("Unexpected basic auth: %s:%s", username, password)
+		}
+		if r.Header.Get("X-API-Key") != "secret" {
+			t.Errorf("Unexpected X-API-Key header: %s", r.Header.Get("X-API-Key"))
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"key": "value"}`))
+		_, _, _ = conn.ReadMessage()
+	})
+
+	uri := strings.Replace(server.URL, "http://", "ws://user:pass@", 1) + "?header_X-API-Key=secret"
+	httpReader, err := NewHTTPReader(uri)
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	data, err := httpReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != `{"key": "value"}` {
+		t.Errorf("Unexpected data: %s", string(data))
+	}
+}
+
+func TestHTTPReader_WebSocketSubscribe(t *testing.T) {
+	var connections atomic.Int32
+	var pings atomic.Int32
+
+	server := newWebSocketServer(t, func(conn *websocket.Conn, r *http.Request) {
+		conn.SetPingHandler(func(data string) error {
+			pings.Add(1)
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+
+		switch connections.Add(1) {
+		case 1:
+			_ = conn.WriteMessage(websocket.TextMessage, []byte("key: text"))
+			_ = conn.WriteMessage(websocket.BinaryMessage, []byte("key: binary"))
+			// Drop the connection without a close frame to force a reconnect
+		default:
+			_ = conn.WriteMessage(websocket.TextMessage, []byte("key: reconnected"))
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	uri := server.URL + "?transport=websocket&retry_delay=10ms&ping_interval=20ms"
+	httpReader, err := NewHTTPReader(uri)
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+	defer httpReader.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := httpReader.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	var payloads []string
+	for len(payloads) < 3 {
+		event := readertest.NextEvent(t, events)
+		if event.IsValid() {
+			payloads = append(payloads, string(event.Data))
+		}
+	}
+
+	if strings.Join(payloads, ",") != "key: text,key: binary,key: reconnected" {
+		t.Errorf("Unexpected payloads: %q", payloads)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for pings.Load() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("Timeout waiting for keepalive ping")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestHTTPReader_WebSocketCloseStopsSubscription(t *testing.T) {
+	server := newWebSocketServer(t, func(conn *websocket.Conn, r *http.Request) {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	httpReader, err := NewHTTPReader(strings.Replace(server.URL, "http://", "ws://", 1))
+	if err != nil {
+		t.Fatalf("Failed to create HTTP reader: %v", err)
+	}
+
+	events, err := httpReader.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := httpReader.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Timeout waiting for subscription close")
+		}
+	}
+}
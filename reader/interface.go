@@ -3,6 +3,8 @@ package reader
 import (
 	"context"
 	"fmt"
+	"mime"
+	"strings"
 	"time"
 )
 
@@ -18,12 +20,68 @@ type ConfReader interface {
 	Close() error
 }
 
+// MetaReader is implemented by readers that can report content metadata
+// alongside the configuration bytes
+type MetaReader interface {
+	// ReadMeta reads configuration data together with its content metadata
+	ReadMeta(ctx context.Context) ([]byte, ContentMeta, error)
+}
+
+// MapReader is implemented by readers that can produce structured values
+// instead of a single document, e.g. by merging several documents
+type MapReader interface {
+	// Structured reports whether ReadMap should be used instead of Read
+	Structured() bool
+
+	// ReadMap reads configuration values ready for struct mapping
+	ReadMap(ctx context.Context) (map[string]any, error)
+}
+
+// ContentMeta describes the content reported by a backend
+type ContentMeta struct {
+	// MIMEType is the media type without parameters, e.g. application/json
+	MIMEType string `json:"mime_type,omitempty"`
+	// Charset is the character set declared by the backend
+	Charset string `json:"charset,omitempty"`
+	// Encoding is the transfer or content encoding declared by the backend
+	Encoding string `json:"encoding,omitempty"`
+	// Extension is a file extension hint, e.g. from a key name or config type
+	Extension string `json:"extension,omitempty"`
+}
+
+// IsZero reports whether no metadata is set
+func (m ContentMeta) IsZero() bool {
+	return m == ContentMeta{}
+}
+
+// ParseContentType builds metadata from a Content-Type header value
+func ParseContentType(value string) ContentMeta {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ContentMeta{}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(value, ";")
+		return ContentMeta{MIMEType: strings.ToLower(strings.TrimSpace(mediaType))}
+	}
+
+	return ContentMeta{
+		MIMEType: mediaType,
+		Charset:  strings.ToLower(params["charset"]),
+	}
+}
+
 // ReadEvent represents configuration update event
 type ReadEvent struct {
-	SourceURI string    `json:"source_uri"`
-	Timestamp time.Time `json:"timestamp"`
-	Data      []byte    `json:"data"`
-	Error     error     `json:"error,omitempty"`
+	SourceURI string         `json:"source_uri"`
+	Timestamp time.Time      `json:"timestamp"`
+	Data      []byte         `json:"data"`
+	Meta      ContentMeta    `json:"meta"`
+	Values    map[string]any `json:"values,omitempty"`
+	Revision  string         `json:"revision,omitempty"`
+	Error     error          `json:"error,omitempty"`
 }
 
 // NewReadEvent creates a new configuration event with validation
@@ -40,6 +98,72 @@ func NewReadEvent(sourceURI string, data []byte, err error) *ReadEvent {
 	}
 }
 
+// WithMeta attaches content metadata to the event
+func (e *ReadEvent) WithMeta(meta ContentMeta) *ReadEvent {
+	e.Meta = meta
+	return e
+}
+
+// WithValues attaches structured values to the event, which take precedence
+// over decoding Data
+func (e *ReadEvent) WithValues(values map[string]any) *ReadEvent {
+	e.Values = values
+	return e
+}
+
+// WithRevision attaches the backend revision of the content to the event, e.g.
+// a resource version
+func (e *ReadEvent) WithRevision(revision string) *ReadEvent {
+	e.Revision = revision
+	return e
+}
+
+// SameContent reports whether e carries the same data, or an error with the
+// same message, as other. Metadata and revisions are not compared, so a
+// rewrite of unchanged content can be skipped.
+func (e *ReadEvent) SameContent(other *ReadEvent) bool {
+	if e == nil || other == nil {
+		return false
+	}
+	if e.Error != nil || other.Error != nil {
+		return e.Error != nil && other.Error != nil && e.Error.Error() == other.Error.Error()
+	}
+	return string(e.Data) == string(other.Data)
+}
+
+// Reporter sends the events of a subscription, skipping events with the same
+// content as the last one sent
+type Reporter struct {
+	events chan<- *ReadEvent
+	last   *ReadEvent
+}
+
+// NewReporter creates a Reporter sending to events
+func NewReporter(events chan<- *ReadEvent) *Reporter {
+	return &Reporter{events: events}
+}
+
+// Report sends event unless SameContent reports it unchanged from the last
+// sent event. It returns false if ctx is done before the event is sent.
+func (r *Reporter) Report(ctx context.Context, event *ReadEvent) bool {
+	if event.SameContent(r.last) {
+		return true
+	}
+	return r.Send(ctx, event)
+}
+
+// Send sends event even if its content is unchanged. It returns false if ctx
+// is done before the event is sent.
+func (r *Reporter) Send(ctx context.Context, event *ReadEvent) bool {
+	r.last = event
+	select {
+	case r.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // IsValid checks if the configuration event is valid
 func (e *ReadEvent) IsValid() bool {
 	return e != nil && e.Error == nil && len(e.Data) > 0
@@ -0,0 +1,67 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReadEventSameContent(t *testing.T) {
+	event := NewReadEvent("test://", []byte("name: app"), nil).WithRevision("1")
+
+	tests := []struct {
+		name  string
+		event *ReadEvent
+		other *ReadEvent
+		want  bool
+	}{
+		{"no previous event", event, nil, false},
+		{"same data at another revision", event, NewReadEvent("test://", []byte("name: app"), nil).WithRevision("2"), true},
+		{"changed data", event, NewReadEvent("test://", []byte("name: other"), nil), false},
+		{"same error", NewReadEvent("test://", nil, errors.New("not found")), NewReadEvent("test://", nil, errors.New("not found")), true},
+		{"changed error", NewReadEvent("test://", nil, errors.New("not found")), NewReadEvent("test://", nil, errors.New("denied")), false},
+		{"error after data", NewReadEvent("test://", nil, errors.New("not found")), event, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.SameContent(tt.other); got != tt.want {
+				t.Errorf("SameContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReporter(t *testing.T) {
+	events := make(chan *ReadEvent, 4)
+	reporter := NewReporter(events)
+	ctx := context.Background()
+
+	reporter.Report(ctx, NewReadEvent("test://", []byte("name: app"), nil))
+	reporter.Report(ctx, NewReadEvent("test://", []byte("name: app"), nil).WithRevision("2"))
+	reporter.Report(ctx, NewReadEvent("test://", nil, errors.New("not found")))
+	reporter.Report(ctx, NewReadEvent("test://", nil, errors.New("not found")))
+	reporter.Send(ctx, NewReadEvent("test://", nil, errors.New("not found")))
+	reporter.Report(ctx, NewReadEvent("test://", []byte("name: app"), nil))
+
+	want := []string{"name: app", "not found", "not found", "name: app"}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(events))
+	}
+	for _, w := range want {
+		event := <-events
+		got := string(event.Data)
+		if event.Error != nil {
+			got = event.Error.Error()
+		}
+		if got != w {
+			t.Errorf("Expected event %q, got %q", w, got)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if NewReporter(make(chan *ReadEvent)).Report(cancelled, NewReadEvent("test://", []byte("name: app"), nil)) {
+		t.Error("Expected Report to return false when the context is done")
+	}
+}
@@ -0,0 +1,82 @@
+// Package readertest provides helpers for testing the subscriptions of
+// configuration readers.
+package readertest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+)
+
+// Timeout is how long the helpers wait for an event
+var Timeout = 10 * time.Second
+
+// NextEvent returns the next event, failing t if the channel is closed or no
+// event arrives within Timeout
+func NextEvent(t testing.TB, events <-chan *reader.ReadEvent) *reader.ReadEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Event channel closed")
+		}
+		return event
+	case <-time.After(Timeout):
+		t.Fatal("Timed out waiting for event")
+		return nil
+	}
+}
+
+// ExpectData fails t unless the next event carries want without an error
+func ExpectData(t testing.TB, events <-chan *reader.ReadEvent, want string) *reader.ReadEvent {
+	t.Helper()
+
+	event := NextEvent(t, events)
+	if event.Error != nil {
+		t.Fatalf("Unexpected event error: %v", event.Error)
+	}
+	if string(event.Data) != want {
+		t.Errorf("Expected event data %q, got %q", want, event.Data)
+	}
+	return event
+}
+
+// ExpectValues fails t unless the next event carries want as its values
+// without an error
+func ExpectValues(t testing.TB, events <-chan *reader.ReadEvent, want map[string]any) *reader.ReadEvent {
+	t.Helper()
+
+	event := NextEvent(t, events)
+	if event.Error != nil {
+		t.Fatalf("Unexpected event error: %v", event.Error)
+	}
+	if !reflect.DeepEqual(event.Values, want) {
+		t.Errorf("Expected event values %v, got %v", want, event.Values)
+	}
+	return event
+}
+
+// ExpectError fails t unless the next event carries an error
+func ExpectError(t testing.TB, events <-chan *reader.ReadEvent) *reader.ReadEvent {
+	t.Helper()
+
+	event := NextEvent(t, events)
+	if event.Error == nil {
+		t.Fatalf("Expected error event, got %q", event.Data)
+	}
+	return event
+}
+
+// ExpectNoEvent fails t if an event arrives within wait
+func ExpectNoEvent(t testing.TB, events <-chan *reader.ReadEvent, wait time.Duration) {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		t.Fatalf("Unexpected event: %+v", event)
+	case <-time.After(wait):
+	}
+}
@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// inClusterTokenFile is the service account token mounted into pods
+const inClusterTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// ClientConfig selects how a K8SReader connects to the cluster
+type ClientConfig struct {
+	// Kubeconfig is the kubeconfig file, defaulting to the files in $KUBECONFIG
+	// or ~/.kube/config
+	Kubeconfig string
+	// Context is the kubeconfig context, defaulting to the current context
+	Context string
+	// Cluster is the kubeconfig cluster, overriding the one of the context
+	Cluster string
+	// Impersonate is the user to act as, e.g.
+	// system:serviceaccount:{namespace}:{name} for a service account
+	Impersonate string
+	// RESTConfig is a prepared client config used instead of a kubeconfig
+	RESTConfig *rest.Config
+	// Clientset is a prepared client used as is
+	Clientset kubernetes.Interface
+	// DynamicClient is a prepared client of custom resources used as is
+	DynamicClient dynamic.Interface
+}
+
+// Option configures the client of a K8SReader.
+type Option func(*ClientConfig)
+
+// WithClientset sets a prepared client, e.g. one shared with the rest of the
+// application. The kubeconfig, context, cluster and as options cannot be used
+// with it.
+func WithClientset(clientset kubernetes.Interface) Option {
+	return func(config *ClientConfig) {
+		config.Clientset = clientset
+	}
+}
+
+// WithDynamicClient sets a prepared client of custom resources. The
+// kubeconfig, context, cluster and as options cannot be used with it.
+func WithDynamicClient(client dynamic.Interface) Option {
+	return func(config *ClientConfig) {
+		config.DynamicClient = client
+	}
+}
+
+// WithRESTConfig sets a prepared client config. The as option still applies
+// to it, the kubeconfig, context and cluster options cannot be used with it.
+func WithRESTConfig(restConfig *rest.Config) Option {
+	return func(config *ClientConfig) {
+		config.RESTConfig = restConfig
+	}
+}
+
+// createK8SClient creates k8s client
+func createK8SClient(config ClientConfig) (kubernetes.Interface, error) {
+	if config.Clientset != nil {
+		if config.kubeconfigSet() || config.Impersonate != "" {
+			return nil, fmt.Errorf("kubeconfig, context, cluster and as cannot be used with a prepared clientset")
+		}
+		return config.Clientset, nil
+	}
+
+	restConfig, err := config.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return clientset, nil
+}
+
+// createDynamicClient creates the client of custom resources, configured like
+// createK8SClient
+func createDynamicClient(config ClientConfig) (dynamic.Interface, error) {
+	if config.DynamicClient != nil {
+		if config.kubeconfigSet() || config.Impersonate != "" {
+			return nil, fmt.Errorf("kubeconfig, context, cluster and as cannot be used with a prepared dynamic client")
+		}
+		return config.DynamicClient, nil
+	}
+	if config.Clientset != nil {
+		return nil, fmt.Errorf("custom resources need a dynamic client, use WithDynamicClient")
+	}
+
+	restConfig, err := config.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s dynamic client: %w", err)
+	}
+	return client, nil
+}
+
+func (config ClientConfig) kubeconfigSet() bool {
+	return config.Kubeconfig != "" || config.Context != "" || config.Cluster != ""
+}
+
+// restConfig returns the prepared client config, impersonating the configured
+// user. Without kubeconfig options it uses the in-cluster config when running
+// in a pod, and the kubeconfig otherwise.
+func (config ClientConfig) restConfig() (*rest.Config, error) {
+	kubeconfigSet := config.kubeconfigSet()
+
+	var (
+		restConfig *rest.Config
+		err        error
+	)
+	switch {
+	case config.RESTConfig != nil:
+		if kubeconfigSet {
+			return nil, fmt.Errorf("kubeconfig, context and cluster cannot be used with a prepared rest config")
+		}
+		restConfig = rest.CopyConfig(config.RESTConfig)
+	case !kubeconfigSet && inCluster():
+		if restConfig, err = rest.InClusterConfig(); err != nil {
+			return nil, fmt.Errorf("failed to create in-cluster config: %w", err)
+		}
+	default:
+		if restConfig, err = kubeconfigRESTConfig(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Impersonate != "" {
+		restConfig.Impersonate = rest.ImpersonationConfig{UserName: config.Impersonate}
+	}
+	return restConfig, nil
+}
+
+// kubeconfigRESTConfig loads the client config of the selected kubeconfig
+// file, context and cluster
+func kubeconfigRESTConfig(config ClientConfig) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if config.Kubeconfig != "" {
+		rules.ExplicitPath = config.Kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: config.Context,
+		Context:        clientcmdapi.Context{Cluster: config.Cluster},
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build config from kubeconfig: %w", err)
+	}
+	return restConfig, nil
+}
+
+func inCluster() bool {
+	_, err := os.Stat(inClusterTokenFile)
+	return err == nil
+}
@@ -0,0 +1,175 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+// apiServer serves the configmap default/app-config and records the user
+// impersonated by each request
+type apiServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	impersonated []string
+}
+
+func newAPIServer(t *testing.T, data string) *apiServer {
+	t.Helper()
+	s := &apiServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.impersonated = append(s.impersonated, r.Header.Get("Impersonate-User"))
+		s.mu.Unlock()
+
+		if r.URL.Path != "/api/v1/namespaces/default/configmaps/app-config" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+			Data:       map[string]string{"name": data},
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *apiServer) lastImpersonated() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.impersonated) == 0 {
+		return ""
+	}
+	return s.impersonated[len(s.impersonated)-1]
+}
+
+func TestNewK8SReaderKubeconfig(t *testing.T) {
+	current := newAPIServer(t, "current")
+	staging := newAPIServer(t, "staging")
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: current
+clusters:
+- name: current
+  cluster: {server: %q}
+- name: staging
+  cluster: {server: %q}
+contexts:
+- name: current
+  context: {cluster: current, user: admin}
+- name: staging
+  context: {cluster: staging, user: admin}
+users:
+- name: admin
+  user: {token: token}
+`, current.URL, staging.URL)
+	if err := os.WriteFile(kubeconfig, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		query            string
+		want             string
+		wantImpersonated string
+	}{
+		{name: "current context", query: "", want: "current"},
+		{name: "context", query: "&context=staging", want: "staging"},
+		{name: "cluster", query: "&cluster=staging", want: "staging"},
+		{
+			name:             "impersonation",
+			query:            "&context=staging&as=system:serviceaccount:default:reader",
+			want:             "staging",
+			wantImpersonated: "system:serviceaccount:default:reader",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewK8SReader("k8s://configmap/default/app-config?kubeconfig=" + kubeconfig + tt.query)
+			if err != nil {
+				t.Fatalf("NewK8SReader() error = %v", err)
+			}
+			defer r.Close()
+
+			values, err := r.ReadMap(context.Background())
+			if err != nil {
+				t.Fatalf("ReadMap() error = %v", err)
+			}
+			if values["name"] != tt.want {
+				t.Errorf("ReadMap() name = %v, want %v", values["name"], tt.want)
+			}
+
+			server := current
+			if tt.want == "staging" {
+				server = staging
+			}
+			if got := server.lastImpersonated(); got != tt.wantImpersonated {
+				t.Errorf("Impersonate-User = %q, want %q", got, tt.wantImpersonated)
+			}
+		})
+	}
+
+	if _, err := NewK8SReader("k8s://configmap/default/app-config?kubeconfig=" + kubeconfig + "&context=missing"); err == nil {
+		t.Error("NewK8SReader() expected error for missing context")
+	}
+}
+
+func TestNewK8SReaderOptions(t *testing.T) {
+	server := newAPIServer(t, "prepared")
+
+	r, err := NewK8SReader("k8s://configmap/default/app-config?as=ops", WithRESTConfig(&rest.Config{Host: server.URL}))
+	if err != nil {
+		t.Fatalf("NewK8SReader() error = %v", err)
+	}
+	defer r.Close()
+	if _, err := r.ReadMap(context.Background()); err != nil {
+		t.Fatalf("ReadMap() error = %v", err)
+	}
+	if got := server.lastImpersonated(); got != "ops" {
+		t.Errorf("Impersonate-User = %q, want %q", got, "ops")
+	}
+
+	clientset := fake.NewClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+		Data:       map[string]string{"name": "fake"},
+	})
+	r, err = NewK8SReader("k8s://configmap/default/app-config", WithClientset(clientset))
+	if err != nil {
+		t.Fatalf("NewK8SReader() error = %v", err)
+	}
+	defer r.Close()
+	values, err := r.ReadMap(context.Background())
+	if err != nil {
+		t.Fatalf("ReadMap() error = %v", err)
+	}
+	if values["name"] != "fake" {
+		t.Errorf("ReadMap() name = %v, want fake", values["name"])
+	}
+
+	for _, uri := range []string{
+		"k8s://configmap/default/app-config?as=ops",
+		"k8s://configmap/default/app-config?context=staging",
+	} {
+		if _, err := NewK8SReader(uri, WithClientset(clientset)); err == nil {
+			t.Errorf("NewK8SReader(%s) expected error with a prepared clientset", uri)
+		}
+	}
+	if _, err := NewK8SReader("k8s://configmap/default/app-config?context=staging", WithRESTConfig(&rest.Config{Host: server.URL})); err == nil {
+		t.Error("NewK8SReader() expected error for context with a prepared rest config")
+	}
+}
@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// DefaultPath selects the spec of a custom resource
+const DefaultPath = ".spec"
+
+// parseCustomTarget parses the target of a custom resource URI:
+// k8s://{resource}.{group}/{version}/{namespace}/{name}[?path={jsonPath}]
+func parseCustomTarget(resourceType string, parts []string, query url.Values) (k8sTarget, error) {
+	resource, group, _ := strings.Cut(resourceType, ".")
+	if resource == "" || group == "" {
+		return k8sTarget{}, fmt.Errorf("invalid custom resource type: %s, expected: {resource}.{group}", resourceType)
+	}
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return k8sTarget{}, fmt.Errorf("invalid k8s URI path, expected format: k8s://{resource}.{group}/{version}/{namespace}/{name}")
+	}
+	if query.Has("selector") {
+		return k8sTarget{}, fmt.Errorf("selector is not supported for custom resources")
+	}
+
+	path := DefaultPath
+	if value := query.Get("path"); value != "" {
+		path = value
+	}
+	if _, err := parseJSONPath(path); err != nil {
+		return k8sTarget{}, err
+	}
+
+	return k8sTarget{
+		resourceType: resourceType,
+		namespace:    parts[1],
+		name:         parts[2],
+		gvr:          schema.GroupVersionResource{Group: group, Version: parts[0], Resource: resource},
+		path:         path,
+	}, nil
+}
+
+// custom reports whether the reader reads a custom resource
+func (k *K8SReader) custom() bool {
+	return !k.gvr.Empty()
+}
+
+// hasClient reports whether the client of the resource type is initialized
+func (k *K8SReader) hasClient() bool {
+	if k.custom() {
+		return k.dynamicClient != nil
+	}
+	return k.clientset != nil
+}
+
+// readCustomValues reads the custom resource and selects the values at its
+// path. It also returns the resource version.
+func (k *K8SReader) readCustomValues(ctx context.Context) (map[string]any, string, error) {
+	obj, err := k.dynamicClient.Resource(k.gvr).Namespace(k.namespace).Get(ctx, k.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get %s %s/%s: %w", k.resourceType, k.namespace, k.name, err)
+	}
+	return k.customValues([]any{obj})
+}
+
+// customValues selects the values at the path of the watched custom resource
+// among objects, and returns its resource version
+func (k *K8SReader) customValues(objects []any) (map[string]any, string, error) {
+	for _, obj := range objects {
+		object, ok := obj.(*unstructured.Unstructured)
+		if !ok || object.GetName() != k.name {
+			continue
+		}
+
+		values, err := selectPath(object.Object, k.path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to select %s of %s %s/%s: %w", k.path, k.resourceType, k.namespace, k.name, err)
+		}
+		return values, object.GetResourceVersion(), nil
+	}
+	return nil, "", fmt.Errorf("%s %s/%s not found", k.resourceType, k.namespace, k.name)
+}
+
+// selectPath returns a copy of the object the JSONPath selects in content
+func selectPath(content map[string]any, path string) (map[string]any, error) {
+	jp, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	results, err := jp.FindResults(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []any
+	for _, result := range results {
+		for _, value := range result {
+			matches = append(matches, value.Interface())
+		}
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("path matches %d values, expected 1", len(matches))
+	}
+
+	values, ok := matches[0].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("path does not select an object")
+	}
+	// Objects of the informer cache must not be modified
+	return runtime.DeepCopyJSON(values), nil
+}
+
+// parseJSONPath parses a JSONPath expression, with or without the braces of
+// kubectl templates, e.g. .spec.config or {.spec.config}
+func parseJSONPath(path string) (*jsonpath.JSONPath, error) {
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	jp := jsonpath.New("path")
+	if err := jp.Parse(path); err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	return jp, nil
+}
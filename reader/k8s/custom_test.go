@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+var appConfigResource = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "appconfigs"}
+
+func newAppConfig(name string, config map[string]any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "example.com/v1",
+		"kind":       "AppConfig",
+		"metadata":   map[string]any{"name": name, "namespace": "default"},
+		"spec":       map[string]any{"owner": "ops", "config": config},
+	}}
+}
+
+func TestParseCustomTarget(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    k8sTarget
+		wantErr bool
+	}{
+		{
+			uri: "k8s://appconfigs.example.com/v1/default/app?path=.spec.config",
+			want: k8sTarget{
+				resourceType: "appconfigs.example.com",
+				namespace:    "default",
+				name:         "app",
+				gvr:          appConfigResource,
+				path:         ".spec.config",
+			},
+		},
+		{
+			uri: "k8s://appconfigs.example.com/v1/default/app",
+			want: k8sTarget{
+				resourceType: "appconfigs.example.com",
+				namespace:    "default",
+				name:         "app",
+				gvr:          appConfigResource,
+				path:         DefaultPath,
+			},
+		},
+		{uri: "k8s://appconfigs.example.com/default/app", wantErr: true},
+		{uri: "k8s://appconfigs.example.com/v1/default/app/key", wantErr: true},
+		{uri: "k8s://appconfigs.example.com/v1/default?selector=app=foo", wantErr: true},
+		{uri: "k8s://appconfigs.example.com/v1/default/app?path=.spec[", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			target, err := parseK8STarget(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseK8STarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(target, tt.want) {
+				t.Errorf("parseK8STarget() = %+v, want %+v", target, tt.want)
+			}
+		})
+	}
+}
+
+func TestK8SReaderCustomResource(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{appConfigResource: "AppConfigList"},
+		newAppConfig("app", map[string]any{"name": "app", "port": int64(8080)}),
+		newAppConfig("other", map[string]any{"name": "other"}),
+	)
+	uri := "k8s://appconfigs.example.com/v1/default/app?path=.spec.config"
+	r, err := NewK8SReader(uri, WithDynamicClient(client))
+	if err != nil {
+		t.Fatalf("NewK8SReader() error = %v", err)
+	}
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	want := map[string]any{"name": "app", "port": int64(8080)}
+	values, err := r.ReadMap(ctx)
+	if err != nil {
+		t.Fatalf("ReadMap() error = %v", err)
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("ReadMap() = %v, want %v", values, want)
+	}
+
+	events, err := r.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	readertest.ExpectValues(t, events, want)
+
+	appConfigs := client.Resource(appConfigResource).Namespace("default")
+	update := func(obj *unstructured.Unstructured) {
+		t.Helper()
+		if _, err := appConfigs.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+
+	// Changes outside the path and to other resources are not reported
+	unchanged := newAppConfig("app", map[string]any{"name": "app", "port": int64(8080)})
+	_ = unstructured.SetNestedField(unchanged.Object, "dev", "spec", "owner")
+	update(unchanged)
+	update(newAppConfig("other", map[string]any{"name": "changed"}))
+	update(newAppConfig("app", map[string]any{"name": "app", "port": int64(9090)}))
+	readertest.ExpectValues(t, events, map[string]any{"name": "app", "port": int64(9090)})
+
+	// A path that no longer selects an object is reported as an error
+	broken := newAppConfig("app", nil)
+	unstructured.RemoveNestedField(broken.Object, "spec", "config")
+	update(broken)
+	select {
+	case event := <-events:
+		if event.Error == nil {
+			t.Errorf("event values = %v, want error", event.Values)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for error event")
+	}
+}
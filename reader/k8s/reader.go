@@ -3,20 +3,25 @@
 package k8s
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"os"
+	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/sower-proxy/feconf/reader"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
@@ -39,46 +44,83 @@ func init() {
 
 // K8SReader implements ConfReader for kubernetes configmap/secret
 type K8SReader struct {
-	uri          string
-	resourceType string
-	namespace    string
-	name         string
-	key          string
-	clientset    kubernetes.Interface
-	informer     cache.SharedIndexInformer
-	stopCh       chan struct{}
-	mu           sync.RWMutex
-	closed       bool
+	uri                string
+	resourceType       string
+	namespace          string
+	name               string
+	key                string
+	selector           labels.Selector
+	priorityAnnotation string
+	gvr                schema.GroupVersionResource
+	path               string
+	clientset          kubernetes.Interface
+	dynamicClient      dynamic.Interface
+	informer           cache.SharedIndexInformer
+	stopCh             chan struct{}
+	mu                 sync.RWMutex
+	closed             bool
 }
 
 type k8sTarget struct {
-	resourceType string
-	namespace    string
-	name         string
-	key          string
+	resourceType       string
+	namespace          string
+	name               string
+	key                string
+	selector           labels.Selector
+	priorityAnnotation string
+	gvr                schema.GroupVersionResource
+	path               string
+	client             ClientConfig
 }
 
 // NewK8SReader creates a new k8s reader
 // URI format: k8s://{resourceType}/{namespace}/{name}[/{key}]
+// or k8s://{resourceType}/{namespace}?selector={labelSelector}
+// or k8s://{resource}.{group}/{version}/{namespace}/{name}[?path={jsonPath}]
 // Example: k8s://configmap/default/my-config/config.yaml
-func NewK8SReader(uri string) (*K8SReader, error) {
+//
+// The client is configured from the kubeconfig, context, cluster and as
+// (impersonated user) query parameters, unless an Option provides one.
+//
+// Without a key, all keys of the resource are read as structured values. A
+// label selector merges all matching resources ordered by their
+// feconf/priority annotation, or the annotation named by priority_annotation.
+func NewK8SReader(uri string, opts ...Option) (*K8SReader, error) {
 	target, err := parseK8STarget(uri)
 	if err != nil {
 		return nil, err
 	}
 
-	clientset, err := createK8SClient()
+	config := target.client
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	var (
+		clientset     kubernetes.Interface
+		dynamicClient dynamic.Interface
+	)
+	if target.gvr.Empty() {
+		clientset, err = createK8SClient(config)
+	} else {
+		dynamicClient, err = createDynamicClient(config)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create k8s client: %w", err)
 	}
 
 	return &K8SReader{
-		uri:          uri,
-		resourceType: target.resourceType,
-		namespace:    target.namespace,
-		name:         target.name,
-		key:          target.key,
-		clientset:    clientset,
+		uri:                uri,
+		resourceType:       target.resourceType,
+		namespace:          target.namespace,
+		name:               target.name,
+		key:                target.key,
+		selector:           target.selector,
+		priorityAnnotation: target.priorityAnnotation,
+		gvr:                target.gvr,
+		path:               target.path,
+		clientset:          clientset,
+		dynamicClient:      dynamicClient,
 	}, nil
 }
 
@@ -100,15 +142,59 @@ func parseK8STarget(uri string) (k8sTarget, error) {
 
 	resourceType := u.Host
 
-	// Validate resource type
-	if resourceType != ResourceTypeConfigMap && resourceType != ResourceTypeSecret {
-		return k8sTarget{}, fmt.Errorf("unsupported resource type: %s, expected: %s or %s", resourceType, ResourceTypeConfigMap, ResourceTypeSecret)
-	}
-
 	// Parse path: /{namespace}/{name}[/{key}]
 	// Using strings.Split to properly handle path segments
 	path := strings.TrimPrefix(u.Path, "/")
 	parts := strings.Split(path, "/")
+
+	query := u.Query()
+	client := ClientConfig{
+		Kubeconfig:  query.Get("kubeconfig"),
+		Context:     query.Get("context"),
+		Cluster:     query.Get("cluster"),
+		Impersonate: query.Get("as"),
+	}
+
+	// Resource types with a group are custom resources
+	if strings.Contains(resourceType, ".") {
+		target, err := parseCustomTarget(resourceType, parts, query)
+		if err != nil {
+			return k8sTarget{}, err
+		}
+		target.client = client
+		return target, nil
+	}
+
+	// Validate resource type
+	if resourceType != ResourceTypeConfigMap && resourceType != ResourceTypeSecret {
+		return k8sTarget{}, fmt.Errorf("unsupported resource type: %s, expected: %s, %s or {resource}.{group}", resourceType, ResourceTypeConfigMap, ResourceTypeSecret)
+	}
+	priorityAnnotation := DefaultPriorityAnnotation
+	if annotation := query.Get("priority_annotation"); annotation != "" {
+		priorityAnnotation = annotation
+	}
+
+	// Parse selector URI: k8s://{resourceType}/{namespace}?selector={labelSelector}
+	if query.Has("selector") {
+		if len(parts) != 1 || parts[0] == "" {
+			return k8sTarget{}, fmt.Errorf("invalid k8s URI path, expected format: k8s://{resourceType}/{namespace}?selector={labelSelector}")
+		}
+		selector, err := labels.Parse(query.Get("selector"))
+		if err != nil {
+			return k8sTarget{}, fmt.Errorf("invalid label selector: %w", err)
+		}
+		if selector.Empty() {
+			return k8sTarget{}, fmt.Errorf("label selector cannot be empty")
+		}
+		return k8sTarget{
+			resourceType:       resourceType,
+			namespace:          parts[0],
+			selector:           selector,
+			priorityAnnotation: priorityAnnotation,
+			client:             client,
+		}, nil
+	}
+
 	if len(parts) < 2 {
 		return k8sTarget{}, fmt.Errorf("invalid k8s URI path, expected format: k8s://{resourceType}/{namespace}/{name}[/{key}]")
 	}
@@ -121,41 +207,67 @@ func parseK8STarget(uri string) (k8sTarget, error) {
 	}
 
 	return k8sTarget{
-		resourceType: resourceType,
-		namespace:    namespace,
-		name:         name,
-		key:          key,
+		resourceType:       resourceType,
+		namespace:          namespace,
+		name:               name,
+		key:                key,
+		priorityAnnotation: priorityAnnotation,
+		client:             client,
 	}, nil
 }
 
 // Read reads configuration data from k8s configmap/secret
 func (k *K8SReader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := k.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads configuration data and reports the extension of the key it came from
+func (k *K8SReader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
 	if k.closed {
-		return nil, fmt.Errorf("reader is closed")
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
 	}
 
 	select {
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return nil, reader.ContentMeta{}, ctx.Err()
 	default:
 	}
 
 	// Check if clientset is nil (for testing)
-	if k.clientset == nil {
-		return nil, fmt.Errorf("k8s client not initialized")
+	if !k.hasClient() {
+		return nil, reader.ContentMeta{}, fmt.Errorf("k8s client not initialized")
+	}
+
+	if k.Structured() {
+		values, _, err := k.readValues(ctx)
+		if err != nil {
+			return nil, reader.ContentMeta{}, err
+		}
+		return encodeValues(values)
 	}
 
+	var (
+		data []byte
+		key  string
+		err  error
+	)
 	switch k.resourceType {
 	case ResourceTypeConfigMap:
-		return k.readConfigMap(ctx)
+		data, key, err = k.readConfigMap(ctx)
 	case ResourceTypeSecret:
-		return k.readSecret(ctx)
+		data, key, err = k.readSecret(ctx)
 	default:
-		return nil, fmt.Errorf("unsupported resource type: %s", k.resourceType)
+		return nil, reader.ContentMeta{}, fmt.Errorf("unsupported resource type: %s", k.resourceType)
 	}
+	if err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
+
+	return data, reader.ContentMeta{Extension: filepath.Ext(key)}, nil
 }
 
 // Subscribe subscribes to k8s configmap/secret changes and returns update channel
@@ -172,22 +284,13 @@ func (k *K8SReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, er
 	}
 
 	// Check if clientset is nil (for testing)
-	if k.clientset == nil {
+	if !k.hasClient() {
 		return nil, fmt.Errorf("k8s client not initialized")
 	}
 
-	// Create informer based on resource type
-	factory := informers.NewSharedInformerFactoryWithOptions(
-		k.clientset, 0, informers.WithNamespace(k.namespace))
-
-	var informer cache.SharedIndexInformer
-	switch k.resourceType {
-	case ResourceTypeConfigMap:
-		informer = factory.Core().V1().ConfigMaps().Informer()
-	case ResourceTypeSecret:
-		informer = factory.Core().V1().Secrets().Informer()
-	default:
-		return nil, fmt.Errorf("unsupported resource type: %s", k.resourceType)
+	informer, err := k.newInformer()
+	if err != nil {
+		return nil, err
 	}
 
 	k.informer = informer
@@ -195,22 +298,21 @@ func (k *K8SReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, er
 
 	eventChan := make(chan *reader.ReadEvent, 1)
 
-	// Set up event handlers
+	if k.Structured() {
+		return k.subscribeValues(ctx, informer, eventChan)
+	}
+
+	// Set up event handlers, which the informer calls one at a time
+	state := &watchState{}
 	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj any) {
-			k.handleResourceUpdate(ctx, eventChan)
+			k.handleResourceUpdate(ctx, obj, state, eventChan)
 		},
 		UpdateFunc: func(oldObj, newObj any) {
-			k.handleResourceUpdate(ctx, eventChan)
+			k.handleResourceUpdate(ctx, newObj, state, eventChan)
 		},
 		DeleteFunc: func(obj any) {
-			// Send empty event when resource is deleted
-			confEvent := reader.NewReadEvent(k.uri, nil, fmt.Errorf("%s deleted", k.resourceType))
-			select {
-			case eventChan <- confEvent:
-			case <-ctx.Done():
-				// Context cancelled, ignore the event
-			}
+			k.handleResourceDelete(ctx, obj, state, eventChan)
 		},
 	})
 
@@ -218,15 +320,124 @@ func (k *K8SReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, er
 	go informer.Run(k.stopCh)
 
 	// Wait for cache sync
-	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+	if err := k.waitForCacheSync(ctx); err != nil {
+		return nil, err
+	}
+
+	return eventChan, nil
+}
+
+// newInformer creates an informer based on resource type, watching only the
+// named resource or the resources matching the selector
+func (k *K8SReader) newInformer() (cache.SharedIndexInformer, error) {
+	tweakListOptions := func(options *metav1.ListOptions) {
+		if k.selector != nil {
+			options.LabelSelector = k.selector.String()
+			return
+		}
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", k.name).String()
+	}
+
+	if k.custom() {
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(
+			k.dynamicClient, 0, k.namespace, tweakListOptions)
+		return factory.ForResource(k.gvr).Informer(), nil
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		k.clientset, 0, informers.WithNamespace(k.namespace),
+		informers.WithTweakListOptions(tweakListOptions))
+
+	switch k.resourceType {
+	case ResourceTypeConfigMap:
+		return factory.Core().V1().ConfigMaps().Informer(), nil
+	case ResourceTypeSecret:
+		return factory.Core().V1().Secrets().Informer(), nil
+	default:
+		return nil, fmt.Errorf("unsupported resource type: %s", k.resourceType)
+	}
+}
+
+// subscribeValues watches the resources read as structured values. Changes
+// are coalesced and the values merged from the informer cache are reported
+// when they differ from the last report.
+func (k *K8SReader) subscribeValues(ctx context.Context, informer cache.SharedIndexInformer, eventChan chan *reader.ReadEvent) (<-chan *reader.ReadEvent, error) {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { notify() },
+		UpdateFunc: func(oldObj, newObj any) { notify() },
+		DeleteFunc: func(obj any) { notify() },
+	})
+
+	go informer.Run(k.stopCh)
+
+	if err := k.waitForCacheSync(ctx); err != nil {
+		return nil, err
+	}
+
+	// Report the synced values first
+	notify()
+	go k.watchValues(ctx, informer.GetStore(), changed, k.stopCh, eventChan)
+
+	return eventChan, nil
+}
+
+func (k *K8SReader) watchValues(ctx context.Context, store cache.Store, changed <-chan struct{}, stopCh <-chan struct{}, eventChan chan<- *reader.ReadEvent) {
+	var (
+		last    []byte
+		lastErr string
+	)
+	for {
+		select {
+		case <-changed:
+		case <-stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		values, revision, err := k.objectValues(store.List())
+		var (
+			data []byte
+			meta reader.ContentMeta
+		)
+		if err == nil {
+			data, meta, err = encodeValues(values)
+		}
+		if err != nil {
+			// Report an error once until the values recover
+			if err.Error() == lastErr {
+				continue
+			}
+			last, lastErr = nil, err.Error()
+			k.sendEvent(ctx, eventChan, reader.NewReadEvent(k.uri, nil, err))
+			continue
+		}
+		if bytes.Equal(data, last) {
+			continue
+		}
+		last, lastErr = data, ""
+		k.sendEvent(ctx, eventChan, reader.NewReadEvent(k.uri, data, nil).WithMeta(meta).WithValues(values).WithRevision(revision))
+	}
+}
+
+// waitForCacheSync waits for the started informer to sync, stopping it on
+// failure
+func (k *K8SReader) waitForCacheSync(ctx context.Context) error {
+	if !cache.WaitForCacheSync(ctx.Done(), k.informer.HasSynced) {
 		// Clean up resources on failure
 		close(k.stopCh)
 		k.informer = nil
 		k.stopCh = nil
-		return nil, fmt.Errorf("failed to sync cache")
+		return fmt.Errorf("failed to sync cache")
 	}
-
-	return eventChan, nil
+	return nil
 }
 
 // Close closes the reader and cleans up resources
@@ -254,130 +465,120 @@ func (k *K8SReader) Close() error {
 	return nil
 }
 
-// readConfigMap reads data from configmap
-func (k *K8SReader) readConfigMap(ctx context.Context) ([]byte, error) {
+// readConfigMap reads data from configmap and returns the key it was read from
+func (k *K8SReader) readConfigMap(ctx context.Context) ([]byte, string, error) {
 	// Check if clientset is nil (for testing)
 	if k.clientset == nil {
-		return nil, fmt.Errorf("k8s client not initialized")
+		return nil, "", fmt.Errorf("k8s client not initialized")
 	}
 
 	cm, err := k.clientset.CoreV1().ConfigMaps(k.namespace).Get(ctx, k.name, metav1.GetOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get configmap %s/%s: %w", k.namespace, k.name, err)
-	}
-
-	if k.key != "" {
-		// Return specific key value
-		if value, exists := cm.Data[k.key]; exists {
-			return []byte(value), nil
-		}
-		return nil, fmt.Errorf("key %s not found in configmap %s/%s", k.key, k.namespace, k.name)
+		return nil, "", fmt.Errorf("failed to get configmap %s/%s: %w", k.namespace, k.name, err)
 	}
 
-	// When no specific key is requested, return the first key's value if there's exactly one
-	if len(cm.Data) == 0 {
-		return nil, fmt.Errorf("configmap %s/%s is empty", k.namespace, k.name)
-	}
+	return k.configMapData(cm)
+}
 
-	if len(cm.Data) == 1 {
-		// Return the value of the single key
-		for _, value := range cm.Data {
-			return []byte(value), nil
-		}
+// configMapData returns the value of the configured key of a configmap and the key it was read from
+func (k *K8SReader) configMapData(cm *corev1.ConfigMap) ([]byte, string, error) {
+	if value, exists := cm.Data[k.key]; exists {
+		return []byte(value), k.key, nil
 	}
-
-	// Multiple keys exist but no specific key requested
-	return nil, fmt.Errorf("configmap %s/%s contains multiple keys, please specify one: %v", k.namespace, k.name, getMapKeys(cm.Data))
+	return nil, "", fmt.Errorf("key %s not found in configmap %s/%s, available keys: %v", k.key, k.namespace, k.name, getMapKeys(cm.Data))
 }
 
-// readSecret reads data from secret
-func (k *K8SReader) readSecret(ctx context.Context) ([]byte, error) {
+// readSecret reads data from secret and returns the key it was read from
+func (k *K8SReader) readSecret(ctx context.Context) ([]byte, string, error) {
 	// Check if clientset is nil (for testing)
 	if k.clientset == nil {
-		return nil, fmt.Errorf("k8s client not initialized")
+		return nil, "", fmt.Errorf("k8s client not initialized")
 	}
 
 	secret, err := k.clientset.CoreV1().Secrets(k.namespace).Get(ctx, k.name, metav1.GetOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get secret %s/%s: %w", k.namespace, k.name, err)
+		return nil, "", fmt.Errorf("failed to get secret %s/%s: %w", k.namespace, k.name, err)
 	}
 
-	if k.key != "" {
-		// Return specific key value
-		if value, exists := secret.Data[k.key]; exists {
-			return value, nil
-		}
-		return nil, fmt.Errorf("key %s not found in secret %s/%s", k.key, k.namespace, k.name)
-	}
-
-	// When no specific key is requested, return the first key's value if there's exactly one
-	if len(secret.Data) == 0 {
-		return nil, fmt.Errorf("secret %s/%s is empty", k.namespace, k.name)
-	}
+	return k.secretData(secret)
+}
 
-	if len(secret.Data) == 1 {
-		// Return the value of the single key
-		for _, value := range secret.Data {
-			return value, nil
-		}
+// secretData returns the value of the configured key of a secret and the key it was read from
+func (k *K8SReader) secretData(secret *corev1.Secret) ([]byte, string, error) {
+	if value, exists := secret.Data[k.key]; exists {
+		return value, k.key, nil
 	}
-
-	// Multiple keys exist but no specific key requested
-	return nil, fmt.Errorf("secret %s/%s contains multiple keys, please specify one: %v", k.namespace, k.name, getMapKeysByte(secret.Data))
+	return nil, "", fmt.Errorf("key %s not found in secret %s/%s, available keys: %v", k.key, k.namespace, k.name, getMapKeysByte(secret.Data))
 }
 
-// handleResourceUpdate handles resource update events
-func (k *K8SReader) handleResourceUpdate(ctx context.Context, eventChan chan<- *reader.ReadEvent) {
-	// Add small delay to ensure resource update is complete
-	time.Sleep(100 * time.Millisecond)
-
-	data, err := k.Read(ctx)
-	confEvent := reader.NewReadEvent(k.uri, data, err)
+// watchState is the last resource version and data seen by a subscription
+type watchState struct {
+	resourceVersion string
+	data            []byte
+	synced          bool
+}
 
-	select {
-	case eventChan <- confEvent:
-	case <-ctx.Done():
-		// Context cancelled, ignore the event
+// handleResourceUpdate handles resource add and update events. Events of
+// other resources, of an already seen resource version, or leaving the
+// configured data unchanged are skipped.
+func (k *K8SReader) handleResourceUpdate(ctx context.Context, obj any, state *watchState, eventChan chan<- *reader.ReadEvent) {
+	object, err := apimeta.Accessor(obj)
+	if err != nil || object.GetName() != k.name {
+		return
+	}
+	if object.GetResourceVersion() == state.resourceVersion {
+		return
+	}
+	state.resourceVersion = object.GetResourceVersion()
+
+	var (
+		data []byte
+		key  string
+	)
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		data, key, err = k.configMapData(obj)
+	case *corev1.Secret:
+		data, key, err = k.secretData(obj)
+	default:
+		err = fmt.Errorf("unexpected object type %T", obj)
+	}
+	if err == nil && state.synced && bytes.Equal(data, state.data) {
+		return
 	}
+	state.data = data
+	state.synced = err == nil
+
+	k.sendEvent(ctx, eventChan, reader.NewReadEvent(k.uri, data, err).
+		WithMeta(reader.ContentMeta{Extension: filepath.Ext(key)}).
+		WithRevision(object.GetResourceVersion()))
 }
 
-// createK8SClient creates k8s client
-func createK8SClient() (kubernetes.Interface, error) {
-	// Check if we're running in-cluster
-	if _, err := os.Stat("/var/run/secrets/kubernetes.io/serviceaccount/token"); err == nil {
-		// Use in-cluster config
-		config, err := rest.InClusterConfig()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create in-cluster config: %w", err)
+// handleResourceDelete handles resource delete events, including tombstones
+// of deletions the informer missed while disconnected
+func (k *K8SReader) handleResourceDelete(ctx context.Context, obj any, state *watchState, eventChan chan<- *reader.ReadEvent) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		if tombstone.Key != k.namespace+"/"+k.name {
+			return
 		}
-		clientset, err := kubernetes.NewForConfig(config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create k8s client from in-cluster config: %w", err)
-		}
-		return clientset, nil
+		obj = tombstone.Obj
 	}
-
-	// Use kubeconfig from environment or default location
-	kubeconfig := os.Getenv("KUBECONFIG")
-	if kubeconfig == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get user home directory: %w", err)
-		}
-		kubeconfig = homeDir + "/.kube/config"
+	if object, err := apimeta.Accessor(obj); err == nil && object.GetName() != k.name {
+		return
 	}
 
-	// Use the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build config from kubeconfig (%s): %w", kubeconfig, err)
-	}
+	// A recreated resource is reported again
+	*state = watchState{}
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create k8s client from kubeconfig: %w", err)
+	k.sendEvent(ctx, eventChan, reader.NewReadEvent(k.uri, nil, fmt.Errorf("%s %s/%s deleted", k.resourceType, k.namespace, k.name)))
+}
+
+func (k *K8SReader) sendEvent(ctx context.Context, eventChan chan<- *reader.ReadEvent, event *reader.ReadEvent) {
+	select {
+	case eventChan <- event:
+	case <-ctx.Done():
+		// Context cancelled, ignore the event
 	}
-	return clientset, nil
 }
 
 // getMapKeys returns keys from string map
@@ -3,8 +3,15 @@ package k8s
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/sower-proxy/feconf/reader"
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestParseURI(t *testing.T) {
@@ -16,6 +23,7 @@ func TestParseURI(t *testing.T) {
 		namespace    string
 		configName   string
 		key          string
+		selector     string
 	}{
 		{
 			name:         "valid configmap uri",
@@ -68,6 +76,29 @@ func TestParseURI(t *testing.T) {
 			uri:     "k8s://configmap/default",
 			wantErr: true,
 		},
+		{
+			name:         "valid selector uri",
+			uri:          "k8s://configmap/default?selector=app=foo,tier=config",
+			wantErr:      false,
+			resourceType: ResourceTypeConfigMap,
+			namespace:    "default",
+			selector:     "app=foo,tier=config",
+		},
+		{
+			name:    "selector with name",
+			uri:     "k8s://configmap/default/my-config?selector=app=foo",
+			wantErr: true,
+		},
+		{
+			name:    "invalid selector",
+			uri:     "k8s://configmap/default?selector=app=(foo",
+			wantErr: true,
+		},
+		{
+			name:    "empty selector",
+			uri:     "k8s://configmap/default?selector=",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -91,6 +122,9 @@ func TestParseURI(t *testing.T) {
 				if target.key != tt.key {
 					t.Errorf("parseK8STarget() key = %v, want %v", target.key, tt.key)
 				}
+				if target.selector != nil && target.selector.String() != tt.selector || target.selector == nil && tt.selector != "" {
+					t.Errorf("parseK8STarget() selector = %v, want %v", target.selector, tt.selector)
+				}
 			}
 		})
 	}
@@ -113,3 +147,119 @@ func TestK8SReaderReadWithoutClient(t *testing.T) {
 		t.Error("Read() expected error but got nil")
 	}
 }
+
+func TestK8SReaderSubscribe(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default", ResourceVersion: "1"},
+			Data:       map[string]string{"config.yaml": "name: v1"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default", ResourceVersion: "1"},
+			Data:       map[string]string{"config.yaml": "name: other"},
+		},
+	)
+	r := &K8SReader{
+		uri:          "k8s://configmap/default/app-config/config.yaml",
+		resourceType: ResourceTypeConfigMap,
+		namespace:    "default",
+		name:         "app-config",
+		key:          "config.yaml",
+		clientset:    clientset,
+	}
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events, err := r.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	readertest.ExpectData(t, events, "name: v1")
+
+	listed := false
+	for _, action := range clientset.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok {
+			listed = true
+			if got := list.GetListRestrictions().Fields.String(); got != "metadata.name=app-config" {
+				t.Errorf("list field selector = %q, want %q", got, "metadata.name=app-config")
+			}
+		}
+	}
+	if !listed {
+		t.Error("informer did not list configmaps")
+	}
+
+	configMaps := clientset.CoreV1().ConfigMaps("default")
+	update := func(name, resourceVersion string, data map[string]string, labels map[string]string) {
+		t.Helper()
+		_, err := configMaps.Update(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: resourceVersion, Labels: labels},
+			Data:       data,
+		}, metav1.UpdateOptions{})
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+
+	// Other objects and metadata-only changes are not reported
+	update("other", "2", map[string]string{"config.yaml": "name: changed"}, nil)
+	update("app-config", "2", map[string]string{"config.yaml": "name: v1"}, map[string]string{"team": "ops"})
+	update("app-config", "3", map[string]string{"config.yaml": "name: v2"}, nil)
+	readertest.ExpectData(t, events, "name: v2")
+
+	if err := configMaps.Delete(ctx, "app-config", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	select {
+	case event := <-events:
+		if event.Error == nil {
+			t.Errorf("event after delete = %q, want error", event.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for delete event")
+	}
+}
+
+func TestK8SReaderHandleTombstone(t *testing.T) {
+	r := &K8SReader{
+		uri:          "k8s://secret/default/app-secret/password",
+		resourceType: ResourceTypeSecret,
+		namespace:    "default",
+		name:         "app-secret",
+		key:          "password",
+	}
+	state := &watchState{resourceVersion: "5", data: []byte("secret"), synced: true}
+	eventChan := make(chan *reader.ReadEvent, 2)
+	ctx := context.Background()
+
+	r.handleResourceDelete(ctx, cache.DeletedFinalStateUnknown{Key: "default/other"}, state, eventChan)
+	if len(eventChan) != 0 {
+		t.Fatalf("tombstone of another object emitted %d events", len(eventChan))
+	}
+
+	r.handleResourceDelete(ctx, cache.DeletedFinalStateUnknown{
+		Key: "default/app-secret",
+		Obj: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"}},
+	}, state, eventChan)
+	if event := <-eventChan; event.Error == nil {
+		t.Error("tombstone event error = nil, want error")
+	}
+	if state.synced || state.resourceVersion != "" {
+		t.Errorf("state after delete = %+v, want reset", state)
+	}
+
+	// The recreated secret is reported even with the same data
+	r.handleResourceUpdate(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default", ResourceVersion: "7"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}, state, eventChan)
+	event := <-eventChan
+	if event.Error != nil || string(event.Data) != "secret" {
+		t.Errorf("event after recreate = %q, %v, want %q", event.Data, event.Error, "secret")
+	}
+	if event.Revision != "7" {
+		t.Errorf("event revision = %q, want 7", event.Revision)
+	}
+}
@@ -0,0 +1,285 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ReportMode selects how a Reporter records outcomes
+type ReportMode string
+
+const (
+	// ReportEvent records outcomes as Kubernetes Events
+	ReportEvent ReportMode = "event"
+	// ReportAnnotation records the last outcome in an annotation
+	ReportAnnotation ReportMode = "annotation"
+
+	// StatusAnnotationPrefix prefixes the status annotations, which are named
+	// after the pod on a source resource and after the source on a pod
+	StatusAnnotationPrefix = "status.feconf/"
+
+	// ReasonApplied is the event reason of applied configuration
+	ReasonApplied = "ConfigApplied"
+	// ReasonRejected is the event reason of rejected configuration
+	ReasonRejected = "ConfigRejected"
+
+	reportComponent      = "feconf"
+	maxEventMessageBytes = 1024
+	inClusterNamespace   = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// ReporterConfig configures a Reporter. The zero value records Events on the
+// source resource.
+type ReporterConfig struct {
+	// Mode selects Events or annotations, defaulting to ReportEvent
+	Mode ReportMode
+	// OnPod records outcomes on the pod instead of the source resource.
+	// Annotating a shared source changes its resource version on every
+	// report, which the pod avoids.
+	OnPod bool
+	// PodName is the reporting pod, defaulting to $POD_NAME and the hostname
+	PodName string
+	// PodNamespace is the namespace of the pod, defaulting to $POD_NAMESPACE,
+	// the namespace of the service account and the namespace of the source
+	PodNamespace string
+}
+
+// Reporter records whether a pod applied or rejected the configuration read
+// by a K8SReader, so that kubectl describe shows which revision a pod runs and
+// why an update was rejected.
+type Reporter struct {
+	reader    *K8SReader
+	clientset kubernetes.Interface
+	config    ReporterConfig
+}
+
+// reportStatus is the value of a status annotation
+type reportStatus struct {
+	Pod      string    `json:"pod"`
+	Source   string    `json:"source"`
+	Revision string    `json:"revision,omitempty"`
+	Applied  bool      `json:"applied"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// NewReporter creates a reporter for a ConfigMap or Secret reader. Readers of
+// a label selector can only report on the pod.
+func NewReporter(r *K8SReader, config ReporterConfig) (*Reporter, error) {
+	if r.custom() {
+		return nil, fmt.Errorf("reporting is not supported for custom resources")
+	}
+	if r.clientset == nil {
+		return nil, fmt.Errorf("k8s client not initialized")
+	}
+	if r.selector != nil && !config.OnPod {
+		return nil, fmt.Errorf("readers of a label selector can only report on the pod")
+	}
+
+	switch config.Mode {
+	case "":
+		config.Mode = ReportEvent
+	case ReportEvent, ReportAnnotation:
+	default:
+		return nil, fmt.Errorf("invalid report mode %q, expected: %s or %s", config.Mode, ReportEvent, ReportAnnotation)
+	}
+
+	if config.PodName == "" {
+		config.PodName = os.Getenv("POD_NAME")
+	}
+	if config.PodName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod name: %w", err)
+		}
+		config.PodName = hostname
+	}
+	if config.PodNamespace == "" {
+		config.PodNamespace = podNamespace(r.namespace)
+	}
+
+	rp := &Reporter{reader: r, clientset: r.clientset, config: config}
+	if config.Mode == ReportAnnotation {
+		if errs := validation.IsQualifiedName(rp.annotationKey()); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid status annotation %s: %s", rp.annotationKey(), strings.Join(errs, "; "))
+		}
+	}
+	return rp, nil
+}
+
+// Report records that the pod applied the revision of a ConfEvent, or
+// rejected it with err, e.g. a decode or validation error. Without a
+// revision, the current resource version of the source is recorded.
+func (rp *Reporter) Report(ctx context.Context, revision string, rejectErr error) error {
+	target, err := rp.target(ctx)
+	if err != nil {
+		return err
+	}
+	if revision == "" && !rp.config.OnPod {
+		revision = target.ResourceVersion
+	}
+
+	if rp.config.Mode == ReportAnnotation {
+		return rp.annotate(ctx, target, revision, rejectErr)
+	}
+	return rp.recordEvent(ctx, target, revision, rejectErr)
+}
+
+// target returns a reference to the object outcomes are recorded on
+func (rp *Reporter) target(ctx context.Context) (*corev1.ObjectReference, error) {
+	var (
+		object metav1.Object
+		kind   string
+		err    error
+	)
+	switch {
+	case rp.config.OnPod:
+		kind = "Pod"
+		object, err = rp.clientset.CoreV1().Pods(rp.config.PodNamespace).Get(ctx, rp.config.PodName, metav1.GetOptions{})
+	case rp.reader.resourceType == ResourceTypeConfigMap:
+		kind = "ConfigMap"
+		object, err = rp.clientset.CoreV1().ConfigMaps(rp.reader.namespace).Get(ctx, rp.reader.name, metav1.GetOptions{})
+	default:
+		kind = "Secret"
+		object, err = rp.clientset.CoreV1().Secrets(rp.reader.namespace).Get(ctx, rp.reader.name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s to report on: %w", strings.ToLower(kind), err)
+	}
+
+	return &corev1.ObjectReference{
+		Kind:            kind,
+		APIVersion:      "v1",
+		Namespace:       object.GetNamespace(),
+		Name:            object.GetName(),
+		UID:             object.GetUID(),
+		ResourceVersion: object.GetResourceVersion(),
+	}, nil
+}
+
+// source describes the configuration source, e.g. configmap default/app
+func (rp *Reporter) source() string {
+	if rp.reader.selector != nil {
+		return fmt.Sprintf("%s in %s matching %s", rp.reader.resourceType, rp.reader.namespace, rp.reader.selector)
+	}
+	return fmt.Sprintf("%s %s/%s", rp.reader.resourceType, rp.reader.namespace, rp.reader.name)
+}
+
+// annotationKey names the status annotation after the pod on a source, and
+// after the source on a pod, e.g. configmap.app or configmaps for a selector
+func (rp *Reporter) annotationKey() string {
+	if rp.config.OnPod && rp.reader.selector != nil {
+		return StatusAnnotationPrefix + rp.reader.resourceType + "s"
+	}
+	if rp.config.OnPod {
+		return StatusAnnotationPrefix + rp.reader.resourceType + "." + rp.reader.name
+	}
+	return StatusAnnotationPrefix + rp.config.PodName
+}
+
+func (rp *Reporter) annotate(ctx context.Context, target *corev1.ObjectReference, revision string, err error) error {
+	status := reportStatus{
+		Pod:      rp.config.PodNamespace + "/" + rp.config.PodName,
+		Source:   rp.source(),
+		Revision: revision,
+		Applied:  err == nil,
+		Time:     time.Now().UTC().Truncate(time.Second),
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	value, _ := json.Marshal(status)
+	patch, _ := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{rp.annotationKey(): string(value)},
+		},
+	})
+
+	var patchErr error
+	switch target.Kind {
+	case "Pod":
+		_, patchErr = rp.clientset.CoreV1().Pods(target.Namespace).Patch(ctx, target.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case "ConfigMap":
+		_, patchErr = rp.clientset.CoreV1().ConfigMaps(target.Namespace).Patch(ctx, target.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	default:
+		_, patchErr = rp.clientset.CoreV1().Secrets(target.Namespace).Patch(ctx, target.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	if patchErr != nil {
+		return fmt.Errorf("failed to annotate %s %s/%s: %w", strings.ToLower(target.Kind), target.Namespace, target.Name, patchErr)
+	}
+	return nil
+}
+
+func (rp *Reporter) recordEvent(ctx context.Context, target *corev1.ObjectReference, revision string, err error) error {
+	at := ""
+	if revision != "" {
+		at = " at revision " + revision
+	}
+
+	eventType, reason := corev1.EventTypeNormal, ReasonApplied
+	message := fmt.Sprintf("pod %s/%s applied %s%s", rp.config.PodNamespace, rp.config.PodName, rp.source(), at)
+	if err != nil {
+		eventType, reason = corev1.EventTypeWarning, ReasonRejected
+		message = fmt.Sprintf("pod %s/%s rejected %s%s: %v", rp.config.PodNamespace, rp.config.PodName, rp.source(), at, err)
+	}
+	message = truncateMessage(message, maxEventMessageBytes)
+
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", target.Name, now.UnixNano()),
+			Namespace: target.Namespace,
+		},
+		InvolvedObject:      *target,
+		Reason:              reason,
+		Message:             message,
+		Type:                eventType,
+		Source:              corev1.EventSource{Component: reportComponent},
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		ReportingController: reportComponent,
+		ReportingInstance:   rp.config.PodName,
+	}
+	if _, err := rp.clientset.CoreV1().Events(target.Namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to record event on %s %s/%s: %w", strings.ToLower(target.Kind), target.Namespace, target.Name, err)
+	}
+	return nil
+}
+
+// truncateMessage cuts message to at most limit bytes on a rune boundary
+func truncateMessage(message string, limit int) string {
+	if len(message) <= limit {
+		return message
+	}
+	n := limit
+	for n > 0 && !utf8.RuneStart(message[n]) {
+		n--
+	}
+	return message[:n]
+}
+
+// podNamespace returns the namespace of the pod from the downward API or the
+// service account, falling back to the namespace of the source
+func podNamespace(fallback string) string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	if data, err := os.ReadFile(inClusterNamespace); err == nil {
+		if namespace := strings.TrimSpace(string(data)); namespace != "" {
+			return namespace
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,191 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newReporterReader() (*K8SReader, *fake.Clientset) {
+	clientset := fake.NewClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default", UID: "cm-uid", ResourceVersion: "4"},
+			Data:       map[string]string{"config.yaml": "name: app"},
+		},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "apps", UID: "pod-uid"}},
+	)
+	return &K8SReader{
+		uri:          "k8s://configmap/default/app-config/config.yaml",
+		resourceType: ResourceTypeConfigMap,
+		namespace:    "default",
+		name:         "app-config",
+		key:          "config.yaml",
+		clientset:    clientset,
+	}, clientset
+}
+
+func TestReporterEvents(t *testing.T) {
+	r, clientset := newReporterReader()
+	reporter, err := NewReporter(r, ReporterConfig{PodName: "app-1", PodNamespace: "apps"})
+	if err != nil {
+		t.Fatalf("NewReporter() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if err := reporter.Report(ctx, "5", nil); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if err := reporter.Report(ctx, "", errors.New("invalid port")); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	events, err := clientset.CoreV1().Events("default").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events.Items) != 2 {
+		t.Fatalf("events = %d, want 2", len(events.Items))
+	}
+
+	byReason := make(map[string]corev1.Event)
+	for _, event := range events.Items {
+		byReason[event.Reason] = event
+		if event.InvolvedObject.Kind != "ConfigMap" || event.InvolvedObject.UID != "cm-uid" {
+			t.Errorf("event involved object = %+v, want configmap cm-uid", event.InvolvedObject)
+		}
+	}
+
+	applied := byReason[ReasonApplied]
+	if applied.Type != corev1.EventTypeNormal || !strings.Contains(applied.Message, "pod apps/app-1 applied configmap default/app-config at revision 5") {
+		t.Errorf("applied event = %s %q", applied.Type, applied.Message)
+	}
+	// Without a revision the current resource version is recorded
+	rejected := byReason[ReasonRejected]
+	if rejected.Type != corev1.EventTypeWarning || !strings.Contains(rejected.Message, "at revision 4: invalid port") {
+		t.Errorf("rejected event = %s %q", rejected.Type, rejected.Message)
+	}
+}
+
+func TestReporterEventMessageTruncation(t *testing.T) {
+	r, clientset := newReporterReader()
+	reporter, err := NewReporter(r, ReporterConfig{PodName: "app-1", PodNamespace: "apps"})
+	if err != nil {
+		t.Fatalf("NewReporter() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if err := reporter.Report(ctx, "5", errors.New(strings.Repeat("配置无效", 200))); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	events, err := clientset.CoreV1().Events("default").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events.Items) != 1 {
+		t.Fatalf("events = %d, want 1", len(events.Items))
+	}
+	message := events.Items[0].Message
+	if len(message) > maxEventMessageBytes || len(message) < maxEventMessageBytes-utf8.UTFMax {
+		t.Errorf("message length = %d, want at most %d", len(message), maxEventMessageBytes)
+	}
+	if !utf8.ValidString(message) {
+		t.Errorf("message is not valid UTF-8 after truncation: %q", message[len(message)-8:])
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		limit   int
+		want    string
+	}{
+		{"short", 10, "short"},
+		{"exact", 5, "exact"},
+		{"truncated", 5, "trunc"},
+		{"ab配置", 4, "ab"},
+		{"ab配置", 5, "ab配"},
+		{"配", 2, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncateMessage(tt.message, tt.limit); got != tt.want {
+			t.Errorf("truncateMessage(%q, %d) = %q, want %q", tt.message, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestReporterAnnotations(t *testing.T) {
+	r, clientset := newReporterReader()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		config ReporterConfig
+		get    func() (metav1.Object, error)
+		key    string
+	}{
+		{
+			name:   "source",
+			config: ReporterConfig{Mode: ReportAnnotation, PodName: "app-1", PodNamespace: "apps"},
+			get: func() (metav1.Object, error) {
+				return clientset.CoreV1().ConfigMaps("default").Get(ctx, "app-config", metav1.GetOptions{})
+			},
+			key: "status.feconf/app-1",
+		},
+		{
+			name:   "pod",
+			config: ReporterConfig{Mode: ReportAnnotation, OnPod: true, PodName: "app-1", PodNamespace: "apps"},
+			get: func() (metav1.Object, error) {
+				return clientset.CoreV1().Pods("apps").Get(ctx, "app-1", metav1.GetOptions{})
+			},
+			key: "status.feconf/configmap.app-config",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter, err := NewReporter(r, tt.config)
+			if err != nil {
+				t.Fatalf("NewReporter() error = %v", err)
+			}
+			if err := reporter.Report(ctx, "5", errors.New("invalid port")); err != nil {
+				t.Fatalf("Report() error = %v", err)
+			}
+
+			object, err := tt.get()
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			var status reportStatus
+			if err := json.Unmarshal([]byte(object.GetAnnotations()[tt.key]), &status); err != nil {
+				t.Fatalf("annotation %s = %q: %v", tt.key, object.GetAnnotations()[tt.key], err)
+			}
+			if status.Applied || status.Error != "invalid port" || status.Revision != "5" || status.Pod != "apps/app-1" {
+				t.Errorf("status = %+v", status)
+			}
+		})
+	}
+}
+
+func TestNewReporterValidation(t *testing.T) {
+	r, _ := newReporterReader()
+	if _, err := NewReporter(r, ReporterConfig{Mode: "log", PodName: "app-1"}); err == nil {
+		t.Error("NewReporter() expected error for invalid mode")
+	}
+
+	r.selector = labels.SelectorFromSet(labels.Set{"app": "foo"})
+	if _, err := NewReporter(r, ReporterConfig{PodName: "app-1"}); err == nil {
+		t.Error("NewReporter() expected error for a selector reporting on the source")
+	}
+	if _, err := NewReporter(r, ReporterConfig{OnPod: true, Mode: ReportAnnotation, PodName: "app-1"}); err != nil {
+		t.Errorf("NewReporter() error = %v", err)
+	}
+}
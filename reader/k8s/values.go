@@ -0,0 +1,255 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sower-proxy/feconf/decoder"
+	"github.com/sower-proxy/feconf/reader"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DefaultPriorityAnnotation is the annotation ordering the resources merged by
+// a label selector
+const DefaultPriorityAnnotation = "feconf/priority"
+
+// resource is the data of a configmap or secret
+type resource struct {
+	name            string
+	resourceVersion string
+	labels          map[string]string
+	annotations     map[string]string
+	data            map[string][]byte
+}
+
+func toResource(obj any) (resource, bool) {
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		data := make(map[string][]byte, len(obj.Data))
+		for key, value := range obj.Data {
+			data[key] = []byte(value)
+		}
+		return resource{
+			name:            obj.Name,
+			resourceVersion: obj.ResourceVersion,
+			labels:          obj.Labels,
+			annotations:     obj.Annotations,
+			data:            data,
+		}, true
+	case *corev1.Secret:
+		return resource{
+			name:            obj.Name,
+			resourceVersion: obj.ResourceVersion,
+			labels:          obj.Labels,
+			annotations:     obj.Annotations,
+			data:            obj.Data,
+		}, true
+	default:
+		return resource{}, false
+	}
+}
+
+// Structured implements reader.MapReader. Without a key, all keys of the
+// resource, or of the resources matching the selector, are read as values.
+func (k *K8SReader) Structured() bool {
+	return k.key == ""
+}
+
+// ReadMap implements reader.MapReader
+func (k *K8SReader) ReadMap(ctx context.Context) (map[string]any, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	// Check if clientset is nil (for testing)
+	if !k.hasClient() {
+		return nil, fmt.Errorf("k8s client not initialized")
+	}
+
+	values, _, err := k.readValues(ctx)
+	return values, err
+}
+
+// readValues reads the named resource, or lists the resources matching the
+// selector, and merges their values. It also returns the revision of the
+// values.
+func (k *K8SReader) readValues(ctx context.Context) (map[string]any, string, error) {
+	if k.custom() {
+		return k.readCustomValues(ctx)
+	}
+
+	var objects []any
+	if k.selector == nil {
+		var (
+			obj any
+			err error
+		)
+		switch k.resourceType {
+		case ResourceTypeConfigMap:
+			obj, err = k.clientset.CoreV1().ConfigMaps(k.namespace).Get(ctx, k.name, metav1.GetOptions{})
+		case ResourceTypeSecret:
+			obj, err = k.clientset.CoreV1().Secrets(k.namespace).Get(ctx, k.name, metav1.GetOptions{})
+		default:
+			return nil, "", fmt.Errorf("unsupported resource type: %s", k.resourceType)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get %s %s/%s: %w", k.resourceType, k.namespace, k.name, err)
+		}
+		objects = append(objects, obj)
+	} else {
+		options := metav1.ListOptions{LabelSelector: k.selector.String()}
+		switch k.resourceType {
+		case ResourceTypeConfigMap:
+			list, err := k.clientset.CoreV1().ConfigMaps(k.namespace).List(ctx, options)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to list configmaps in %s: %w", k.namespace, err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+		case ResourceTypeSecret:
+			list, err := k.clientset.CoreV1().Secrets(k.namespace).List(ctx, options)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to list secrets in %s: %w", k.namespace, err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+		default:
+			return nil, "", fmt.Errorf("unsupported resource type: %s", k.resourceType)
+		}
+	}
+
+	return k.mergeObjects(objects)
+}
+
+// objectValues returns the values of the watched resources among objects and
+// their revision
+func (k *K8SReader) objectValues(objects []any) (map[string]any, string, error) {
+	if k.custom() {
+		return k.customValues(objects)
+	}
+	return k.mergeObjects(objects)
+}
+
+// mergeObjects merges the values of the watched resources among objects in
+// ascending priority, so resources of higher priority override lower ones.
+// Resources of equal priority are merged by name. The revision is the resource
+// version of the named resource, or the name=resourceVersion list of the
+// merged resources in merge order.
+func (k *K8SReader) mergeObjects(objects []any) (map[string]any, string, error) {
+	type prioritized struct {
+		resource
+		priority int
+	}
+
+	var resources []prioritized
+	for _, obj := range objects {
+		res, ok := toResource(obj)
+		if !ok || !k.matches(res) {
+			continue
+		}
+		priority := 0
+		if value, ok := res.annotations[k.priorityAnnotation]; ok {
+			var err error
+			if priority, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, "", fmt.Errorf("invalid annotation %s of %s %s/%s: %w", k.priorityAnnotation, k.resourceType, k.namespace, res.name, err)
+			}
+		}
+		resources = append(resources, prioritized{resource: res, priority: priority})
+	}
+	if len(resources) == 0 {
+		if k.selector == nil {
+			return nil, "", fmt.Errorf("%s %s/%s not found", k.resourceType, k.namespace, k.name)
+		}
+		return nil, "", fmt.Errorf("no %s in namespace %s matches selector %s", k.resourceType, k.namespace, k.selector)
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].priority != resources[j].priority {
+			return resources[i].priority < resources[j].priority
+		}
+		return resources[i].name < resources[j].name
+	})
+
+	values := make(map[string]any)
+	versions := make([]string, 0, len(resources))
+	for _, res := range resources {
+		resourceValues, err := k.resourceValues(res.resource)
+		if err != nil {
+			return nil, "", err
+		}
+		values = reader.MergeValues(values, resourceValues)
+		versions = append(versions, res.name+"="+res.resourceVersion)
+	}
+
+	if k.selector == nil {
+		return values, resources[0].resourceVersion, nil
+	}
+	return values, strings.Join(versions, ","), nil
+}
+
+// matches reports whether a resource is the named one, or matches the
+// selector
+func (k *K8SReader) matches(res resource) bool {
+	if k.selector == nil {
+		return res.name == k.name
+	}
+	return k.selector.Matches(labels.Set(res.labels))
+}
+
+// resourceValues maps the keys of a resource to values. Keys with the
+// extension of a registered format are decoded and nested under the key
+// without its extension, e.g. database.yaml under database; other keys are
+// string values.
+func (k *K8SReader) resourceValues(res resource) (map[string]any, error) {
+	keys := make([]string, 0, len(res.data))
+	for key := range res.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]any, len(keys))
+	for _, key := range keys {
+		name := key
+		var value any = string(res.data[key])
+
+		ext := filepath.Ext(key)
+		if format, err := decoder.FormatFromExtension(ext); err == nil && len(ext) < len(key) {
+			dec, err := decoder.GetDecoder(format)
+			if err != nil {
+				return nil, fmt.Errorf("get decoder for key %s of %s %s/%s: %w", key, k.resourceType, k.namespace, res.name, err)
+			}
+			var document map[string]any
+			if err := dec.Unmarshal(res.data[key], &document); err != nil {
+				return nil, fmt.Errorf("decode key %s of %s %s/%s: %w", key, k.resourceType, k.namespace, res.name, err)
+			}
+			name, value = strings.TrimSuffix(key, ext), document
+		}
+
+		if _, exists := values[name]; exists {
+			return nil, fmt.Errorf("key %s of %s %s/%s conflicts with another key named %s", key, k.resourceType, k.namespace, res.name, name)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// encodeValues encodes values read as a structured map to JSON
+func encodeValues(values map[string]any) ([]byte, reader.ContentMeta, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, reader.ContentMeta{}, fmt.Errorf("failed to encode values: %w", err)
+	}
+	return data, reader.ContentMeta{MIMEType: "application/json", Extension: ".json"}, nil
+}
@@ -0,0 +1,163 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/sower-proxy/feconf/decoder/yaml"
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestK8SReaderReadMap(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+			Data: map[string]string{
+				"database.yaml": "host: localhost\nport: 5432\n",
+				"LOG_LEVEL":     "debug",
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+			Data: map[string][]byte{
+				"password":    []byte("secret"),
+				"config.yaml": []byte("name: a\n"),
+				"config.yml":  []byte("name: b\n"),
+			},
+		},
+	)
+	r := &K8SReader{
+		uri:          "k8s://configmap/default/app-config",
+		resourceType: ResourceTypeConfigMap,
+		namespace:    "default",
+		name:         "app-config",
+		clientset:    clientset,
+	}
+	if !r.Structured() {
+		t.Fatal("Structured() = false, want true")
+	}
+
+	ctx := context.Background()
+	values, err := r.ReadMap(ctx)
+	if err != nil {
+		t.Fatalf("ReadMap() error = %v", err)
+	}
+	want := map[string]any{
+		"database":  map[string]any{"host": "localhost", "port": 5432},
+		"LOG_LEVEL": "debug",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("ReadMap() = %v, want %v", values, want)
+	}
+
+	_, meta, err := r.ReadMeta(ctx)
+	if err != nil {
+		t.Fatalf("ReadMeta() error = %v", err)
+	}
+	if meta.MIMEType != "application/json" {
+		t.Errorf("ReadMeta() MIME type = %s, want application/json", meta.MIMEType)
+	}
+
+	// Keys decoded to the same name conflict
+	secret := &K8SReader{
+		resourceType: ResourceTypeSecret,
+		namespace:    "default",
+		name:         "app-secret",
+		clientset:    clientset,
+	}
+	if _, err := secret.ReadMap(ctx); err == nil {
+		t.Error("ReadMap() expected error for conflicting keys")
+	}
+}
+
+func TestK8SReaderSelector(t *testing.T) {
+	selected := map[string]string{"app": "foo", "tier": "config"}
+	configMap := func(name, priority, data string, labels map[string]string) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Data:       map[string]string{"config.yaml": data},
+		}
+		if priority != "" {
+			cm.Annotations = map[string]string{DefaultPriorityAnnotation: priority}
+		}
+		return cm
+	}
+	clientset := fake.NewClientset(
+		configMap("team", "10", "log: info\ndb:\n  host: team-db\n", selected),
+		configMap("base", "", "log: warn\ndb:\n  host: base-db\n  port: 5432\n", selected),
+		configMap("other", "100", "log: error\n", map[string]string{"app": "bar"}),
+	)
+	r := &K8SReader{
+		uri:                "k8s://configmap/default?selector=app=foo,tier=config",
+		resourceType:       ResourceTypeConfigMap,
+		namespace:          "default",
+		selector:           labels.SelectorFromSet(selected),
+		priorityAnnotation: DefaultPriorityAnnotation,
+		clientset:          clientset,
+	}
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	want := map[string]any{"config": map[string]any{
+		"log": "info",
+		"db":  map[string]any{"host": "team-db", "port": 5432},
+	}}
+	values, err := r.ReadMap(ctx)
+	if err != nil {
+		t.Fatalf("ReadMap() error = %v", err)
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("ReadMap() = %v, want %v", values, want)
+	}
+
+	events, err := r.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	readertest.ExpectValues(t, events, want)
+
+	configMaps := clientset.CoreV1().ConfigMaps("default")
+	// Overridden and unselected changes leave the values as they are
+	if _, err := configMaps.Update(ctx, configMap("base", "", "log: debug\ndb:\n  host: base-db\n  port: 5432\n", selected), metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := configMaps.Update(ctx, configMap("other", "100", "log: debug\n", map[string]string{"app": "bar"}), metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := configMaps.Update(ctx, configMap("base", "20", "log: debug\n", selected), metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	readertest.ExpectValues(t, events, map[string]any{"config": map[string]any{
+		"log": "debug",
+		"db":  map[string]any{"host": "team-db"},
+	}})
+
+	if err := configMaps.Delete(ctx, "base", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	readertest.ExpectValues(t, events, map[string]any{"config": map[string]any{
+		"log": "info",
+		"db":  map[string]any{"host": "team-db"},
+	}})
+
+	// No configmap matches once the last one is deleted
+	if err := configMaps.Delete(ctx, "team", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	select {
+	case event := <-events:
+		if event.Error == nil {
+			t.Errorf("event after delete = %v, want error", event.Values)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for delete event")
+	}
+}
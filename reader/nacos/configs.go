@@ -0,0 +1,183 @@
+package nacos
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/sower-proxy/feconf/decoder"
+	"github.com/sower-proxy/feconf/reader"
+)
+
+// ConfigKey identifies a Nacos config within the reader namespace.
+type ConfigKey struct {
+	Group  string
+	DataID string
+}
+
+func (k ConfigKey) String() string {
+	return k.Group + "/" + k.DataID
+}
+
+// configState is the last fetched content of a config.
+type configState struct {
+	data []byte
+	meta reader.ContentMeta
+	md5  string
+}
+
+// keys returns the watched configs in merge order: shared configs in declared
+// order, then the config of the URI path.
+func (n *NacosReader) keys() []ConfigKey {
+	keys := make([]ConfigKey, 0, len(n.config.SharedConfigs)+1)
+	keys = append(keys, n.config.SharedConfigs...)
+	return append(keys, ConfigKey{Group: n.config.Group, DataID: n.config.DataID})
+}
+
+// store records fetched content of key and reports whether its MD5 differs
+// from the previously recorded content.
+func (n *NacosReader) store(key ConfigKey, state configState) bool {
+	n.stateMu.Lock()
+	defer n.stateMu.Unlock()
+
+	previous, ok := n.states[key]
+	n.states[key] = &state
+	return !ok || previous.md5 != state.md5
+}
+
+func (n *NacosReader) state(key ConfigKey) (configState, bool) {
+	n.stateMu.Lock()
+	defer n.stateMu.Unlock()
+
+	state, ok := n.states[key]
+	if !ok {
+		return configState{}, false
+	}
+	return *state, true
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (n *NacosReader) currentMD5(key ConfigKey) string {
+	state, _ := n.state(key)
+	return state.md5
+}
+
+// mergeStates decodes the recorded content of every config and merges them in
+// declared order, later configs overriding earlier ones.
+func (n *NacosReader) mergeStates() (map[string]any, error) {
+	values := make(map[string]any)
+	for _, key := range n.keys() {
+		state, ok := n.state(key)
+		if !ok {
+			return nil, fmt.Errorf("config %s not fetched from Nacos", key)
+		}
+		configValues, err := decodeConfig(key, state)
+		if err != nil {
+			return nil, err
+		}
+		values = reader.MergeValues(values, configValues)
+	}
+	return values, nil
+}
+
+// decodeConfig decodes a config using the format of its dataId extension,
+// falling back to the Nacos config type.
+func decodeConfig(key ConfigKey, state configState) (map[string]any, error) {
+	format, err := decoder.FormatFromExtension(filepath.Ext(key.DataID))
+	if err != nil && state.meta.Extension != "" {
+		format, err = decoder.FormatFromExtension(state.meta.Extension)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("determine format of Nacos config %s: %w", key, err)
+	}
+
+	dec, err := decoder.GetDecoder(format)
+	if err != nil {
+		return nil, fmt.Errorf("get decoder for Nacos config %s: %w", key, err)
+	}
+
+	var values map[string]any
+	if err := dec.Unmarshal(state.data, &values); err != nil {
+		return nil, fmt.Errorf("decode Nacos config %s: %w", key, err)
+	}
+	return values, nil
+}
+
+// mergedEvent builds an event carrying the merged values of all configs, with
+// their JSON encoding as data.
+func (n *NacosReader) mergedEvent() *reader.ReadEvent {
+	values, err := n.mergeStates()
+	if err != nil {
+		return reader.NewReadEvent(n.uri, nil, err)
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return reader.NewReadEvent(n.uri, nil, fmt.Errorf("encode merged Nacos configs: %w", err))
+	}
+	return reader.NewReadEvent(n.uri, data, nil).
+		WithMeta(reader.ContentMeta{MIMEType: "application/json", Extension: ".json"}).
+		WithValues(values)
+}
+
+// changedKeys parses a listener response, which lists the changed configs as
+// URL encoded dataId^2group[^2tenant]^1 entries. Unrecognized responses mark
+// every config as changed.
+func (n *NacosReader) changedKeys(body string) []ConfigKey {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil
+	}
+
+	keys := n.keys()
+	if decoded, err := url.QueryUnescape(body); err == nil {
+		body = decoded
+	}
+
+	var changed []ConfigKey
+	for _, line := range strings.Split(body, splitConfig) {
+		fields := strings.Split(line, splitConfigInner)
+		if len(fields) < 2 {
+			continue
+		}
+		key := ConfigKey{Group: fields[1], DataID: fields[0]}
+		for _, watched := range keys {
+			if watched == key {
+				changed = append(changed, key)
+				break
+			}
+		}
+	}
+	if len(changed) == 0 {
+		return keys
+	}
+	return changed
+}
+
+// parseSharedConfigs parses a comma-separated list of [group/]dataId entries.
+// Entries without a group use defaultGroup.
+func parseSharedConfigs(value, defaultGroup string) ([]ConfigKey, error) {
+	var keys []ConfigKey
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key := ConfigKey{Group: defaultGroup, DataID: entry}
+		if group, dataID, ok := strings.Cut(entry, "/"); ok {
+			key = ConfigKey{Group: strings.TrimSpace(group), DataID: strings.TrimSpace(dataID)}
+		}
+		if key.Group == "" || key.DataID == "" {
+			return nil, fmt.Errorf("invalid shared config %q, expected: [group/]dataId", entry)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,555 @@
+package nacos
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+	"github.com/sower-proxy/feconf/reader/nacos/internal/nacospb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// ProtocolGRPC talks to Nacos 2.x over its gRPC bi-stream with server push.
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTP uses the HTTP long-polling API of Nacos 1.x.
+	ProtocolHTTP = "http"
+
+	// DefaultGRPCPortOffset is the distance of the gRPC port from the HTTP port.
+	DefaultGRPCPortOffset = 1000
+
+	grpcRequestMethod  = "/Request/request"
+	grpcBiStreamMethod = "/BiRequestStream/requestBiStream"
+	grpcClientVersion  = "Nacos-Go-Client:feconf"
+
+	maxGRPCMessageSize = 16 << 20
+
+	// grpcSetupWait bounds the wait for the server to acknowledge connection
+	// setup. Servers without setup acks register the connection meanwhile.
+	grpcSetupWait = 100 * time.Millisecond
+
+	grpcResultSuccess     = 200
+	grpcErrorNotFound     = 300
+	grpcErrorUnregistered = 301
+	grpcErrorNoRight      = 403
+)
+
+// errGRPCUnavailable reports that no gRPC connection could be set up with any
+// of the servers.
+var errGRPCUnavailable = errors.New("no Nacos gRPC server available")
+
+var grpcBiStreamDesc = grpc.StreamDesc{
+	StreamName:    "requestBiStream",
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// grpcResponse holds the status fields shared by all Nacos responses.
+type grpcResponse struct {
+	ResultCode int    `json:"resultCode"`
+	ErrorCode  int    `json:"errorCode"`
+	Message    string `json:"message,omitempty"`
+	RequestID  string `json:"requestId,omitempty"`
+	Success    bool   `json:"success"`
+}
+
+// grpcError is a Nacos response with a non-success result code.
+type grpcError struct {
+	Type      string
+	ErrorCode int
+	Message   string
+}
+
+func (e *grpcError) Error() string {
+	return fmt.Sprintf("%s failed with error code %d: %s", e.Type, e.ErrorCode, e.Message)
+}
+
+type grpcConfigKey struct {
+	Group  string `json:"group"`
+	DataID string `json:"dataId"`
+	Tenant string `json:"tenant"`
+	MD5    string `json:"md5,omitempty"`
+}
+
+type grpcServerCheckRequest struct {
+	Module string `json:"module"`
+}
+
+type grpcConnectionSetupRequest struct {
+	ClientVersion string            `json:"clientVersion"`
+	Tenant        string            `json:"tenant"`
+	Labels        map[string]string `json:"labels"`
+	Module        string            `json:"module"`
+}
+
+type grpcConfigQueryRequest struct {
+	grpcConfigKey
+	Module string `json:"module"`
+}
+
+type grpcConfigBatchListenRequest struct {
+	Listen               bool            `json:"listen"`
+	ConfigListenContexts []grpcConfigKey `json:"configListenContexts"`
+	Module               string          `json:"module"`
+}
+
+// grpcConn is one gRPC connection to a Nacos server. Unary requests and the
+// bi-stream must share the HTTP/2 connection, which the server identifies by
+// its connection id.
+type grpcConn struct {
+	server  string
+	cc      *grpc.ClientConn
+	id      string
+	cancel  context.CancelFunc
+	stream  grpc.ClientStream
+	writeMu sync.Mutex
+
+	notify   chan struct{}
+	setupAck chan struct{}
+	ackOnce  sync.Once
+	done     chan struct{}
+	failOnce sync.Once
+	err      error
+}
+
+// grpcConnection returns the current gRPC connection, connecting to the next
+// reachable server when there is none.
+func (n *NacosReader) grpcConnection(ctx context.Context) (*grpcConn, error) {
+	n.grpcMu.Lock()
+	defer n.grpcMu.Unlock()
+
+	if n.grpcConn != nil {
+		select {
+		case <-n.grpcConn.done:
+			n.grpcConn.close()
+			n.grpcConn = nil
+		default:
+			return n.grpcConn, nil
+		}
+	}
+
+	servers, err := n.servers.candidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		var conn *grpcConn
+		conn, err = n.dialGRPC(ctx, server)
+		if err == nil {
+			n.servers.markUp(server)
+			n.grpcConn = conn
+			n.grpcConnected = true
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		n.servers.markDown(server)
+	}
+	return nil, fmt.Errorf("%w: %w", errGRPCUnavailable, err)
+}
+
+// useGRPC reports whether requests go over gRPC.
+func (n *NacosReader) useGRPC() bool {
+	n.grpcMu.Lock()
+	defer n.grpcMu.Unlock()
+
+	return n.config.Protocol == ProtocolGRPC && !n.httpFallback
+}
+
+// fallbackToHTTP switches the reader to HTTP long polling for good, unless
+// the fallback is disabled or a gRPC connection was ever set up, and reports
+// whether it did.
+func (n *NacosReader) fallbackToHTTP() bool {
+	n.grpcMu.Lock()
+	defer n.grpcMu.Unlock()
+
+	if !n.config.GRPCFallback || n.grpcConnected {
+		return false
+	}
+	n.httpFallback = true
+	return true
+}
+
+// resetGRPC drops conn so the next request reconnects.
+func (n *NacosReader) resetGRPC(conn *grpcConn) {
+	n.grpcMu.Lock()
+	defer n.grpcMu.Unlock()
+
+	if n.grpcConn == conn {
+		n.grpcConn = nil
+	}
+	conn.close()
+}
+
+func (n *NacosReader) closeGRPC() {
+	n.grpcMu.Lock()
+	defer n.grpcMu.Unlock()
+
+	if n.grpcConn != nil {
+		n.grpcConn.close()
+		n.grpcConn = nil
+	}
+}
+
+// dialGRPC checks the server, opens the bi-stream and sets the connection up.
+func (n *NacosReader) dialGRPC(ctx context.Context, server string) (*grpcConn, error) {
+	target, err := n.grpcTarget(server)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := insecure.NewCredentials()
+	if strings.HasPrefix(server, "https://") {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if n.tlsConfig != nil {
+			tlsConfig = n.tlsConfig.Clone()
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	cc, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxGRPCMessageSize)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create Nacos gRPC client %s: %w", target, err)
+	}
+
+	conn := &grpcConn{
+		server:   server,
+		cc:       cc,
+		notify:   make(chan struct{}, 1),
+		setupAck: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	body, err := conn.call(ctx, n.config.Timeout, "ServerCheckRequest", grpcServerCheckRequest{Module: "internal"}, nil)
+	if err != nil {
+		conn.close()
+		return nil, fmt.Errorf("check Nacos gRPC server %s: %w", target, err)
+	}
+	var check struct {
+		ConnectionID string `json:"connectionId"`
+	}
+	if err := json.Unmarshal(body, &check); err != nil {
+		conn.close()
+		return nil, fmt.Errorf("decode Nacos server check response: %w", err)
+	}
+	conn.id = check.ConnectionID
+
+	streamCtx, cancel := context.WithCancel(n.closeCtx)
+	conn.cancel = cancel
+	conn.stream, err = cc.NewStream(streamCtx, &grpcBiStreamDesc, grpcBiStreamMethod)
+	if err != nil {
+		conn.close()
+		return nil, fmt.Errorf("open Nacos bi-stream: %w", err)
+	}
+	go conn.serve()
+
+	setup := grpcConnectionSetupRequest{
+		ClientVersion: grpcClientVersion,
+		Tenant:        n.config.Namespace,
+		Labels:        map[string]string{"source": "sdk", "module": "config", "AppName": "feconf"},
+		Module:        "internal",
+	}
+	if err := conn.send("ConnectionSetupRequest", setup); err != nil {
+		conn.close()
+		return nil, fmt.Errorf("set up Nacos gRPC connection: %w", err)
+	}
+
+	timer := time.NewTimer(grpcSetupWait)
+	defer timer.Stop()
+	select {
+	case <-conn.setupAck:
+	case <-timer.C:
+	case <-conn.done:
+		conn.close()
+		return nil, fmt.Errorf("set up Nacos gRPC connection: %w", conn.err)
+	case <-ctx.Done():
+		conn.close()
+		return nil, ctx.Err()
+	}
+	return conn, nil
+}
+
+// grpcTarget derives the gRPC address from a server base URL by applying the
+// gRPC port offset.
+func (n *NacosReader) grpcTarget(server string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("invalid Nacos server %s: %w", server, err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return "", fmt.Errorf("invalid Nacos server port %s: %w", server, err)
+	}
+	grpcPort := port + n.config.GRPCPortOffset
+	if grpcPort <= 0 || grpcPort > 65535 {
+		return "", fmt.Errorf("invalid Nacos gRPC port %d", grpcPort)
+	}
+	return net.JoinHostPort(u.Hostname(), strconv.Itoa(grpcPort)), nil
+}
+
+// grpcCall sends a request over the current connection. A missing permission
+// invalidates the access token and an unregistered connection reconnects,
+// each retried once, as is a server that cannot be reached.
+func (n *NacosReader) grpcCall(ctx context.Context, requestType string, request any) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		conn, err := n.grpcConnection(ctx)
+		if err != nil {
+			return nil, err
+		}
+		token, err := n.ensureToken(ctx, conn.server)
+		if err != nil {
+			return nil, err
+		}
+		var group, tenant string
+		if query, ok := request.(grpcConfigQueryRequest); ok {
+			group, tenant = query.Group, query.Tenant
+		}
+		headers := n.signHeaders(group, tenant)
+		if token != "" {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers["accessToken"] = token
+		}
+
+		body, err := conn.call(ctx, n.config.Timeout, requestType, request, headers)
+		if err == nil || ctx.Err() != nil || attempt > 0 {
+			return body, err
+		}
+
+		var statusErr *grpcError
+		switch {
+		case errors.As(err, &statusErr) && statusErr.ErrorCode == grpcErrorNoRight && token != "":
+			n.invalidateToken(token)
+		case errors.As(err, &statusErr) && statusErr.ErrorCode == grpcErrorUnregistered:
+			n.resetGRPC(conn)
+		case statusErr == nil:
+			n.servers.markDown(conn.server)
+			n.resetGRPC(conn)
+		default:
+			return nil, err
+		}
+	}
+}
+
+func (n *NacosReader) grpcConfigKey(key ConfigKey) grpcConfigKey {
+	return grpcConfigKey{Group: key.Group, DataID: key.DataID, Tenant: n.config.Namespace}
+}
+
+// grpcQuery fetches a config with ConfigQueryRequest, returning its content,
+// config type and encrypted data key.
+func (n *NacosReader) grpcQuery(ctx context.Context, key ConfigKey) ([]byte, reader.ContentMeta, string, error) {
+	body, err := n.grpcCall(ctx, "ConfigQueryRequest", grpcConfigQueryRequest{
+		grpcConfigKey: n.grpcConfigKey(key),
+		Module:        "config",
+	})
+	var statusErr *grpcError
+	if errors.As(err, &statusErr) && statusErr.ErrorCode == grpcErrorNotFound {
+		return nil, reader.ContentMeta{}, "", fmt.Errorf("config data not exist")
+	}
+	if err != nil {
+		return nil, reader.ContentMeta{}, "", err
+	}
+
+	var resp struct {
+		Content          string `json:"content"`
+		ContentType      string `json:"contentType"`
+		EncryptedDataKey string `json:"encryptedDataKey"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, reader.ContentMeta{}, "", fmt.Errorf("decode Nacos config query response: %w", err)
+	}
+
+	var meta reader.ContentMeta
+	if contentType := strings.TrimSpace(resp.ContentType); contentType != "" {
+		meta.Extension = "." + strings.ToLower(contentType)
+	}
+	return []byte(resp.Content), meta, resp.EncryptedDataKey, nil
+}
+
+// grpcListen registers all configs with their MD5 and waits for a change
+// notification pushed over the bi-stream. Configs are re-registered every
+// listen timeout.
+func (n *NacosReader) grpcListen(ctx context.Context) ([]ConfigKey, error) {
+	timer := time.NewTimer(n.config.ListenTimeout)
+	defer timer.Stop()
+
+	for {
+		conn, err := n.grpcConnection(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		changed, err := n.grpcBatchListen(ctx)
+		if err != nil || len(changed) > 0 {
+			return changed, err
+		}
+
+		select {
+		case <-conn.notify:
+		case <-conn.done:
+			return nil, fmt.Errorf("gRPC stream to Nacos closed: %w", conn.err)
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (n *NacosReader) grpcBatchListen(ctx context.Context) ([]ConfigKey, error) {
+	keys := n.keys()
+	request := grpcConfigBatchListenRequest{Listen: true, Module: "config"}
+	for _, key := range keys {
+		listenKey := n.grpcConfigKey(key)
+		listenKey.MD5 = n.currentMD5(key)
+		request.ConfigListenContexts = append(request.ConfigListenContexts, listenKey)
+	}
+
+	body, err := n.grpcCall(ctx, "ConfigBatchListenRequest", request)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		ChangedConfigs []grpcConfigKey `json:"changedConfigs"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decode Nacos batch listen response: %w", err)
+	}
+
+	var changed []ConfigKey
+	for _, config := range resp.ChangedConfigs {
+		key := ConfigKey{Group: config.Group, DataID: config.DataID}
+		for _, watched := range keys {
+			if watched == key {
+				changed = append(changed, key)
+				break
+			}
+		}
+	}
+	return changed, nil
+}
+
+// call sends a unary request and returns the body of a successful response.
+func (c *grpcConn) call(ctx context.Context, timeout time.Duration, requestType string, request any, headers map[string]string) ([]byte, error) {
+	payload, err := newPayload(requestType, request, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	response := new(nacospb.Payload)
+	if err := c.cc.Invoke(ctx, grpcRequestMethod, payload, response); err != nil {
+		return nil, err
+	}
+
+	body := response.GetBody().GetValue()
+	var status grpcResponse
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", response.GetMetadata().GetType(), err)
+	}
+	if status.ResultCode != grpcResultSuccess {
+		return nil, &grpcError{Type: requestType, ErrorCode: status.ErrorCode, Message: status.Message}
+	}
+	return body, nil
+}
+
+// send writes a message to the bi-stream.
+func (c *grpcConn) send(messageType string, body any) error {
+	payload, err := newPayload(messageType, body, nil)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.stream.SendMsg(payload)
+}
+
+// serve reads server pushes from the bi-stream until it ends.
+func (c *grpcConn) serve() {
+	for {
+		payload := new(nacospb.Payload)
+		if err := c.stream.RecvMsg(payload); err != nil {
+			c.fail(err)
+			return
+		}
+		if err := c.handlePush(payload); err != nil {
+			c.fail(err)
+			return
+		}
+	}
+}
+
+// handlePush acknowledges a request pushed by the server and records change
+// notifications for the listener.
+func (c *grpcConn) handlePush(payload *nacospb.Payload) error {
+	var request struct {
+		RequestID string `json:"requestId"`
+	}
+	_ = json.Unmarshal(payload.GetBody().GetValue(), &request)
+
+	var resetErr error
+	payloadType := payload.GetMetadata().GetType()
+	switch payloadType {
+	case "ConfigChangeNotifyRequest":
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+	case "SetupAckRequest":
+		c.ackOnce.Do(func() { close(c.setupAck) })
+	case "ConnectResetRequest":
+		resetErr = errors.New("connection reset by server")
+	}
+
+	if responseType, ok := strings.CutSuffix(payloadType, "Request"); ok {
+		response := grpcResponse{ResultCode: grpcResultSuccess, RequestID: request.RequestID, Success: true}
+		if err := c.send(responseType+"Response", response); err != nil {
+			return err
+		}
+	}
+	return resetErr
+}
+
+func (c *grpcConn) fail(err error) {
+	c.failOnce.Do(func() {
+		c.err = err
+		close(c.done)
+	})
+}
+
+func (c *grpcConn) close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	_ = c.cc.Close()
+}
+
+// newPayload wraps the JSON encoding of a request in a Nacos Payload.
+func newPayload(requestType string, request any, headers map[string]string) (*nacospb.Payload, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", requestType, err)
+	}
+	return &nacospb.Payload{
+		Metadata: &nacospb.Metadata{Type: requestType, Headers: headers},
+		Body:     &anypb.Any{Value: body},
+	}, nil
+}
@@ -0,0 +1,398 @@
+package nacos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader/nacos/internal/nacospb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestGRPCPayloadWireFormat checks the field numbers of Payload against the
+// published Nacos proto: metadata = 2, body = 3, and type = 3 in Metadata.
+func TestGRPCPayloadWireFormat(t *testing.T) {
+	payload, err := newPayload("ServerCheckRequest", map[string]string{}, nil)
+	if err != nil {
+		t.Fatalf("newPayload() error = %v", err)
+	}
+	got, err := proto.Marshal(payload)
+	if err != nil {
+		t.Fatalf("proto.Marshal() error = %v", err)
+	}
+
+	want := []byte{0x12, 20, 0x1a, 18}
+	want = append(want, "ServerCheckRequest"...)
+	want = append(want, 0x1a, 4, 0x12, 2, '{', '}')
+	if !bytes.Equal(got, want) {
+		t.Errorf("payload = %x, want %x", got, want)
+	}
+}
+
+func TestNacosReaderGRPC(t *testing.T) {
+	stub := newGRPCStub(t, map[string]string{"app.yaml": "name: v1"})
+	defer stub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nacosReader, err := NewNacosReader(stub.uri("/DEFAULT_GROUP/app.yaml?listen_timeout=5s"))
+	if err != nil {
+		t.Fatalf("NewNacosReader() error = %v", err)
+	}
+	defer nacosReader.Close()
+
+	data, meta, err := nacosReader.ReadMeta(ctx)
+	if err != nil {
+		t.Fatalf("ReadMeta() error = %v", err)
+	}
+	if string(data) != "name: v1" {
+		t.Errorf("ReadMeta() data = %s", string(data))
+	}
+	if meta.Extension != ".yaml" {
+		t.Errorf("Extension = %s, want .yaml", meta.Extension)
+	}
+
+	events, err := nacosReader.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	stub.waitListening(t)
+
+	stub.publish("app.yaml", "name: v2")
+	select {
+	case event := <-events:
+		if !event.IsValid() {
+			t.Fatalf("event should be valid: %+v", event)
+		}
+		if string(event.Data) != "name: v2" {
+			t.Errorf("event data = %s", string(event.Data))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	if got := stub.ackCount(); got == 0 {
+		t.Error("change notification was not acknowledged")
+	}
+	if got := stub.connectionCount(); got != 1 {
+		t.Errorf("connections = %d, want 1", got)
+	}
+}
+
+func TestNacosReaderGRPCNotFound(t *testing.T) {
+	stub := newGRPCStub(t, map[string]string{})
+	defer stub.Close()
+
+	nacosReader, err := NewNacosReader(stub.uri("/DEFAULT_GROUP/app.yaml"))
+	if err != nil {
+		t.Fatalf("NewNacosReader() error = %v", err)
+	}
+	defer nacosReader.Close()
+
+	if _, err := nacosReader.Read(context.Background()); err == nil {
+		t.Fatal("Read() expected error for missing config")
+	}
+}
+
+func TestNacosReaderGRPCFallback(t *testing.T) {
+	stub := newNacosStub(t, "name: v1")
+	defer stub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nacosReader := newTestReader(t, stub.URL+"/DEFAULT_GROUP/app.yaml?protocol=grpc&listen_timeout=5s")
+	defer nacosReader.Close()
+
+	data, err := nacosReader.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if string(data) != "name: v1" {
+		t.Errorf("Read() = %s, want name: v1", string(data))
+	}
+	if nacosReader.useGRPC() {
+		t.Fatal("reader should fall back to HTTP without a gRPC server")
+	}
+
+	events, err := nacosReader.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	for stub.listenCount() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stub.publish("app.yaml", "name: v2")
+	select {
+	case event := <-events:
+		if !event.IsValid() || string(event.Data) != "name: v2" {
+			t.Fatalf("event = %+v, want name: v2", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for HTTP long-polling event")
+	}
+}
+
+func TestNacosReaderGRPCNoFallback(t *testing.T) {
+	stub := newNacosStub(t, "name: v1")
+	defer stub.Close()
+
+	nacosReader := newTestReader(t, stub.URL+"/DEFAULT_GROUP/app.yaml?protocol=grpc&grpc_fallback=false")
+	defer nacosReader.Close()
+
+	_, err := nacosReader.Read(context.Background())
+	if !errors.Is(err, errGRPCUnavailable) {
+		t.Fatalf("Read() error = %v, want %v", err, errGRPCUnavailable)
+	}
+	if !nacosReader.useGRPC() {
+		t.Error("reader with grpc_fallback=false should not fall back to HTTP")
+	}
+}
+
+func TestNacosReaderGRPCNoFallbackAfterConnect(t *testing.T) {
+	stub := newGRPCStub(t, map[string]string{"app.yaml": "name: v1"})
+
+	nacosReader, err := NewNacosReader(stub.uri("/DEFAULT_GROUP/app.yaml?timeout=1s"))
+	if err != nil {
+		t.Fatalf("NewNacosReader() error = %v", err)
+	}
+	defer nacosReader.Close()
+
+	if _, err := nacosReader.Read(context.Background()); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	nacosReader.closeGRPC()
+	stub.Close()
+	if _, err := nacosReader.Read(context.Background()); err == nil {
+		t.Fatal("Read() expected error once the gRPC server is gone")
+	}
+	if !nacosReader.useGRPC() {
+		t.Error("reader should keep gRPC after it connected once")
+	}
+}
+
+// grpcStub is an in-process Nacos 2.x gRPC server that registers the Request
+// and BiRequestStream services. It serves unary requests only on connections
+// that set up a bi-stream, and pushes change notifications to them.
+type grpcStub struct {
+	t         *testing.T
+	listener  net.Listener
+	server    *grpc.Server
+	mu        sync.Mutex
+	contents  map[string]string
+	streams   map[string]*grpcStubStream
+	listening chan struct{}
+	acks      int
+	setups    int
+}
+
+type grpcStubStream struct {
+	mu     sync.Mutex
+	stream grpc.ServerStream
+}
+
+func newGRPCStub(t *testing.T, contents map[string]string) *grpcStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	stub := &grpcStub{
+		t:         t,
+		listener:  listener,
+		server:    grpc.NewServer(),
+		contents:  contents,
+		streams:   make(map[string]*grpcStubStream),
+		listening: make(chan struct{}, 1),
+	}
+	stub.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "Request",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "request",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				request := new(nacospb.Payload)
+				if err := dec(request); err != nil {
+					return nil, err
+				}
+				return stub.handleRequest(ctx, request), nil
+			},
+		}},
+	}, stub)
+	stub.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "BiRequestStream",
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "requestBiStream",
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				stub.handleStream(stream)
+				return nil
+			},
+		}},
+	}, stub)
+	go func() { _ = stub.server.Serve(listener) }()
+	return stub
+}
+
+func (s *grpcStub) Close() {
+	s.server.Stop()
+}
+
+// uri returns a nacos URI whose HTTP port maps to the stub with the default
+// gRPC port offset.
+func (s *grpcStub) uri(pathAndQuery string) string {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	grpcPort, _ := strconv.Atoi(port)
+	separator := "?"
+	if strings.Contains(pathAndQuery, "?") {
+		separator = "&"
+	}
+	return "nacos://" + net.JoinHostPort(host, strconv.Itoa(grpcPort-DefaultGRPCPortOffset)) + pathAndQuery + separator + "protocol=grpc"
+}
+
+func (s *grpcStub) handleRequest(ctx context.Context, payload *nacospb.Payload) *nacospb.Payload {
+	remote, _ := peer.FromContext(ctx)
+	body := payload.GetBody().GetValue()
+
+	var request grpcConfigBatchListenRequest
+	var query grpcConfigQueryRequest
+	payloadType := payload.GetMetadata().GetType()
+	switch payloadType {
+	case "ConfigQueryRequest":
+		_ = json.Unmarshal(body, &query)
+	case "ConfigBatchListenRequest":
+		_ = json.Unmarshal(body, &request)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, registered := s.streams[remote.Addr.String()]
+	response := map[string]any{"resultCode": grpcResultSuccess, "success": true}
+	responseType := "ErrorResponse"
+	switch {
+	case payloadType == "ServerCheckRequest":
+		responseType = "ServerCheckResponse"
+		response["connectionId"] = remote.Addr.String()
+	case !registered:
+		response = map[string]any{"resultCode": 500, "errorCode": grpcErrorUnregistered, "message": "Connection is unregistered."}
+	case payloadType == "ConfigQueryRequest":
+		responseType = "ConfigQueryResponse"
+		content, ok := s.contents[query.DataID]
+		if !ok {
+			response = map[string]any{"resultCode": 500, "errorCode": grpcErrorNotFound, "message": "config data not exist"}
+			break
+		}
+		response["content"] = content
+		response["contentType"] = "yaml"
+		response["md5"] = md5Hex([]byte(content))
+	case payloadType == "ConfigBatchListenRequest":
+		responseType = "ConfigChangeBatchListenResponse"
+		var changed []grpcConfigKey
+		for _, key := range request.ConfigListenContexts {
+			if key.MD5 != md5Hex([]byte(s.contents[key.DataID])) {
+				changed = append(changed, grpcConfigKey{Group: key.Group, DataID: key.DataID, Tenant: key.Tenant})
+			}
+		}
+		response["changedConfigs"] = changed
+		select {
+		case s.listening <- struct{}{}:
+		default:
+		}
+	default:
+		s.t.Errorf("unexpected request type: %s", payloadType)
+	}
+
+	return stubPayload(responseType, response)
+}
+
+func (s *grpcStub) handleStream(serverStream grpc.ServerStream) {
+	remote, _ := peer.FromContext(serverStream.Context())
+	setup := new(nacospb.Payload)
+	if err := serverStream.RecvMsg(setup); err != nil || setup.GetMetadata().GetType() != "ConnectionSetupRequest" {
+		s.t.Errorf("first stream message = %q, %v", setup.GetMetadata().GetType(), err)
+		return
+	}
+
+	stream := &grpcStubStream{stream: serverStream}
+	s.mu.Lock()
+	s.streams[remote.Addr.String()] = stream
+	s.setups++
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, remote.Addr.String())
+		s.mu.Unlock()
+	}()
+	stream.push("SetupAckRequest", map[string]any{"requestId": "setup"})
+
+	for {
+		payload := new(nacospb.Payload)
+		if err := serverStream.RecvMsg(payload); err != nil {
+			return
+		}
+		if payload.GetMetadata().GetType() == "ConfigChangeNotifyResponse" {
+			s.mu.Lock()
+			s.acks++
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *grpcStub) publish(dataID, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.contents[dataID] = content
+	for _, stream := range s.streams {
+		stream.push("ConfigChangeNotifyRequest", map[string]any{
+			"requestId": "1",
+			"dataId":    dataID,
+			"group":     "DEFAULT_GROUP",
+			"module":    "config",
+		})
+	}
+}
+
+func (s *grpcStub) waitListening(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.listening:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for batch listen")
+	}
+}
+
+func (s *grpcStub) ackCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acks
+}
+
+func (s *grpcStub) connectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setups
+}
+
+func (s *grpcStubStream) push(messageType string, body any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.stream.SendMsg(stubPayload(messageType, body))
+}
+
+func stubPayload(messageType string, body any) *nacospb.Payload {
+	payload, _ := newPayload(messageType, body, nil)
+	return payload
+}
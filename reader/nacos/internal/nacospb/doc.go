@@ -0,0 +1,4 @@
+// Package nacospb holds the Payload messages of the Nacos 2.x gRPC API.
+package nacospb
+
+//go:generate protoc -I ../../../.. --go_out=../../../.. --go_opt=paths=source_relative reader/nacos/internal/nacospb/nacos_grpc_service.proto
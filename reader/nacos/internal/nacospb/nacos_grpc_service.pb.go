@@ -0,0 +1,205 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: reader/nacos/internal/nacospb/nacos_grpc_service.proto
+
+package nacospb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Metadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	ClientIp      string                 `protobuf:"bytes,8,opt,name=clientIp,proto3" json:"clientIp,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,7,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metadata) Reset() {
+	*x = Metadata{}
+	mi := &file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metadata) ProtoMessage() {}
+
+func (x *Metadata) ProtoReflect() protoreflect.Message {
+	mi := &file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metadata.ProtoReflect.Descriptor instead.
+func (*Metadata) Descriptor() ([]byte, []int) {
+	return file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Metadata) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Metadata) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *Metadata) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+type Payload struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      *Metadata              `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Body          *anypb.Any             `protobuf:"bytes,3,opt,name=body,proto3" json:"body,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Payload) Reset() {
+	*x = Payload{}
+	mi := &file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Payload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Payload) ProtoMessage() {}
+
+func (x *Payload) ProtoReflect() protoreflect.Message {
+	mi := &file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Payload.ProtoReflect.Descriptor instead.
+func (*Payload) Descriptor() ([]byte, []int) {
+	return file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Payload) GetMetadata() *Metadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Payload) GetBody() *anypb.Any {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+var File_reader_nacos_internal_nacospb_nacos_grpc_service_proto protoreflect.FileDescriptor
+
+const file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDesc = "" +
+	"\n" +
+	"6reader/nacos/internal/nacospb/nacos_grpc_service.proto\x12\ffeconf.nacos\x1a\x19google/protobuf/any.proto\"\xb5\x01\n" +
+	"\bMetadata\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x1a\n" +
+	"\bclientIp\x18\b \x01(\tR\bclientIp\x12=\n" +
+	"\aheaders\x18\a \x03(\v2#.feconf.nacos.Metadata.HeadersEntryR\aheaders\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"g\n" +
+	"\aPayload\x122\n" +
+	"\bmetadata\x18\x02 \x01(\v2\x16.feconf.nacos.MetadataR\bmetadata\x12(\n" +
+	"\x04body\x18\x03 \x01(\v2\x14.google.protobuf.AnyR\x04bodyB=Z;github.com/sower-proxy/feconf/reader/nacos/internal/nacospbb\x06proto3"
+
+var (
+	file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDescOnce sync.Once
+	file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDescData []byte
+)
+
+func file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDescGZIP() []byte {
+	file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDescOnce.Do(func() {
+		file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDesc), len(file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDesc)))
+	})
+	return file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDescData
+}
+
+var file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_goTypes = []any{
+	(*Metadata)(nil),  // 0: feconf.nacos.Metadata
+	(*Payload)(nil),   // 1: feconf.nacos.Payload
+	nil,               // 2: feconf.nacos.Metadata.HeadersEntry
+	(*anypb.Any)(nil), // 3: google.protobuf.Any
+}
+var file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_depIdxs = []int32{
+	2, // 0: feconf.nacos.Metadata.headers:type_name -> feconf.nacos.Metadata.HeadersEntry
+	0, // 1: feconf.nacos.Payload.metadata:type_name -> feconf.nacos.Metadata
+	3, // 2: feconf.nacos.Payload.body:type_name -> google.protobuf.Any
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_init() }
+func file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_init() {
+	if File_reader_nacos_internal_nacospb_nacos_grpc_service_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDesc), len(file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_goTypes,
+		DependencyIndexes: file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_depIdxs,
+		MessageInfos:      file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_msgTypes,
+	}.Build()
+	File_reader_nacos_internal_nacospb_nacos_grpc_service_proto = out.File
+	file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_goTypes = nil
+	file_reader_nacos_internal_nacospb_nacos_grpc_service_proto_depIdxs = nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,6 +32,7 @@ const (
 	configListenPath          = "/v1/cs/configs/listener"
 	authLoginPath             = "/v1/auth/users/login"
 	listeningConfigsParamName = "Listening-Configs"
+	configTypeHeader          = "Config-Type"
 )
 
 func init() {
@@ -41,33 +43,58 @@ func init() {
 
 // NacosConfig holds Nacos reader configuration.
 type NacosConfig struct {
-	BaseURL       string
-	Group         string
-	DataID        string
-	Namespace     string
-	Username      string
-	Password      string
-	Timeout       time.Duration
-	ListenTimeout time.Duration
-	RetryDelay    time.Duration
+	Protocol        string
+	GRPCFallback    bool
+	GRPCPortOffset  int
+	ServerURLs      []string
+	Endpoint        string
+	EndpointRefresh time.Duration
+	ServerScheme    string
+	ContextPath     string
+	Group           string
+	DataID          string
+	SharedConfigs   []ConfigKey
+	Namespace       string
+	Username        string
+	Password        string
+	AccessKey       string
+	SecretKey       string
+	Decryptors      map[string]Decryptor
+	Timeout         time.Duration
+	ListenTimeout   time.Duration
+	RetryDelay      time.Duration
 }
 
 // NacosReader implements ConfReader for Nacos configuration.
 type NacosReader struct {
-	uri         string
-	config      *NacosConfig
-	client      *http.Client
-	closeCtx    context.Context
-	closeCancel context.CancelFunc
-	accessToken string
-	tokenMu     sync.Mutex
-	mu          sync.RWMutex
-	closed      bool
+	uri            string
+	config         *NacosConfig
+	client         *http.Client
+	tlsConfig      *tls.Config
+	servers        *serverList
+	grpcConn       *grpcConn
+	grpcConnected  bool
+	httpFallback   bool
+	grpcMu         sync.Mutex
+	closeCtx       context.Context
+	closeCancel    context.CancelFunc
+	accessToken    string
+	tokenRefreshAt time.Time
+	tokenMu        sync.Mutex
+	states         map[ConfigKey]*configState
+	stateMu        sync.Mutex
+	mu             sync.RWMutex
+	closed         bool
 }
 
 // NewNacosReader creates a new Nacos reader.
-// URI format: nacos://host:port/group/dataId?namespace=public&username=nacos&password=nacos
-func NewNacosReader(uri string) (*NacosReader, error) {
+// URI format: nacos://host:port[,host:port...]/group/dataId?namespace=public&username=nacos&password=nacos
+// or nacos:///group/dataId?endpoint=address-server:8080 to discover the servers.
+// shared_configs=[group/]dataId,... lists configs merged before the path config.
+// protocol=grpc selects the Nacos 2.x gRPC API instead of HTTP long polling,
+// which it falls back to when no gRPC connection can be set up unless
+// grpc_fallback=false.
+func NewNacosReader(uri string, opts ...Option) (*NacosReader, error) {
 	u, err := reader.ParseURI(uri)
 	if err != nil {
 		return nil, err
@@ -81,25 +108,33 @@ func NewNacosReader(uri string) (*NacosReader, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Nacos URI: %w", err)
 	}
+	for _, opt := range opts {
+		opt(config)
+	}
 
-	transport := &http.Transport{}
+	var tlsConfig *tls.Config
 	if u.Query().Get("tls_insecure") == "true" {
-		transport.TLSClientConfig = &tls.Config{
+		tlsConfig = &tls.Config{
 			MinVersion:         tls.VersionTLS12,
 			InsecureSkipVerify: true,
 		}
 	}
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
 
+	client := &http.Client{
+		Timeout:   config.Timeout,
+		Transport: transport,
+	}
 	closeCtx, closeCancel := context.WithCancel(context.Background())
 	n := &NacosReader{
 		uri:         uri,
 		config:      config,
+		client:      client,
+		tlsConfig:   tlsConfig,
+		servers:     newServerList(config, client),
+		states:      make(map[ConfigKey]*configState),
 		closeCtx:    closeCtx,
 		closeCancel: closeCancel,
-		client: &http.Client{
-			Timeout:   config.Timeout,
-			Transport: transport,
-		},
 	}
 
 	return n, nil
@@ -107,6 +142,37 @@ func NewNacosReader(uri string) (*NacosReader, error) {
 
 // Read reads configuration data from Nacos.
 func (n *NacosReader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := n.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads configuration data from Nacos together with its config type.
+// With shared configs, the data is the JSON encoding of the merged configs.
+func (n *NacosReader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
+	}
+
+	ctx, cancel := reader.WithClose(ctx, n.closeCtx)
+	defer cancel()
+	if _, err := n.fetchChanged(ctx, n.keys()); err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
+
+	event := n.currentEvent()
+	return event.Data, event.Meta, event.Error
+}
+
+// Structured reports whether shared configs are merged into structured values.
+func (n *NacosReader) Structured() bool {
+	return len(n.config.SharedConfigs) > 0
+}
+
+// ReadMap reads all configs and merges them in declared order.
+func (n *NacosReader) ReadMap(ctx context.Context) (map[string]any, error) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
@@ -114,17 +180,40 @@ func (n *NacosReader) Read(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("reader is closed")
 	}
 
-	ctx, cancel := n.withClose(ctx)
+	ctx, cancel := reader.WithClose(ctx, n.closeCtx)
 	defer cancel()
-	data, err := n.fetch(ctx)
-	if err != nil {
+	if _, err := n.fetchChanged(ctx, n.keys()); err != nil {
 		return nil, err
 	}
-	if strings.TrimSpace(string(data)) == "" {
-		return nil, fmt.Errorf("empty Nacos config %s/%s", n.config.Group, n.config.DataID)
+	return n.mergeStates()
+}
+
+// fetchChanged fetches and records keys, reporting whether any of them changed.
+func (n *NacosReader) fetchChanged(ctx context.Context, keys []ConfigKey) (bool, error) {
+	changed := false
+	for _, key := range keys {
+		state, err := n.fetch(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		if strings.TrimSpace(string(state.data)) == "" {
+			return false, fmt.Errorf("empty Nacos config %s", key)
+		}
+		if n.store(key, state) {
+			changed = true
+		}
 	}
+	return changed, nil
+}
 
-	return data, nil
+// currentEvent builds an event from the recorded content: the path config
+// itself, or the merged values when shared configs are configured.
+func (n *NacosReader) currentEvent() *reader.ReadEvent {
+	if n.Structured() {
+		return n.mergedEvent()
+	}
+	state, _ := n.state(ConfigKey{Group: n.config.Group, DataID: n.config.DataID})
+	return reader.NewReadEvent(n.uri, state.data, nil).WithMeta(state.meta)
 }
 
 // Subscribe subscribes to Nacos configuration changes.
@@ -137,7 +226,7 @@ func (n *NacosReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent,
 	}
 
 	eventChan := make(chan *reader.ReadEvent, 1)
-	ctx, cancel := n.withClose(ctx)
+	ctx, cancel := reader.WithClose(ctx, n.closeCtx)
 	go func() {
 		defer cancel()
 		n.subscribe(ctx, eventChan)
@@ -160,33 +249,65 @@ func (n *NacosReader) Close() error {
 
 	n.closed = true
 	n.closeCancel()
+	n.closeGRPC()
 	n.client.CloseIdleConnections()
 	return nil
 }
 
-func (n *NacosReader) withClose(ctx context.Context) (context.Context, context.CancelFunc) {
-	requestCtx, cancel := context.WithCancel(ctx)
-	stop := context.AfterFunc(n.closeCtx, cancel)
-	return requestCtx, func() {
-		stop()
-		cancel()
+// fetch gets the content of key, decrypting cipher- configs. The MD5 is that
+// of the content as stored by Nacos.
+func (n *NacosReader) fetch(ctx context.Context, key ConfigKey) (configState, error) {
+	var (
+		content []byte
+		meta    reader.ContentMeta
+		dataKey string
+		err     error
+	)
+	if n.useGRPC() {
+		content, meta, dataKey, err = n.grpcQuery(ctx, key)
+		if errors.Is(err, errGRPCUnavailable) && n.fallbackToHTTP() {
+			content, meta, dataKey, err = n.httpQuery(ctx, key)
+		}
+	} else {
+		content, meta, dataKey, err = n.httpQuery(ctx, key)
+	}
+	if err != nil {
+		return configState{}, fmt.Errorf("get Nacos config %s: %w", key, err)
 	}
-}
 
-func (n *NacosReader) fetch(ctx context.Context) ([]byte, error) {
-	params := n.configValues()
-	reqURL := n.config.BaseURL + configPath + "?" + params.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	data, err := n.decrypt(ctx, key, content, dataKey)
 	if err != nil {
-		return nil, fmt.Errorf("create Nacos config request: %w", err)
+		return configState{}, fmt.Errorf("decrypt Nacos config %s: %w", key, err)
 	}
+	return configState{data: data, meta: meta, md5: md5Hex(content)}, nil
+}
 
-	body, err := n.do(req, n.config.Timeout)
+func (n *NacosReader) httpQuery(ctx context.Context, key ConfigKey) ([]byte, reader.ContentMeta, string, error) {
+	params := n.configValues(key)
+	body, header, err := n.do(ctx, n.config.Timeout, func(server string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server+configPath+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("create Nacos config request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("get Nacos config %s/%s: %w", n.config.Group, n.config.DataID, err)
+		return nil, reader.ContentMeta{}, "", err
 	}
 
-	return body, nil
+	return body, configMeta(header), header.Get(encryptedDataKeyHeader), nil
+}
+
+// configMeta reports the Nacos config type as an extension hint. The
+// Content-Type of config responses is always text/plain, so only its charset
+// is kept.
+func configMeta(header http.Header) reader.ContentMeta {
+	meta := reader.ParseContentType(header.Get("Content-Type"))
+	meta.MIMEType = ""
+	if configType := strings.TrimSpace(header.Get(configTypeHeader)); configType != "" {
+		meta.Extension = "." + strings.ToLower(configType)
+	}
+	return meta
 }
 
 func (n *NacosReader) subscribe(ctx context.Context, eventChan chan<- *reader.ReadEvent) {
@@ -199,7 +320,7 @@ func (n *NacosReader) subscribe(ctx context.Context, eventChan chan<- *reader.Re
 		default:
 		}
 
-		changed, err := n.listen(ctx)
+		changedKeys, err := n.listen(ctx)
 		if err != nil {
 			confEvent := reader.NewReadEvent(n.uri, nil, err)
 			select {
@@ -216,9 +337,16 @@ func (n *NacosReader) subscribe(ctx context.Context, eventChan chan<- *reader.Re
 			}
 		}
 
-		if changed {
-			data, err := n.fetch(ctx)
-			confEvent := reader.NewReadEvent(n.uri, data, err)
+		if len(changedKeys) > 0 {
+			changed, err := n.fetchChanged(ctx, changedKeys)
+			if err == nil && !changed {
+				// Content is identical to what was last delivered
+				continue
+			}
+			confEvent := reader.NewReadEvent(n.uri, nil, err)
+			if err == nil {
+				confEvent = n.currentEvent()
+			}
 			select {
 			case eventChan <- confEvent:
 			case <-ctx.Done():
@@ -228,127 +356,208 @@ func (n *NacosReader) subscribe(ctx context.Context, eventChan chan<- *reader.Re
 	}
 }
 
-func (n *NacosReader) listen(ctx context.Context) (bool, error) {
+// listen long polls all configs and returns the ones that changed.
+func (n *NacosReader) listen(ctx context.Context) ([]ConfigKey, error) {
+	if n.useGRPC() {
+		changed, err := n.grpcListen(ctx)
+		if !errors.Is(err, errGRPCUnavailable) || !n.fallbackToHTTP() {
+			if err != nil {
+				return nil, fmt.Errorf("listen Nacos config %s/%s: %w", n.config.Group, n.config.DataID, err)
+			}
+			return changed, nil
+		}
+	}
+
 	params := url.Values{}
 	params.Set(listeningConfigsParamName, n.listenPayload())
 
 	timeout := n.config.ListenTimeout + time.Second
-	reqCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, n.config.BaseURL+configListenPath, strings.NewReader(params.Encode()))
-	if err != nil {
-		return false, fmt.Errorf("create Nacos listener request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
-	req.Header.Set("Long-Pulling-Timeout", strconv.FormatInt(n.config.ListenTimeout.Milliseconds(), 10))
-
-	body, err := n.do(req, timeout)
+	body, _, err := n.do(ctx, timeout, func(server string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+configListenPath, strings.NewReader(params.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("create Nacos listener request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
+		req.Header.Set("Long-Pulling-Timeout", strconv.FormatInt(n.config.ListenTimeout.Milliseconds(), 10))
+		return req, nil
+	})
 	if err != nil {
-		return false, fmt.Errorf("listen Nacos config %s/%s: %w", n.config.Group, n.config.DataID, err)
+		return nil, fmt.Errorf("listen Nacos config %s/%s: %w", n.config.Group, n.config.DataID, err)
 	}
 
-	return strings.TrimSpace(string(body)) != "", nil
+	return n.changedKeys(string(body)), nil
 }
 
-func (n *NacosReader) do(req *http.Request, timeout time.Duration) ([]byte, error) {
-	if err := n.ensureToken(req.Context()); err != nil {
-		return nil, err
+// do sends the request built by newRequest for a server base URL, moving on to
+// the next server of the cluster when one cannot be reached.
+func (n *NacosReader) do(ctx context.Context, timeout time.Duration, newRequest func(server string) (*http.Request, error)) ([]byte, http.Header, error) {
+	servers, err := n.servers.candidates(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	values := req.URL.Query()
-	if n.accessToken != "" {
-		values.Set("accessToken", n.accessToken)
-		req.URL.RawQuery = values.Encode()
+	for _, server := range servers {
+		var body []byte
+		var header http.Header
+		body, header, err = n.doServer(ctx, server, timeout, newRequest)
+		if err == nil {
+			n.servers.markUp(server)
+			return body, header, nil
+		}
+		if ctx.Err() != nil || !isConnError(err) {
+			return nil, nil, err
+		}
+		n.servers.markDown(server)
 	}
+	return nil, nil, err
+}
 
+// doServer sends a request to server with the current access token. A 403
+// response invalidates the token and the request is retried once after
+// logging in again.
+func (n *NacosReader) doServer(ctx context.Context, server string, timeout time.Duration, newRequest func(server string) (*http.Request, error)) ([]byte, http.Header, error) {
 	client := *n.client
 	client.Timeout = timeout
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response body: %w", err)
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	for attempt := 0; ; attempt++ {
+		token, err := n.ensureToken(ctx, server)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		req, err := newRequest(server)
+		if err != nil {
+			return nil, nil, err
+		}
+		query := req.URL.Query()
+		for name, value := range n.signHeaders(query.Get("group"), query.Get("tenant")) {
+			req.Header.Set(name, value)
+		}
+		if token != "" {
+			values := req.URL.Query()
+			values.Set("accessToken", token)
+			req.URL.RawQuery = values.Encode()
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("read response body: %w", err)
+		}
+		if resp.StatusCode == http.StatusForbidden && token != "" && attempt == 0 {
+			n.invalidateToken(token)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, nil, fmt.Errorf("unexpected HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+
+		return body, resp.Header, nil
 	}
+}
 
-	return body, nil
+// invalidateToken drops token so the next request logs in again, unless it
+// was already replaced by a concurrent login.
+func (n *NacosReader) invalidateToken(token string) {
+	n.tokenMu.Lock()
+	defer n.tokenMu.Unlock()
+
+	if n.accessToken == token {
+		n.accessToken = ""
+	}
 }
 
-func (n *NacosReader) ensureToken(ctx context.Context) error {
+// ensureToken returns a valid access token, logging in when there is none or
+// the current one is within a tenth of its tokenTtl from expiring.
+func (n *NacosReader) ensureToken(ctx context.Context, server string) (string, error) {
 	n.tokenMu.Lock()
 	defer n.tokenMu.Unlock()
 
-	if n.config.Username == "" || n.accessToken != "" {
-		return nil
+	if n.config.Username == "" {
+		return "", nil
+	}
+	if n.accessToken != "" && (n.tokenRefreshAt.IsZero() || time.Now().Before(n.tokenRefreshAt)) {
+		return n.accessToken, nil
 	}
 
 	params := url.Values{}
 	params.Set("username", n.config.Username)
 	params.Set("password", n.config.Password)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.config.BaseURL+authLoginPath, strings.NewReader(params.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+authLoginPath, strings.NewReader(params.Encode()))
 	if err != nil {
-		return fmt.Errorf("create Nacos auth request: %w", err)
+		return "", fmt.Errorf("create Nacos auth request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := n.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("login Nacos: %w", err)
+		return "", fmt.Errorf("login Nacos: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("read auth response body: %w", err)
+		return "", fmt.Errorf("read auth response body: %w", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("login Nacos failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return "", fmt.Errorf("login Nacos failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
 
 	var result struct {
 		AccessToken string `json:"accessToken"`
+		TokenTTL    int64  `json:"tokenTtl"`
 	}
 	if err := json.Unmarshal(body, &result); err != nil {
-		return fmt.Errorf("decode Nacos auth response: %w", err)
+		return "", fmt.Errorf("decode Nacos auth response: %w", err)
 	}
 	if result.AccessToken == "" {
-		return fmt.Errorf("empty Nacos access token")
+		return "", fmt.Errorf("empty Nacos access token")
 	}
 
 	n.accessToken = result.AccessToken
-	return nil
+	n.tokenRefreshAt = time.Time{}
+	if result.TokenTTL > 0 {
+		ttl := time.Duration(result.TokenTTL) * time.Second
+		n.tokenRefreshAt = time.Now().Add(ttl - ttl/10)
+	}
+	return n.accessToken, nil
 }
 
-func (n *NacosReader) configValues() url.Values {
+func (n *NacosReader) configValues(key ConfigKey) url.Values {
 	values := url.Values{}
-	values.Set("dataId", n.config.DataID)
-	values.Set("group", n.config.Group)
+	values.Set("dataId", key.DataID)
+	values.Set("group", key.Group)
 	if n.config.Namespace != "" {
 		values.Set("tenant", n.config.Namespace)
 	}
 	return values
 }
 
+// listenPayload encodes every watched config as dataId^2group^2md5[^2tenant]^1
+// with the MD5 of its last fetched content.
 func (n *NacosReader) listenPayload() string {
-	tenant := n.config.Namespace
-	return n.config.DataID + splitConfigInner +
-		n.config.Group + splitConfigInner +
-		"" + splitConfigInner +
-		tenant + splitConfigInner +
-		splitConfig
+	var payload strings.Builder
+	for _, key := range n.keys() {
+		payload.WriteString(key.DataID + splitConfigInner + key.Group + splitConfigInner + n.currentMD5(key))
+		if n.config.Namespace != "" {
+			payload.WriteString(splitConfigInner + n.config.Namespace)
+		}
+		payload.WriteString(splitConfig)
+	}
+	return payload.String()
 }
 
 func parseNacosURI(u *url.URL) (*NacosConfig, error) {
-	if strings.TrimSpace(u.Hostname()) == "" {
-		return nil, fmt.Errorf("host is required")
+	query := u.Query()
+	hosts := reader.Hosts(u)
+	if len(hosts) == 0 && query.Get("endpoint") == "" {
+		return nil, fmt.Errorf("host or endpoint is required")
 	}
 
 	group, dataID, err := parsePath(u.EscapedPath())
@@ -356,29 +565,35 @@ func parseNacosURI(u *url.URL) (*NacosConfig, error) {
 		return nil, err
 	}
 
-	port := DefaultPort
-	if u.Port() != "" {
-		parsedPort, err := strconv.ParseUint(u.Port(), 10, 16)
-		if err != nil {
-			return nil, fmt.Errorf("invalid port: %w", err)
-		}
-		port = int(parsedPort)
+	timeout, err := reader.ParseDuration(query, "timeout", DefaultTimeout)
+	if err != nil {
+		return nil, err
 	}
-
-	query := u.Query()
-	timeout, err := parseDuration(query, "timeout", DefaultTimeout)
+	listenTimeout, err := reader.ParseDuration(query, "listen_timeout", DefaultListenTimeout)
 	if err != nil {
 		return nil, err
 	}
-	listenTimeout, err := parseDuration(query, "listen_timeout", DefaultListenTimeout)
+	retryDelay, err := reader.ParseDuration(query, "retry_delay", DefaultRetryDelay)
 	if err != nil {
 		return nil, err
 	}
-	retryDelay, err := parseDuration(query, "retry_delay", DefaultRetryDelay)
+	endpointRefresh, err := reader.ParseDuration(query, "endpoint_refresh", DefaultEndpointRefresh)
 	if err != nil {
 		return nil, err
 	}
 
+	protocol := firstNonEmpty(query.Get("protocol"), ProtocolHTTP)
+	if protocol != ProtocolGRPC && protocol != ProtocolHTTP {
+		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+	grpcFallback := protocol == ProtocolGRPC && query.Get("grpc_fallback") != "false"
+	grpcPortOffset := DefaultGRPCPortOffset
+	if value := query.Get("grpc_port_offset"); value != "" {
+		if grpcPortOffset, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("invalid grpc_port_offset: %w", err)
+		}
+	}
+
 	serverScheme := firstNonEmpty(query.Get("server_scheme"), "http")
 	contextPath := firstNonEmpty(query.Get("context_path"), DefaultContextPath)
 	if !strings.HasPrefix(contextPath, "/") {
@@ -402,18 +617,53 @@ func parseNacosURI(u *url.URL) (*NacosConfig, error) {
 		}
 	}
 
-	baseURL := fmt.Sprintf("%s://%s:%d%s", serverScheme, u.Hostname(), port, contextPath)
+	serverURLs := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		baseURL, err := serverURL(serverScheme, host, contextPath)
+		if err != nil {
+			return nil, err
+		}
+		serverURLs = append(serverURLs, baseURL)
+	}
+
+	accessKey := query.Get("access_key")
+	secretKey := query.Get("secret_key")
+	if (accessKey == "") != (secretKey == "") {
+		return nil, fmt.Errorf("access_key and secret_key must be set together")
+	}
+
+	sharedConfigs, err := parseSharedConfigs(query.Get("shared_configs"), group)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoint string
+	if value := query.Get("endpoint"); value != "" {
+		if endpoint, err = endpointURL(value); err != nil {
+			return nil, err
+		}
+	}
 
 	return &NacosConfig{
-		BaseURL:       baseURL,
-		Group:         group,
-		DataID:        dataID,
-		Namespace:     namespace,
-		Username:      username,
-		Password:      password,
-		Timeout:       timeout,
-		ListenTimeout: listenTimeout,
-		RetryDelay:    retryDelay,
+		Protocol:        protocol,
+		GRPCFallback:    grpcFallback,
+		GRPCPortOffset:  grpcPortOffset,
+		ServerURLs:      serverURLs,
+		Endpoint:        endpoint,
+		EndpointRefresh: endpointRefresh,
+		ServerScheme:    serverScheme,
+		ContextPath:     contextPath,
+		Group:           group,
+		DataID:          dataID,
+		SharedConfigs:   sharedConfigs,
+		Namespace:       namespace,
+		Username:        username,
+		Password:        password,
+		AccessKey:       accessKey,
+		SecretKey:       secretKey,
+		Timeout:         timeout,
+		ListenTimeout:   listenTimeout,
+		RetryDelay:      retryDelay,
 	}, nil
 }
 
@@ -441,21 +691,6 @@ func parsePath(path string) (string, string, error) {
 	return group, dataID, nil
 }
 
-func parseDuration(query url.Values, key string, defaultValue time.Duration) (time.Duration, error) {
-	value := query.Get(key)
-	if value == "" {
-		return defaultValue, nil
-	}
-	duration, err := time.ParseDuration(value)
-	if err != nil {
-		return 0, fmt.Errorf("invalid %s format: %w", key, err)
-	}
-	if duration <= 0 {
-		return 0, fmt.Errorf("%s must be positive", key)
-	}
-	return duration, nil
-}
-
 func firstNonEmpty(values ...string) string {
 	for _, value := range values {
 		if value != "" {
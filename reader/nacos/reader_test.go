@@ -2,30 +2,48 @@ package nacos
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	_ "github.com/sower-proxy/feconf/decoder/json"
+	_ "github.com/sower-proxy/feconf/decoder/yaml"
 	"github.com/sower-proxy/feconf/reader"
 )
 
 func TestParseNacosURI(t *testing.T) {
 	tests := []struct {
-		name      string
-		uri       string
-		wantErr   bool
-		wantGroup string
-		wantData  string
+		name         string
+		uri          string
+		wantErr      bool
+		wantGroup    string
+		wantData     string
+		wantServers  []string
+		wantEndpoint string
+		wantProtocol string
 	}{
 		{
-			name:      "valid URI",
-			uri:       "nacos://127.0.0.1:8848/DEFAULT_GROUP/app.yaml?namespace=dev&username=user&password=pass&timeout=5s",
-			wantGroup: "DEFAULT_GROUP",
-			wantData:  "app.yaml",
+			name:         "valid URI",
+			uri:          "nacos://127.0.0.1:8848/DEFAULT_GROUP/app.yaml?namespace=dev&username=user&password=pass&timeout=5s",
+			wantGroup:    "DEFAULT_GROUP",
+			wantData:     "app.yaml",
+			wantProtocol: ProtocolHTTP,
+		},
+		{
+			name:         "grpc protocol",
+			uri:          "nacos://127.0.0.1:8848/DEFAULT_GROUP/app.yaml?protocol=grpc",
+			wantGroup:    "DEFAULT_GROUP",
+			wantData:     "app.yaml",
+			wantProtocol: ProtocolGRPC,
 		},
 		{
 			name:      "valid URI with escaped slash in dataId",
@@ -53,6 +71,35 @@ func TestParseNacosURI(t *testing.T) {
 			uri:     "nacos://127.0.0.1/DEFAULT_GROUP/app.yaml?listen_timeout=0s",
 			wantErr: true,
 		},
+		{
+			name:        "server list",
+			uri:         "nacos://10.0.0.1:8848,10.0.0.2,10.0.0.3:8858/DEFAULT_GROUP/app.yaml",
+			wantGroup:   "DEFAULT_GROUP",
+			wantData:    "app.yaml",
+			wantServers: []string{"http://10.0.0.1:8848/nacos", "http://10.0.0.2:8848/nacos", "http://10.0.0.3:8858/nacos"},
+		},
+		{
+			name:         "endpoint without host",
+			uri:          "nacos:///DEFAULT_GROUP/app.yaml?endpoint=address.example.com",
+			wantGroup:    "DEFAULT_GROUP",
+			wantData:     "app.yaml",
+			wantEndpoint: "http://address.example.com:8080/nacos/serverlist",
+		},
+		{
+			name:    "access key without secret key",
+			uri:     "nacos://127.0.0.1/DEFAULT_GROUP/app.yaml?access_key=ak",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported protocol",
+			uri:     "nacos://127.0.0.1/DEFAULT_GROUP/app.yaml?protocol=udp",
+			wantErr: true,
+		},
+		{
+			name:    "invalid server port",
+			uri:     "nacos://10.0.0.1:8848,10.0.0.2:99999/DEFAULT_GROUP/app.yaml",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -78,6 +125,15 @@ func TestParseNacosURI(t *testing.T) {
 			if tt.name == "valid URI" && config.Timeout != 5*time.Second {
 				t.Errorf("Timeout = %v, want 5s", config.Timeout)
 			}
+			if tt.wantServers != nil && !slices.Equal(config.ServerURLs, tt.wantServers) {
+				t.Errorf("ServerURLs = %v, want %v", config.ServerURLs, tt.wantServers)
+			}
+			if config.Endpoint != tt.wantEndpoint {
+				t.Errorf("Endpoint = %s, want %s", config.Endpoint, tt.wantEndpoint)
+			}
+			if tt.wantProtocol != "" && config.Protocol != tt.wantProtocol {
+				t.Errorf("Protocol = %s, want %s", config.Protocol, tt.wantProtocol)
+			}
 		})
 	}
 }
@@ -111,6 +167,30 @@ func TestNacosReaderRead(t *testing.T) {
 	}
 }
 
+func TestNacosReaderReadMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+		w.Header().Set(configTypeHeader, "yaml")
+		fmt.Fprint(w, "name: app")
+	}))
+	defer server.Close()
+
+	nacosReader := newTestReader(t, server.URL+"/DEFAULT_GROUP/app")
+	_, meta, err := nacosReader.ReadMeta(context.Background())
+	if err != nil {
+		t.Fatalf("ReadMeta() error = %v", err)
+	}
+	if meta.Extension != ".yaml" {
+		t.Errorf("Extension = %s, want .yaml", meta.Extension)
+	}
+	if meta.MIMEType != "" {
+		t.Errorf("MIMEType = %s, want empty", meta.MIMEType)
+	}
+	if meta.Charset != "utf-8" {
+		t.Errorf("Charset = %s, want utf-8", meta.Charset)
+	}
+}
+
 func TestNacosReaderReadWithAuth(t *testing.T) {
 	const testData = `{"name":"app"}`
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -206,6 +286,301 @@ func TestNacosReaderSubscribe(t *testing.T) {
 	}
 }
 
+func TestNacosReaderSubscribeTracksMD5(t *testing.T) {
+	stub := newNacosStub(t, "name: v1")
+	defer stub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nacosReader := newTestReader(t, stub.URL+"/DEFAULT_GROUP/app.yaml?listen_timeout=50ms")
+
+	if _, err := nacosReader.Read(ctx); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	events, err := nacosReader.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	// Unchanged content must not be reported even after several polls
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event for unchanged config: %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if got := stub.listenMD5(); got != md5Hex([]byte("name: v1")) {
+		t.Errorf("listen MD5 = %q, want %q", got, md5Hex([]byte("name: v1")))
+	}
+
+	stub.publish("app.yaml", "name: v2")
+	select {
+	case event := <-events:
+		if !event.IsValid() {
+			t.Fatalf("event should be valid: %+v", event)
+		}
+		if string(event.Data) != "name: v2" {
+			t.Errorf("event data = %s", string(event.Data))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	select {
+	case event := <-events:
+		t.Fatalf("duplicate event: %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestNacosReaderTokenRefresh(t *testing.T) {
+	stub := newNacosStub(t, "name: app")
+	defer stub.Close()
+
+	nacosReader := newTestReader(t, stub.URL+"/DEFAULT_GROUP/app.yaml?username=user&password=pass")
+	ctx := context.Background()
+
+	if _, err := nacosReader.Read(ctx); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got := stub.loginCount(); got != 1 {
+		t.Fatalf("logins = %d, want 1", got)
+	}
+	if _, err := nacosReader.Read(ctx); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got := stub.loginCount(); got != 1 {
+		t.Fatalf("logins = %d, want cached token", got)
+	}
+
+	// Token close to its tokenTtl is refreshed before use
+	nacosReader.tokenMu.Lock()
+	nacosReader.tokenRefreshAt = time.Now().Add(-time.Second)
+	nacosReader.tokenMu.Unlock()
+	if _, err := nacosReader.Read(ctx); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got := stub.loginCount(); got != 2 {
+		t.Fatalf("logins = %d, want 2 after expiry", got)
+	}
+
+	// Token revoked server side is replaced after a 403
+	stub.revokeTokens()
+	data, err := nacosReader.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read() after revoke error = %v", err)
+	}
+	if string(data) != "name: app" {
+		t.Errorf("Read() data = %s", string(data))
+	}
+	if got := stub.loginCount(); got != 3 {
+		t.Fatalf("logins = %d, want 3 after 403", got)
+	}
+}
+
+func TestNacosReaderSharedConfigs(t *testing.T) {
+	stub := newNacosStub(t, "server:\n  port: 8080\nname: app")
+	defer stub.Close()
+	stub.set("common.yaml", "server:\n  host: 0.0.0.0\n  port: 80\nlog: info")
+	stub.set("db.json", `{"db":{"host":"db"},"log":"warn"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nacosReader := newTestReader(t, stub.URL+"/DEFAULT_GROUP/app.yaml?shared_configs=common.yaml,SHARED/db.json&listen_timeout=50ms")
+	if !nacosReader.Structured() {
+		t.Fatal("Structured() = false, want true")
+	}
+	want := []ConfigKey{{"DEFAULT_GROUP", "common.yaml"}, {"SHARED", "db.json"}, {"DEFAULT_GROUP", "app.yaml"}}
+	if got := nacosReader.keys(); !slices.Equal(got, want) {
+		t.Errorf("keys() = %v, want %v", got, want)
+	}
+
+	values, err := nacosReader.ReadMap(ctx)
+	if err != nil {
+		t.Fatalf("ReadMap() error = %v", err)
+	}
+	wantValues := map[string]any{
+		"server": map[string]any{"host": "0.0.0.0", "port": 8080},
+		"db":     map[string]any{"host": "db"},
+		"log":    "warn",
+		"name":   "app",
+	}
+	if fmt.Sprint(values) != fmt.Sprint(wantValues) {
+		t.Errorf("ReadMap() = %v, want %v", values, wantValues)
+	}
+
+	events, err := nacosReader.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	stub.publish("common.yaml", "server:\n  host: 127.0.0.1\nlog: debug")
+	select {
+	case event := <-events:
+		if !event.IsValid() {
+			t.Fatalf("event should be valid: %+v", event)
+		}
+		server, _ := event.Values["server"].(map[string]any)
+		if server["host"] != "127.0.0.1" || server["port"] != 8080 {
+			t.Errorf("event server = %v", server)
+		}
+		if event.Values["log"] != "warn" {
+			t.Errorf("event log = %v, want warn", event.Values["log"])
+		}
+		if event.Meta.MIMEType != "application/json" {
+			t.Errorf("event MIMEType = %s", event.Meta.MIMEType)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	if got := stub.listenCount(); got == 0 {
+		t.Fatal("listener was not called")
+	}
+}
+
+func TestNacosReaderFailover(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadHost := dead.Listener.Addr().String()
+	dead.Close()
+
+	stub := newNacosStub(t, "name: app")
+	defer stub.Close()
+	stubHost := stub.Listener.Addr().String()
+
+	nacosReader, err := NewNacosReader("nacos://" + deadHost + "," + stubHost + "/DEFAULT_GROUP/app.yaml")
+	if err != nil {
+		t.Fatalf("NewNacosReader() error = %v", err)
+	}
+
+	for range 2 {
+		data, err := nacosReader.Read(context.Background())
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if string(data) != "name: app" {
+			t.Errorf("Read() data = %s", string(data))
+		}
+	}
+
+	servers, err := nacosReader.servers.candidates(context.Background())
+	if err != nil {
+		t.Fatalf("candidates() error = %v", err)
+	}
+	if want := []string{"http://" + stubHost + "/nacos", "http://" + deadHost + "/nacos"}; !slices.Equal(servers, want) {
+		t.Errorf("candidates() = %v, want %v", servers, want)
+	}
+}
+
+func TestNacosReaderEndpointDiscovery(t *testing.T) {
+	first := newNacosStub(t, "name: first")
+	defer first.Close()
+	second := newNacosStub(t, "name: second")
+	defer second.Close()
+
+	var mu sync.Mutex
+	serverList := first.Listener.Addr().String()
+	addressServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != endpointServerListPath {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(w, serverList)
+	}))
+	defer addressServer.Close()
+
+	nacosReader, err := NewNacosReader("nacos:///DEFAULT_GROUP/app.yaml?endpoint=" + addressServer.Listener.Addr().String() + "&endpoint_refresh=50ms")
+	if err != nil {
+		t.Fatalf("NewNacosReader() error = %v", err)
+	}
+
+	data, err := nacosReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if string(data) != "name: first" {
+		t.Errorf("Read() data = %s, want name: first", string(data))
+	}
+
+	mu.Lock()
+	serverList = second.Listener.Addr().String()
+	mu.Unlock()
+	time.Sleep(100 * time.Millisecond)
+
+	data, err = nacosReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if string(data) != "name: second" {
+		t.Errorf("Read() data = %s, want name: second", string(data))
+	}
+}
+
+func TestNacosReaderAccessKeySignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get(accessKeyHeader); got != "ak" {
+			t.Errorf("%s = %s, want ak", accessKeyHeader, got)
+		}
+		timestamp := r.Header.Get(timestampHeader)
+		mac := hmac.New(sha1.New, []byte("sk"))
+		mac.Write([]byte("dev+DEFAULT_GROUP+" + timestamp))
+		if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); r.Header.Get(signatureHeader) != want {
+			http.Error(w, "signature mismatch", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "name: app")
+	}))
+	defer server.Close()
+
+	nacosReader := newTestReader(t, server.URL+"/DEFAULT_GROUP/app.yaml?namespace=dev&access_key=ak&secret_key=sk")
+	data, err := nacosReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if string(data) != "name: app" {
+		t.Errorf("Read() data = %s", string(data))
+	}
+}
+
+func TestNacosReaderDecryptsCipherConfig(t *testing.T) {
+	aesCipher, err := NewAESCipher([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewAESCipher() error = %v", err)
+	}
+	content, dataKey, err := aesCipher.Encrypt([]byte("password: secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(encryptedDataKeyHeader, dataKey)
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+
+	rawURL := server.URL + "/DEFAULT_GROUP/cipher-aes-db.yaml"
+	nacosReader := newTestReader(t, rawURL)
+	if _, err := nacosReader.Read(context.Background()); err == nil {
+		t.Fatal("Read() expected error without decryptor")
+	}
+
+	parsedURL, _ := url.Parse(rawURL)
+	nacosReader, err = NewNacosReader("nacos://"+parsedURL.Host+parsedURL.Path+queryServerScheme("http", ""), WithDecryptor("aes", aesCipher))
+	if err != nil {
+		t.Fatalf("NewNacosReader() error = %v", err)
+	}
+	data, err := nacosReader.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if string(data) != "password: secret" {
+		t.Errorf("Read() data = %s", string(data))
+	}
+	if got := nacosReader.currentMD5(ConfigKey{Group: "DEFAULT_GROUP", DataID: "cipher-aes-db.yaml"}); got != md5Hex([]byte(content)) {
+		t.Errorf("MD5 = %s, want MD5 of encrypted content", got)
+	}
+}
+
 func TestNacosReaderClose(t *testing.T) {
 	nacosReader := newTestReader(t, "http://127.0.0.1:8848/DEFAULT_GROUP/app.yaml")
 
@@ -283,3 +658,147 @@ func queryServerScheme(scheme, query string) string {
 	}
 	return separator + "server_scheme=" + scheme
 }
+
+// nacosStub is a minimal Nacos server keeping configs by dataId, honouring
+// listen MD5s and issuing access tokens that can be revoked.
+type nacosStub struct {
+	*httptest.Server
+
+	t        *testing.T
+	mu       sync.Mutex
+	contents map[string]string
+	changed  chan struct{}
+	tokens   map[string]bool
+	logins   int
+	lastMD5  string
+	listens  int
+}
+
+func newNacosStub(t *testing.T, content string) *nacosStub {
+	stub := &nacosStub{
+		t:        t,
+		contents: map[string]string{"app.yaml": content},
+		changed:  make(chan struct{}),
+		tokens:   make(map[string]bool),
+	}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.handle))
+	return stub
+}
+
+func (s *nacosStub) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == DefaultContextPath+authLoginPath {
+		s.mu.Lock()
+		s.logins++
+		token := fmt.Sprintf("token-%d", s.logins)
+		s.tokens[token] = true
+		s.mu.Unlock()
+		fmt.Fprintf(w, `{"accessToken":%q,"tokenTtl":18000}`, token)
+		return
+	}
+
+	s.mu.Lock()
+	authorized := s.logins == 0 || s.tokens[r.URL.Query().Get("accessToken")]
+	s.mu.Unlock()
+	if !authorized {
+		http.Error(w, "token invalid", http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Path {
+	case DefaultContextPath + configPath:
+		s.mu.Lock()
+		content, ok := s.contents[r.URL.Query().Get("dataId")]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, content)
+	case DefaultContextPath + configListenPath:
+		if err := r.ParseForm(); err != nil {
+			s.t.Errorf("ParseForm() error = %v", err)
+			return
+		}
+
+		s.mu.Lock()
+		s.listens++
+		s.mu.Unlock()
+		timeout, _ := time.ParseDuration(r.Header.Get("Long-Pulling-Timeout") + "ms")
+		deadline := time.After(timeout)
+		for {
+			changedLines, changed := s.changedLines(r.Form.Get(listeningConfigsParamName))
+			if changedLines != "" {
+				fmt.Fprint(w, url.QueryEscape(changedLines))
+				return
+			}
+			select {
+			case <-changed:
+			case <-deadline:
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	default:
+		s.t.Errorf("unexpected path: %s", r.URL.Path)
+	}
+}
+
+// changedLines returns the dataId^2group^1 lines of listened configs whose MD5
+// differs from the stored content.
+func (s *nacosStub) changedLines(payload string) (string, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changedLines strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(payload, splitConfig), splitConfig) {
+		fields := strings.Split(line, splitConfigInner)
+		if len(fields) < 3 {
+			s.t.Errorf("listener payload fields = %q", fields)
+			continue
+		}
+		s.lastMD5 = fields[2]
+		if fields[2] != md5Hex([]byte(s.contents[fields[0]])) {
+			changedLines.WriteString(fields[0] + splitConfigInner + fields[1] + splitConfig)
+		}
+	}
+	return changedLines.String(), s.changed
+}
+
+func (s *nacosStub) set(dataID, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contents[dataID] = content
+}
+
+func (s *nacosStub) publish(dataID, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contents[dataID] = content
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *nacosStub) listenCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listens
+}
+
+func (s *nacosStub) revokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.tokens)
+}
+
+func (s *nacosStub) loginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+func (s *nacosStub) listenMD5() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastMD5
+}
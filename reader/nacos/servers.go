@@ -0,0 +1,225 @@
+package nacos
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultEndpointPort    = 8080
+	DefaultEndpointRefresh = 30 * time.Second
+	endpointServerListPath = "/nacos/serverlist"
+
+	// serverCooldown is how long a server that failed to connect is tried
+	// only after all other servers.
+	serverCooldown = 30 * time.Second
+)
+
+// serverList tracks the Nacos servers of a cluster. Servers come from the URI
+// host list or are discovered from an address server endpoint, and the list
+// is rotated when a server cannot be reached.
+type serverList struct {
+	client      *http.Client
+	endpoint    string
+	refresh     time.Duration
+	scheme      string
+	contextPath string
+
+	mu          sync.Mutex
+	servers     []string
+	current     int
+	downUntil   map[string]time.Time
+	refreshedAt time.Time
+}
+
+func newServerList(config *NacosConfig, client *http.Client) *serverList {
+	return &serverList{
+		client:      client,
+		endpoint:    config.Endpoint,
+		refresh:     config.EndpointRefresh,
+		scheme:      config.ServerScheme,
+		contextPath: config.ContextPath,
+		servers:     append([]string(nil), config.ServerURLs...),
+		downUntil:   make(map[string]time.Time),
+	}
+}
+
+// candidates returns the base URLs in the order they should be tried: healthy
+// servers starting with the current one, then servers still cooling down.
+func (s *serverList) candidates(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.discover(ctx); err != nil && len(s.servers) == 0 {
+		return nil, err
+	}
+	if len(s.servers) == 0 {
+		return nil, fmt.Errorf("no Nacos servers available")
+	}
+
+	now := time.Now()
+	healthy := make([]string, 0, len(s.servers))
+	var cooling []string
+	for i := range s.servers {
+		server := s.servers[(s.current+i)%len(s.servers)]
+		if now.Before(s.downUntil[server]) {
+			cooling = append(cooling, server)
+		} else {
+			healthy = append(healthy, server)
+		}
+	}
+	return append(healthy, cooling...), nil
+}
+
+// markUp makes server the current server.
+func (s *serverList) markUp(server string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.downUntil, server)
+	if i := s.index(server); i >= 0 {
+		s.current = i
+	}
+}
+
+// markDown moves server to the back of the rotation for serverCooldown.
+func (s *serverList) markDown(server string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.downUntil[server] = time.Now().Add(serverCooldown)
+	if i := s.index(server); i >= 0 && i == s.current {
+		s.current = (i + 1) % len(s.servers)
+	}
+}
+
+func (s *serverList) index(server string) int {
+	for i, candidate := range s.servers {
+		if candidate == server {
+			return i
+		}
+	}
+	return -1
+}
+
+// discover refreshes the server list from the address server once the refresh
+// interval has passed. A failed refresh keeps the previous list.
+func (s *serverList) discover(ctx context.Context) error {
+	if s.endpoint == "" || (!s.refreshedAt.IsZero() && time.Since(s.refreshedAt) < s.refresh) {
+		return nil
+	}
+
+	servers, err := s.fetchServers(ctx)
+	if err != nil {
+		if len(s.servers) > 0 {
+			s.refreshedAt = time.Now()
+		}
+		return fmt.Errorf("discover Nacos servers from %s: %w", s.endpoint, err)
+	}
+	s.refreshedAt = time.Now()
+
+	current := ""
+	if len(s.servers) > 0 {
+		current = s.servers[s.current]
+	}
+	s.servers = servers
+	s.current = max(s.index(current), 0)
+	for server := range s.downUntil {
+		if s.index(server) < 0 {
+			delete(s.downUntil, server)
+		}
+	}
+	return nil
+}
+
+func (s *serverList) fetchServers(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create server list request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read server list: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var servers []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		server, err := serverURL(s.scheme, line, s.contextPath)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read server list: %w", err)
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("empty server list")
+	}
+	return servers, nil
+}
+
+// serverURL builds the base URL of a server given as host or host:port.
+func serverURL(scheme, hostPort, contextPath string) (string, error) {
+	host, port := hostPort, DefaultPort
+	if h, p, err := net.SplitHostPort(hostPort); err == nil {
+		parsedPort, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return "", fmt.Errorf("invalid port in %q: %w", hostPort, err)
+		}
+		host, port = h, int(parsedPort)
+	}
+	host = strings.Trim(host, "[]")
+	if strings.TrimSpace(host) == "" {
+		return "", fmt.Errorf("host is required")
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)), contextPath), nil
+}
+
+// endpointURL returns the address server URL for the endpoint query value,
+// which is either a full URL or host[:port] of the default server list path.
+func endpointURL(endpoint string) (string, error) {
+	if strings.Contains(endpoint, "://") {
+		if _, err := url.Parse(endpoint); err != nil {
+			return "", fmt.Errorf("invalid endpoint: %w", err)
+		}
+		return endpoint, nil
+	}
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		endpoint = net.JoinHostPort(strings.Trim(endpoint, "[]"), strconv.Itoa(DefaultEndpointPort))
+	}
+	return "http://" + endpoint + endpointServerListPath, nil
+}
+
+// isConnError reports whether err was returned by the HTTP transport, as
+// opposed to a response from the server.
+func isConnError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// publishScript writes the content, bumps the version and stores the checksum
+// in one step, so readers never observe a partial update. It then announces
+// the version on the stream and channel, if any. Content whose checksum is
+// already stored is not written again and the current version is returned.
+// The checksum of a hash field or JSONPath covers its name.
+//
+// KEYS: key, version key, checksum key[, stream]
+// ARGV: source, checksum, channel, hash field or JSONPath, content or
+// field/value pairs
+var publishScript = redis.NewScript(`
+local hashChunk = 1000
+
+if redis.call('GET', KEYS[3]) == ARGV[2] then
+	return tonumber(redis.call('GET', KEYS[2]) or '0')
+end
+
+local source = ARGV[1]
+if source == 'string' then
+	redis.call('SET', KEYS[1], ARGV[5])
+elseif source == 'field' then
+	redis.call('HSET', KEYS[1], ARGV[4], ARGV[5])
+elseif source == 'hash' then
+	redis.call('DEL', KEYS[1])
+	-- Fields are written in chunks, unpack fails beyond the Lua stack limit
+	for i = 5, #ARGV, hashChunk do
+		redis.call('HSET', KEYS[1], unpack(ARGV, i, math.min(i + hashChunk - 1, #ARGV)))
+	end
+elseif source == 'json' then
+	redis.call('JSON.SET', KEYS[1], ARGV[4], ARGV[5])
+end
+
+local version = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[3], ARGV[2])
+if KEYS[4] then
+	redis.call('XADD', KEYS[4], '*', 'version', version, 'checksum', ARGV[2])
+end
+if ARGV[3] ~= '' then
+	redis.call('PUBLISH', ARGV[3], version)
+end
+return version
+`)
+
+// Publisher writes configuration read by Redis readers of the same URI. Every
+// publish atomically updates the content, the version key and the checksum
+// key, so it works with all watch modes. In a cluster these keys, and the
+// stream, must share a hash tag, e.g. {config:app} and {config:app}:version.
+type Publisher struct {
+	client redis.UniversalClient
+	config *RedisConfig
+}
+
+// NewPublisher creates a publisher for a Redis URI with the string, hash or
+// json source
+func NewPublisher(uri string) (*Publisher, error) {
+	config, err := newRedisConfig(uri)
+	if err != nil {
+		return nil, err
+	}
+	if config.Source == SourceScan {
+		return nil, fmt.Errorf("publishing is not supported for source=%s", config.Source)
+	}
+
+	return &Publisher{client: newClient(config), config: config}, nil
+}
+
+// Publish writes a document to the key, the hash field, or the JSONPath of
+// the json source, and returns the new version.
+func (p *Publisher) Publish(ctx context.Context, data []byte) (int64, error) {
+	switch {
+	case p.config.Source == SourceHash:
+		return 0, fmt.Errorf("use PublishValues for source=%s", p.config.Source)
+	case p.config.Source == SourceJSON:
+		if !json.Valid(data) {
+			return 0, fmt.Errorf("content of source=%s is not valid JSON", p.config.Source)
+		}
+		return p.run(ctx, "json", checksum(p.config.JSONPath, data), p.config.JSONPath, string(data))
+	case p.config.HashField != "":
+		return p.run(ctx, "field", checksum(p.config.HashField, data), p.config.HashField, string(data))
+	default:
+		return p.run(ctx, "string", checksum("", data), "", string(data))
+	}
+}
+
+// PublishValues replaces the hash with the values, as fields named by their
+// dotted path, or writes them as JSON for the json source. It returns the new
+// version.
+func (p *Publisher) PublishValues(ctx context.Context, values map[string]any) (int64, error) {
+	switch p.config.Source {
+	case SourceHash:
+		fields := make(map[string]string)
+		flattenValues("", values, fields)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var pairs []any
+		var canonical strings.Builder
+		for _, name := range names {
+			pairs = append(pairs, name, fields[name])
+			canonical.WriteString(name + "=" + fields[name] + "\n")
+		}
+		return p.run(ctx, "hash", checksum("", []byte(canonical.String())), "", pairs...)
+	case SourceJSON:
+		data, err := json.Marshal(values)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode values: %w", err)
+		}
+		return p.Publish(ctx, data)
+	default:
+		return 0, fmt.Errorf("use Publish for source=%s", p.config.Source)
+	}
+}
+
+// Close closes the publisher
+func (p *Publisher) Close() error {
+	return p.client.Close()
+}
+
+func (p *Publisher) run(ctx context.Context, source, sum, target string, content ...any) (int64, error) {
+	keys := []string{p.config.Key, p.config.VersionKey, p.config.ChecksumKey}
+	if p.config.Stream != "" {
+		keys = append(keys, p.config.Stream)
+	}
+	args := append([]any{source, sum, p.config.Channel, target}, content...)
+
+	version, err := publishScript.Run(ctx, p.client, keys, args...).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish key '%s': %w", p.config.Key, err)
+	}
+	return version, nil
+}
+
+// checksum returns the SHA-256 of content published to a hash field or
+// JSONPath, prefixed with its name, or of the whole content without a target
+func checksum(target string, data []byte) string {
+	h := sha256.New()
+	if target != "" {
+		h.Write([]byte(target))
+		h.Write([]byte{0})
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// flattenValues turns nested maps into dotted field names, the reverse of the
+// hash source. Lists are joined with commas.
+func flattenValues(prefix string, values map[string]any, fields map[string]string) {
+	for key, value := range values {
+		name := prefix + key
+		switch value := value.(type) {
+		case map[string]any:
+			flattenValues(name+".", value, fields)
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			fields[name] = strings.Join(items, ",")
+		default:
+			fields[name] = fmt.Sprint(value)
+		}
+	}
+}
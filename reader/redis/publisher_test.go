@@ -0,0 +1,246 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+)
+
+func TestPublisherPublish(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	publisher, err := NewPublisher(fmt.Sprintf("redis://%s/config:app?stream=config:app:stream", s.Addr()))
+	if err != nil {
+		t.Fatalf("NewPublisher() error = %v", err)
+	}
+	defer publisher.Close()
+
+	ctx := context.Background()
+	for i, tt := range []struct {
+		data    string
+		version int64
+	}{
+		{data: "name: v1", version: 1},
+		{data: "name: v1", version: 1},
+		{data: "name: v2", version: 2},
+	} {
+		version, err := publisher.Publish(ctx, []byte(tt.data))
+		if err != nil {
+			t.Fatalf("Publish() #%d error = %v", i, err)
+		}
+		if version != tt.version {
+			t.Errorf("Publish() #%d version = %d, want %d", i, version, tt.version)
+		}
+	}
+
+	if got, _ := s.Get("config:app"); got != "name: v2" {
+		t.Errorf("content = %q, want %q", got, "name: v2")
+	}
+	if got, _ := s.Get("config:app:version"); got != "2" {
+		t.Errorf("version = %q, want 2", got)
+	}
+	if got, _ := s.Get("config:app:checksum"); got != checksum("", []byte("name: v2")) {
+		t.Errorf("checksum = %q, want %q", got, checksum("", []byte("name: v2")))
+	}
+	if entries, _ := s.Stream("config:app:stream"); len(entries) != 2 {
+		t.Errorf("stream entries = %d, want 2", len(entries))
+	}
+}
+
+func TestPublisherPublishValuesToHash(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.HSet("config:app", "stale", "value")
+	uri := fmt.Sprintf("redis://%s/config:app?source=hash&watch=channel", s.Addr())
+
+	publisher, err := NewPublisher(uri)
+	if err != nil {
+		t.Fatalf("NewPublisher() error = %v", err)
+	}
+	defer publisher.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := publisher.PublishValues(ctx, map[string]any{"name": "app"}); err != nil {
+		t.Fatalf("PublishValues() error = %v", err)
+	}
+
+	rr, err := NewRedisReader(uri)
+	if err != nil {
+		t.Fatalf("NewRedisReader() error = %v", err)
+	}
+	defer rr.Close()
+
+	events, err := rr.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	expectValues(t, events, map[string]any{"name": "app"})
+
+	want := map[string]any{
+		"name":  "app",
+		"hosts": []any{"a", "b"},
+		"db":    map[string]any{"host": "localhost", "port": 5432},
+	}
+	version, err := publisher.PublishValues(ctx, want)
+	if err != nil {
+		t.Fatalf("PublishValues() error = %v", err)
+	}
+	if version != 2 {
+		t.Errorf("PublishValues() version = %d, want 2", version)
+	}
+
+	// All fields arrive with the announcement, the stale field is gone
+	expectValues(t, events, map[string]any{
+		"name":  "app",
+		"hosts": "a,b",
+		"db":    map[string]any{"host": "localhost", "port": "5432"},
+	})
+}
+
+func TestPublisherPublishFields(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+
+	// The same content published to another field is written
+	for i, field := range []string{"a", "b", "a"} {
+		publisher, err := NewPublisher(fmt.Sprintf("redis://%s/config:app#%s", s.Addr(), field))
+		if err != nil {
+			t.Fatalf("NewPublisher() error = %v", err)
+		}
+		version, err := publisher.Publish(ctx, []byte("name: app"))
+		_ = publisher.Close()
+		if err != nil {
+			t.Fatalf("Publish() #%d error = %v", i, err)
+		}
+		if want := int64(i + 1); version != want {
+			t.Errorf("Publish() #%d version = %d, want %d", i, version, want)
+		}
+	}
+	for _, field := range []string{"a", "b"} {
+		if got := s.HGet("config:app", field); got != "name: app" {
+			t.Errorf("field %s = %q, want %q", field, got, "name: app")
+		}
+	}
+}
+
+func TestPublisherPublishValuesLargeHash(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	publisher, err := NewPublisher(fmt.Sprintf("redis://%s/config:app?source=hash", s.Addr()))
+	if err != nil {
+		t.Fatalf("NewPublisher() error = %v", err)
+	}
+	defer publisher.Close()
+
+	values := make(map[string]any)
+	for i := range 10000 {
+		values[fmt.Sprintf("key%d", i)] = i
+	}
+	if _, err := publisher.PublishValues(context.Background(), values); err != nil {
+		t.Fatalf("PublishValues() error = %v", err)
+	}
+	if fields, _ := s.HKeys("config:app"); len(fields) != len(values) {
+		t.Errorf("fields = %d, want %d", len(fields), len(values))
+	}
+}
+
+func TestRedisReaderJSONSource(t *testing.T) {
+	s := miniredis.RunT(t)
+	withJSONCommands(t, s)
+
+	publisher, err := NewPublisher(fmt.Sprintf("redis://%s/config:doc?source=json", s.Addr()))
+	if err != nil {
+		t.Fatalf("NewPublisher() error = %v", err)
+	}
+	defer publisher.Close()
+
+	ctx := context.Background()
+	if _, err := publisher.Publish(ctx, []byte(`{"app":{"name":"app","port":8080},"other":{}}`)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if _, err := publisher.Publish(ctx, []byte(`{"app":`)); err == nil {
+		t.Error("Publish() expected error for invalid JSON")
+	}
+
+	tests := []struct {
+		path    string
+		want    map[string]any
+		wantErr bool
+	}{
+		{path: "$.app", want: map[string]any{"name": "app", "port": float64(8080)}},
+		{path: "$.app.name", wantErr: true},
+		{path: "$.missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr, err := NewRedisReader(fmt.Sprintf("redis://%s/config:doc?source=json&json_path=%s&max_retries=1", s.Addr(), tt.path))
+			if err != nil {
+				t.Fatalf("NewRedisReader() error = %v", err)
+			}
+			defer rr.Close()
+
+			values, err := rr.ReadMap(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("ReadMap() = %v, want %v", values, tt.want)
+			}
+		})
+	}
+}
+
+// withJSONCommands adds JSON.SET and JSON.GET on the root path and top-level
+// members, which miniredis lacks.
+func withJSONCommands(t *testing.T, s *miniredis.Miniredis) {
+	t.Helper()
+	var mu sync.Mutex
+	docs := make(map[string]map[string]any)
+
+	register := func(name string, cmd server.Cmd) {
+		if err := s.Server().Register(name, cmd); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+	register("JSON.SET", func(c *server.Peer, cmd string, args []string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var doc map[string]any
+		if len(args) != 3 || args[1] != "$" || json.Unmarshal([]byte(args[2]), &doc) != nil {
+			c.WriteError("ERR unsupported JSON.SET")
+			return
+		}
+		docs[args[0]] = doc
+		c.WriteOK()
+	})
+	register("JSON.GET", func(c *server.Peer, cmd string, args []string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		doc, ok := docs[args[0]]
+		if !ok {
+			c.WriteNull()
+			return
+		}
+		var value any = doc
+		for _, member := range strings.Split(strings.TrimPrefix(args[1], "$"), ".")[1:] {
+			object, _ := value.(map[string]any)
+			if value, ok = object[member]; !ok {
+				c.WriteBulk("[]")
+				return
+			}
+		}
+		data, _ := json.Marshal([]any{value})
+		c.WriteBulk(string(data))
+	})
+}
@@ -3,7 +3,9 @@ package redis
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"strconv"
 	"strings"
@@ -30,63 +32,99 @@ var (
 	DefaultRetryDelay = 1 * time.Second
 	// DefaultDB is the default Redis database
 	DefaultDB = 0
+	// DefaultHealthInterval between health checks of an idle subscription
+	DefaultHealthInterval = 30 * time.Second
 )
 
 // init registers Redis readers
 func init() {
-	_ = reader.RegisterReader(SchemeRedis, func(uri string) (reader.ConfReader, error) {
-		return NewRedisReader(uri)
-	})
-	_ = reader.RegisterReader(SchemeRediss, func(uri string) (reader.ConfReader, error) {
-		return NewRedisReader(uri)
-	})
+	for _, scheme := range []reader.Scheme{
+		SchemeRedis, SchemeRediss,
+		SchemeRedisSentinel, SchemeRedissSentinel,
+		SchemeRedisCluster, SchemeRedissCluster,
+	} {
+		_ = reader.RegisterReader(scheme, func(uri string) (reader.ConfReader, error) {
+			return NewRedisReader(uri)
+		})
+	}
 }
 
 // RedisConfig holds Redis client configuration
 type RedisConfig struct {
-	Addr         string
-	Password     string
-	DB           int
-	Key          string
-	HashField    string // Optional hash field name for hash operations
-	Timeout      time.Duration
-	TLSConfig    *tls.Config
-	RetryDelay   time.Duration
-	MaxRetries   int
-	PoolSize     int
-	MinIdleConns int
+	Addr             string
+	Addrs            []string // Sentinel addresses or cluster seed nodes
+	Topology         Topology
+	MasterName       string // Sentinel master name
+	SentinelPassword string
+	Password         string
+	DB               int
+	Key              string // Key, or key pattern for the scan source
+	HashField        string // Optional hash field name for hash operations
+	Source           Source
+	JSONPath         string // JSONPath of the json source
+	Timeout          time.Duration
+	TLSConfig        *tls.Config
+	RetryDelay       time.Duration
+	MaxRetries       int
+	PoolSize         int
+	MinIdleConns     int
+	HealthInterval   time.Duration
+	Watch            WatchMode
+	Channel          string // Channel announcing changes
+	VersionKey       string // Key bumped on changes
+	ChecksumKey      string // Key holding the checksum of published content
+	Stream           string // Stream appended to on changes
+	PollInterval     time.Duration
 }
 
 // RedisReader implements ConfReader for Redis-based configuration
 type RedisReader struct {
 	uri           string
-	client        *redis.Client
+	client        redis.UniversalClient
 	config        *RedisConfig
 	mu            sync.RWMutex
 	closed        bool
-	subsChan      chan *redis.PubSub
+	pubsubs       map[*redis.PubSub]struct{}
 	errorCount    int
 	lastErrorTime time.Time
 }
 
 // NewRedisReader creates a new Redis reader
 func NewRedisReader(uri string) (*RedisReader, error) {
+	config, err := newRedisConfig(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisReader{
+		uri:     uri,
+		client:  newClient(config),
+		config:  config,
+		pubsubs: make(map[*redis.PubSub]struct{}),
+	}, nil
+}
+
+// newRedisConfig parses a Redis URI into configuration with defaults
+func newRedisConfig(uri string) (*RedisConfig, error) {
 	u, err := reader.ParseURI(uri)
 	if err != nil {
 		return nil, err
 	}
 
-	if u.Scheme != string(SchemeRedis) && u.Scheme != string(SchemeRediss) {
-		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s or %s", u.Scheme, SchemeRedis, SchemeRediss)
+	if _, ok := topologyOf(u.Scheme); !ok {
+		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s, %s, %s or their TLS variants",
+			u.Scheme, SchemeRedis, SchemeRedisSentinel, SchemeRedisCluster)
 	}
 
 	config := &RedisConfig{
-		Timeout:      DefaultTimeout,
-		RetryDelay:   DefaultRetryDelay,
-		MaxRetries:   DefaultRetryAttempts,
-		DB:           DefaultDB,
-		PoolSize:     10,
-		MinIdleConns: 1,
+		Timeout:        DefaultTimeout,
+		RetryDelay:     DefaultRetryDelay,
+		MaxRetries:     DefaultRetryAttempts,
+		DB:             DefaultDB,
+		PoolSize:       10,
+		MinIdleConns:   1,
+		HealthInterval: DefaultHealthInterval,
+		PollInterval:   DefaultPollInterval,
 	}
 
 	// Parse Redis URI configuration
@@ -99,28 +137,7 @@ func NewRedisReader(uri string) (*RedisReader, error) {
 		return nil, fmt.Errorf("redis key must be specified in path")
 	}
 
-	// Create Redis options
-	opts := &redis.Options{
-		Addr:         config.Addr,
-		Password:     config.Password,
-		DB:           config.DB,
-		DialTimeout:  config.Timeout,
-		ReadTimeout:  config.Timeout,
-		WriteTimeout: config.Timeout,
-		PoolSize:     config.PoolSize,
-		MinIdleConns: config.MinIdleConns,
-		MaxRetries:   config.MaxRetries,
-		TLSConfig:    config.TLSConfig,
-	}
-
-	client := redis.NewClient(opts)
-
-	return &RedisReader{
-		uri:      uri,
-		client:   client,
-		config:   config,
-		subsChan: make(chan *redis.PubSub, 1),
-	}, nil
+	return config, nil
 }
 
 // Read reads configuration data from Redis key
@@ -132,10 +149,12 @@ func (r *RedisReader) Read(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("reader is closed")
 	}
 
-	return r.fetchWithRetry(ctx)
+	data, _, err := r.fetchContentWithRetry(ctx)
+	return data, err
 }
 
-// Subscribe subscribes to Redis keyspace notifications for real-time updates
+// Subscribe watches the key for real-time updates, using keyspace
+// notifications unless another watch mode is configured
 func (r *RedisReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -146,13 +165,13 @@ func (r *RedisReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent,
 
 	eventChan := make(chan *reader.ReadEvent, 1)
 
-	// Check if keyspace notifications are enabled
-	if err := r.ensureKeyspaceNotifications(ctx); err != nil {
+	mode, err := r.watchMode(ctx)
+	if err != nil {
 		close(eventChan)
-		return nil, fmt.Errorf("failed to enable keyspace notifications: %w", err)
+		return nil, err
 	}
 
-	go r.subscribeKeyspace(ctx, eventChan)
+	go r.subscribe(ctx, eventChan, mode)
 
 	return eventChan, nil
 }
@@ -173,12 +192,8 @@ func (r *RedisReader) Close() error {
 	r.closed = true
 
 	// Close any active subscriptions
-	select {
-	case pubsub := <-r.subsChan:
-		if pubsub != nil {
-			_ = pubsub.Close()
-		}
-	default:
+	for pubsub := range r.pubsubs {
+		_ = pubsub.Close()
 	}
 
 	if r.client != nil {
@@ -188,28 +203,28 @@ func (r *RedisReader) Close() error {
 	return nil
 }
 
-// fetchWithRetry performs Redis GET with retry mechanism
-func (r *RedisReader) fetchWithRetry(ctx context.Context) ([]byte, error) {
+// fetchContentWithRetry performs fetchContent with retry mechanism
+func (r *RedisReader) fetchContentWithRetry(ctx context.Context) ([]byte, map[string]any, error) {
 	var lastErr error
 
 	for attempt := 0; attempt < r.config.MaxRetries; attempt++ {
 		if attempt > 0 {
 			select {
 			case <-ctx.Done():
-				return nil, ctx.Err()
+				return nil, nil, ctx.Err()
 			case <-time.After(r.config.RetryDelay):
 			}
 		}
 
-		data, err := r.fetch(ctx)
+		data, values, err := r.fetchContent(ctx)
 		if err == nil {
-			return data, nil
+			return data, values, nil
 		}
 
 		lastErr = err
 	}
 
-	return nil, fmt.Errorf("failed after %d attempts: %w", r.config.MaxRetries, lastErr)
+	return nil, nil, fmt.Errorf("failed after %d attempts: %w", r.config.MaxRetries, lastErr)
 }
 
 // fetch performs single Redis GET or HGET operation
@@ -254,9 +269,9 @@ func (r *RedisReader) fetch(ctx context.Context) ([]byte, error) {
 }
 
 // ensureKeyspaceNotifications enables keyspace notifications if not already enabled
-func (r *RedisReader) ensureKeyspaceNotifications(ctx context.Context) error {
+func (r *RedisReader) ensureKeyspaceNotifications(ctx context.Context, client redis.Cmdable) error {
 	// Check current notification settings
-	config := r.client.ConfigGet(ctx, "notify-keyspace-events")
+	config := client.ConfigGet(ctx, "notify-keyspace-events")
 	if config.Err() != nil {
 		return fmt.Errorf("failed to check keyspace notifications config: %w", config.Err())
 	}
@@ -269,8 +284,13 @@ func (r *RedisReader) ensureKeyspaceNotifications(ctx context.Context) error {
 	// Enable keyspace notifications if not already enabled
 	// For hash fields, we need 'K' (keyspace events) and 'h' (hash commands) or '$' (generic commands)
 	needsKeyspace := !strings.Contains(currentConfig, "K")
+	hashCommands := r.config.HashField != "" || r.config.Source == SourceHash
+	// Keys matching a pattern also come and go with generic commands such as DEL
+	needsGeneric := r.config.Source == SourceScan && !strings.Contains(currentConfig, "g")
+	// RedisJSON commands raise module key type events
+	needsModule := r.config.Source == SourceJSON && !strings.Contains(currentConfig, "d")
 	var needsCommands bool
-	if r.config.HashField != "" {
+	if hashCommands {
 		// For hash operations, prefer 'h' (hash commands) but fall back to '$' (generic commands)
 		needsCommands = !strings.Contains(currentConfig, "h") && !strings.Contains(currentConfig, "$")
 	} else {
@@ -278,13 +298,19 @@ func (r *RedisReader) ensureKeyspaceNotifications(ctx context.Context) error {
 		needsCommands = !strings.Contains(currentConfig, "s") && !strings.Contains(currentConfig, "$")
 	}
 
-	if needsKeyspace || needsCommands {
+	if needsKeyspace || needsCommands || needsGeneric || needsModule {
 		newConfig := currentConfig
 		if needsKeyspace {
 			newConfig += "K"
 		}
+		if needsGeneric {
+			newConfig += "g"
+		}
+		if needsModule {
+			newConfig += "d"
+		}
 		if needsCommands {
-			if r.config.HashField != "" {
+			if hashCommands {
 				// Prefer hash-specific commands for hash operations
 				newConfig += "h"
 			} else {
@@ -292,7 +318,7 @@ func (r *RedisReader) ensureKeyspaceNotifications(ctx context.Context) error {
 				newConfig += "$"
 			}
 		}
-		result := r.client.ConfigSet(ctx, "notify-keyspace-events", newConfig)
+		result := client.ConfigSet(ctx, "notify-keyspace-events", newConfig)
 		if result.Err() != nil {
 			return fmt.Errorf("failed to enable keyspace notifications: %w", result.Err())
 		}
@@ -301,124 +327,278 @@ func (r *RedisReader) ensureKeyspaceNotifications(ctx context.Context) error {
 	return nil
 }
 
-// subscribeKeyspace subscribes to Redis keyspace notifications
-func (r *RedisReader) subscribeKeyspace(ctx context.Context, eventChan chan<- *reader.ReadEvent) {
-	defer close(eventChan)
+// Circuit breaker parameters for keyspace notification handling
+const (
+	maxErrors         = 5
+	resetTimeout      = 30 * time.Second
+	backoffMultiplier = 2
+	maxBackoff        = 30 * time.Second
+)
 
-	// Create keyspace pattern for the specific key
-	keyspacePattern := fmt.Sprintf("__keyspace@%d__:%s", r.config.DB, r.config.Key)
+// subscription delivers changes of the key as read events, detected with
+// its watch mode.
+type subscription struct {
+	reader   *RedisReader
+	reporter *reader.Reporter
+	mode     WatchMode
+	backoff  time.Duration
+	streamID string
+}
 
-	pubsub := r.client.PSubscribe(ctx, keyspacePattern)
-	defer pubsub.Close()
+// subscribe watches the key for changes until ctx is done
+func (r *RedisReader) subscribe(ctx context.Context, eventChan chan<- *reader.ReadEvent, mode WatchMode) {
+	defer close(eventChan)
 
-	// Store pubsub for cleanup
-	select {
-	case r.subsChan <- pubsub:
-	default:
-		// Channel full, proceed anyway
+	s := &subscription{
+		reader:   r,
+		reporter: reader.NewReporter(eventChan),
+		mode:     mode,
+		backoff:  time.Second,
 	}
 
-	// Send initial read
-	if data, err := r.fetch(ctx); err == nil {
-		confEvent := reader.NewReadEvent(r.uri, data, nil)
+	// Resubscribe whenever the subscription is lost, e.g. after a failover
+	// or a slot migration, backing off while it cannot be established.
+	resubscribe := false
+	delay := r.config.RetryDelay
+	for {
+		subscribed, err := s.watch(ctx, resubscribe)
+		if ctx.Err() != nil || r.isClosed() {
+			return
+		}
+		resubscribe = true
+		if subscribed {
+			delay = r.config.RetryDelay
+			continue
+		}
+
+		if !s.reporter.Send(ctx, reader.NewReadEvent(r.uri, nil, fmt.Errorf("failed to resubscribe %s changes: %w", mode, err))) {
+			return
+		}
 		select {
-		case eventChan <- confEvent:
 		case <-ctx.Done():
 			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*backoffMultiplier, maxBackoff)
+	}
+}
+
+// watch detects changes until it fails. It reports whether it was set up,
+// i.e. subscribed or done with the first read.
+func (s *subscription) watch(ctx context.Context, resubscribe bool) (bool, error) {
+	switch s.mode {
+	case WatchVersion:
+		return s.pollVersion(ctx)
+	case WatchStream:
+		return s.readStream(ctx)
+	default:
+		return s.watchPubSub(ctx, resubscribe)
+	}
+}
+
+// watchPubSub subscribes to the announcement channel, or to keyspace
+// notifications on every node publishing them, and handles messages until a
+// subscription is lost.
+func (s *subscription) watchPubSub(ctx context.Context, resubscribe bool) (bool, error) {
+	r := s.reader
+	nodes := []pubsubNode{{client: r.client, addr: r.config.Channel}}
+	if s.mode == WatchKeyspace {
+		var err error
+		if nodes, err = r.keyspaceNodes(ctx); err != nil {
+			return false, err
 		}
 	}
 
-	// Circuit breaker parameters
-	const (
-		maxErrors         = 5
-		resetTimeout      = 30 * time.Second
-		backoffMultiplier = 2
-		maxBackoff        = 30 * time.Second
-	)
+	// Every node is received from in its own goroutine, and messages are
+	// handled here one at a time. A burst of messages results in one read.
+	messages := make(chan struct{}, 1)
+	lost := make(chan error, len(nodes))
+	for _, node := range nodes {
+		pubsub, err := s.subscribeNode(ctx, node, resubscribe)
+		if err != nil {
+			return false, err
+		}
+		defer r.untrackPubSub(pubsub)
+		defer pubsub.Close()
+		go s.receive(ctx, pubsub, node.addr, messages, lost)
+	}
 
-	backoff := time.Second
+	// Changes made while not subscribed are only seen by reading the key
+	if !s.sync(ctx) {
+		return true, ctx.Err()
+	}
 
-	// Listen for notifications
-	ch := pubsub.Channel()
+	ticker := time.NewTicker(r.config.HealthInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
-			return
-		case msg, ok := <-ch:
-			if !ok {
-				return
+			return true, ctx.Err()
+		case err := <-lost:
+			return true, err
+		case <-ticker.C:
+			if s.mode != WatchKeyspace {
+				continue
+			}
+			if current, err := r.keyspaceNodes(ctx); err == nil && nodeAddrs(current) != nodeAddrs(nodes) {
+				return true, fmt.Errorf("keyspace notifications moved from %s to %s", nodeAddrs(nodes), nodeAddrs(current))
 			}
+		case <-messages:
+			if !s.notify(ctx) {
+				return true, ctx.Err()
+			}
+		}
+	}
+}
 
-			// Handle keyspace notification
-			if msg != nil {
-				// Check circuit breaker state
-				r.mu.Lock()
-				now := time.Now()
-				if r.errorCount >= maxErrors && now.Sub(r.lastErrorTime) < resetTimeout {
-					r.mu.Unlock()
-					// Circuit breaker is open, wait with backoff
-					select {
-					case <-time.After(backoff):
-						backoff = time.Duration(float64(backoff) * backoffMultiplier)
-						if backoff > maxBackoff {
-							backoff = maxBackoff
-						}
-					case <-ctx.Done():
-						return
-					}
-					continue
-				}
-				r.mu.Unlock()
-
-				// Fetch the updated value
-				data, err := r.fetch(ctx)
-
-				// Handle hash field not found gracefully
-				if err != nil && strings.Contains(err.Error(), "hash field not found") {
-					r.mu.Lock()
-					r.errorCount++
-					r.lastErrorTime = time.Now()
-					currentErrorCount := r.errorCount
-					r.mu.Unlock()
-
-					// Create enhanced error with timestamp and context
-					enhancedErr := fmt.Errorf("[%s] hash field '%s' temporarily unavailable (attempt %d/%d): %w",
-						now.Format(time.RFC3339), r.config.HashField, currentErrorCount, maxErrors, err)
-
-					confEvent := reader.NewReadEvent(r.uri, nil, enhancedErr)
-					select {
-					case eventChan <- confEvent:
-					case <-ctx.Done():
-						return
-					}
-					continue
-				}
+// subscribeNode subscribes on a node and waits for the confirmation.
+func (s *subscription) subscribeNode(ctx context.Context, node pubsubNode, resubscribe bool) (*redis.PubSub, error) {
+	r := s.reader
+	var pubsub *redis.PubSub
+	if s.mode == WatchChannel {
+		pubsub = node.client.Subscribe(ctx, r.config.Channel)
+	} else {
+		if resubscribe {
+			if err := r.ensureKeyspaceNotifications(ctx, node.client); err != nil && !isConfigDenied(err) {
+				return nil, fmt.Errorf("failed to enable keyspace notifications on %s: %w", node.addr, err)
+			}
+		}
+		pubsub = node.client.PSubscribe(ctx, fmt.Sprintf("__keyspace@%d__:%s", r.config.DB, r.config.Key))
+	}
 
-				// Reset error count on successful fetch
-				if err == nil {
-					r.mu.Lock()
-					r.errorCount = 0
-					backoff = time.Second
-					r.mu.Unlock()
-				}
+	if !r.trackPubSub(pubsub) {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("reader is closed")
+	}
+	if _, err := pubsub.Receive(ctx); err != nil {
+		r.untrackPubSub(pubsub)
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe %s changes on %s: %w", s.mode, node.addr, err)
+	}
+	return pubsub, nil
+}
 
-				confEvent := reader.NewReadEvent(r.uri, data, err)
-				select {
-				case eventChan <- confEvent:
-				case <-ctx.Done():
-					return
+// receive forwards messages of a subscription until it is lost or closed.
+// Idle subscriptions are pinged every health interval.
+func (s *subscription) receive(ctx context.Context, pubsub *redis.PubSub, addr string, messages chan<- struct{}, lost chan<- error) {
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, s.reader.config.HealthInterval)
+		if err != nil {
+			if isTimeout(err) {
+				if err = pubsub.Ping(ctx); err == nil {
+					continue
 				}
 			}
+			lost <- fmt.Errorf("%s subscription on %s lost: %w", s.mode, addr, err)
+			return
+		}
+
+		if _, ok := msg.(*redis.Message); ok {
+			select {
+			case messages <- struct{}{}:
+			default:
+			}
 		}
 	}
 }
 
+// sync reads the key and sends it, or the error reading it, unless the last
+// sent event has the same content. The first read is always sent.
+func (s *subscription) sync(ctx context.Context) bool {
+	data, values, err := s.reader.fetchContent(ctx)
+	if err != nil && ctx.Err() != nil {
+		return false
+	}
+	return s.reporter.Report(ctx, s.reader.newEvent(data, values, err))
+}
+
+// notify handles a keyspace notification by reading the updated value.
+func (s *subscription) notify(ctx context.Context) bool {
+	r := s.reader
+
+	// Check circuit breaker state
+	r.mu.Lock()
+	now := time.Now()
+	if r.errorCount >= maxErrors && now.Sub(r.lastErrorTime) < resetTimeout {
+		r.mu.Unlock()
+		// Circuit breaker is open, wait with backoff
+		select {
+		case <-time.After(s.backoff):
+			s.backoff = min(time.Duration(float64(s.backoff)*backoffMultiplier), maxBackoff)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	r.mu.Unlock()
+
+	// Fetch the updated value
+	data, values, err := r.fetchContent(ctx)
+
+	// Handle hash field not found gracefully
+	if err != nil && strings.Contains(err.Error(), "hash field not found") {
+		r.mu.Lock()
+		r.errorCount++
+		r.lastErrorTime = time.Now()
+		currentErrorCount := r.errorCount
+		r.mu.Unlock()
+
+		// Create enhanced error with timestamp and context
+		enhancedErr := fmt.Errorf("[%s] hash field '%s' temporarily unavailable (attempt %d/%d): %w",
+			now.Format(time.RFC3339), r.config.HashField, currentErrorCount, maxErrors, err)
+
+		return s.reporter.Send(ctx, reader.NewReadEvent(r.uri, nil, enhancedErr))
+	}
+
+	// Reset error count on successful fetch
+	if err == nil {
+		r.mu.Lock()
+		r.errorCount = 0
+		s.backoff = time.Second
+		r.mu.Unlock()
+	}
+
+	return s.reporter.Send(ctx, r.newEvent(data, values, err))
+}
+
+// trackPubSub records an active subscription so Close can end it.
+func (r *RedisReader) trackPubSub(pubsub *redis.PubSub) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return false
+	}
+	r.pubsubs[pubsub] = struct{}{}
+	return true
+}
+
+func (r *RedisReader) untrackPubSub(pubsub *redis.PubSub) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pubsubs, pubsub)
+}
+
+func (r *RedisReader) isClosed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.closed
+}
+
+// isTimeout reports whether err is a read timeout, which leaves the
+// subscription usable.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 // parseRedisURI parses Redis URI into configuration
 func parseRedisURI(u *url.URL, config *RedisConfig) error {
-	// Set address
-	config.Addr = u.Host
-	if config.Addr == "" {
-		config.Addr = "localhost:6379"
+	// Set node addresses
+	if err := parseTopology(u, config); err != nil {
+		return err
 	}
 
 	// Extract password from userinfo
@@ -453,23 +633,19 @@ func parseRedisURI(u *url.URL, config *RedisConfig) error {
 		}
 		config.DB = db
 	}
+	if config.Topology == TopologyCluster && config.DB != 0 {
+		return fmt.Errorf("db is not supported by Redis Cluster")
+	}
 
 	// Parse timeout
-	if timeoutStr := query.Get("timeout"); timeoutStr != "" {
-		timeout, err := time.ParseDuration(timeoutStr)
-		if err != nil {
-			return fmt.Errorf("invalid timeout format: %w", err)
-		}
-		config.Timeout = timeout
+	var err error
+	if config.Timeout, err = reader.ParseNonNegativeDuration(query, "timeout", config.Timeout); err != nil {
+		return err
 	}
 
 	// Parse retry delay
-	if delayStr := query.Get("retry_delay"); delayStr != "" {
-		delay, err := time.ParseDuration(delayStr)
-		if err != nil {
-			return fmt.Errorf("invalid retry_delay format: %w", err)
-		}
-		config.RetryDelay = delay
+	if config.RetryDelay, err = reader.ParseNonNegativeDuration(query, "retry_delay", config.RetryDelay); err != nil {
+		return err
 	}
 
 	// Parse max retries
@@ -496,6 +672,21 @@ func parseRedisURI(u *url.URL, config *RedisConfig) error {
 		config.PoolSize = poolSize
 	}
 
+	// Parse source of configuration values
+	if err := parseSource(query, config); err != nil {
+		return err
+	}
+
+	// Parse change detection mode
+	if err := parseWatchMode(query, config); err != nil {
+		return err
+	}
+
+	// Parse health check interval
+	if config.HealthInterval, err = reader.ParseDuration(query, "health_interval", config.HealthInterval); err != nil {
+		return err
+	}
+
 	// Parse min idle connections
 	if minIdleStr := query.Get("min_idle_conns"); minIdleStr != "" {
 		minIdle, err := strconv.Atoi(minIdleStr)
@@ -508,8 +699,8 @@ func parseRedisURI(u *url.URL, config *RedisConfig) error {
 		config.MinIdleConns = minIdle
 	}
 
-	// Configure TLS for rediss scheme
-	if u.Scheme == string(SchemeRediss) {
+	// Configure TLS for rediss schemes
+	if strings.HasPrefix(u.Scheme, string(SchemeRediss)) {
 		config.TLSConfig = &tls.Config{
 			MinVersion: tls.VersionTLS12,
 		}
@@ -0,0 +1,248 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sower-proxy/feconf/reader"
+)
+
+// Source is the Redis data a reader maps to configuration.
+type Source string
+
+const (
+	// SourceString reads the key, or a field of a hash, as one document
+	SourceString Source = "string"
+	// SourceHash reads all fields of a hash as configuration values
+	SourceHash Source = "hash"
+	// SourceScan reads the string keys matching the key pattern as
+	// configuration values
+	SourceScan Source = "scan"
+	// SourceJSON reads the object selected by a JSONPath in a RedisJSON
+	// document
+	SourceJSON Source = "json"
+
+	// DefaultJSONPath selects the whole RedisJSON document
+	DefaultJSONPath = "$"
+
+	scanCount = 100
+)
+
+// parseSource sets the source of config from the source query parameter.
+func parseSource(query url.Values, config *RedisConfig) error {
+	config.Source = SourceString
+	if source := query.Get("source"); source != "" {
+		config.Source = Source(source)
+	}
+	config.JSONPath = DefaultJSONPath
+	if path := query.Get("json_path"); path != "" {
+		config.JSONPath = path
+	}
+
+	switch config.Source {
+	case SourceString:
+	case SourceHash, SourceScan, SourceJSON:
+		if config.HashField != "" {
+			return fmt.Errorf("hash field cannot be used with source=%s", config.Source)
+		}
+	default:
+		return fmt.Errorf("invalid source %q, expected: string, hash, scan or json", config.Source)
+	}
+	return nil
+}
+
+// Structured implements reader.MapReader. Hash, scan and JSON sources are read
+// as values instead of a document.
+func (r *RedisReader) Structured() bool {
+	return r.config.Source != SourceString
+}
+
+// ReadMap implements reader.MapReader
+func (r *RedisReader) ReadMap(ctx context.Context) (map[string]any, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	_, values, err := r.fetchContentWithRetry(ctx)
+	return values, err
+}
+
+// fetchContent reads the key as a document, or as values encoded to JSON for
+// structured sources.
+func (r *RedisReader) fetchContent(ctx context.Context) ([]byte, map[string]any, error) {
+	if !r.Structured() {
+		data, err := r.fetch(ctx)
+		return data, nil, err
+	}
+
+	values, err := r.fetchValues(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode values: %w", err)
+	}
+	return data, values, nil
+}
+
+// newEvent builds a read event of fetched content.
+func (r *RedisReader) newEvent(data []byte, values map[string]any, err error) *reader.ReadEvent {
+	event := reader.NewReadEvent(r.uri, data, err)
+	if err != nil || values == nil {
+		return event
+	}
+	return event.
+		WithMeta(reader.ContentMeta{MIMEType: "application/json", Extension: ".json"}).
+		WithValues(values)
+}
+
+// fetchValues reads the fields of the hash, or the keys matching the pattern,
+// and expands dotted names into nested maps. JSON documents are used as is.
+func (r *RedisReader) fetchValues(ctx context.Context) (map[string]any, error) {
+	if r.config.Source == SourceJSON {
+		return r.fetchJSON(ctx)
+	}
+
+	var flat map[string]string
+	if r.config.Source == SourceHash {
+		fields, err := r.client.HGetAll(ctx, r.config.Key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to HGETALL key '%s': %w", r.config.Key, err)
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("key '%s' not found", r.config.Key)
+		}
+		flat = fields
+	} else {
+		var err error
+		if flat, err = r.scanValues(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return reader.ExpandDotted(flat)
+}
+
+// fetchJSON reads the object selected by the JSONPath with JSON.GET. JSONPath
+// queries starting with $ return an array of matches, which must hold exactly
+// one object; legacy paths return the value itself.
+func (r *RedisReader) fetchJSON(ctx context.Context) (map[string]any, error) {
+	raw, err := r.client.Do(ctx, "JSON.GET", r.config.Key, r.config.JSONPath).Text()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("key '%s' not found", r.config.Key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON.GET key '%s': %w", r.config.Key, err)
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON of key '%s': %w", r.config.Key, err)
+	}
+	if strings.HasPrefix(r.config.JSONPath, "$") {
+		matches, _ := value.([]any)
+		if len(matches) != 1 {
+			return nil, fmt.Errorf("path '%s' of key '%s' matches %d values, expected 1", r.config.JSONPath, r.config.Key, len(matches))
+		}
+		value = matches[0]
+	}
+
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("path '%s' of key '%s' is not an object", r.config.JSONPath, r.config.Key)
+	}
+	return values, nil
+}
+
+// scanValues reads the string keys matching the pattern, named without the
+// literal prefix of the pattern. Keys of other types are skipped.
+func (r *RedisReader) scanValues(ctx context.Context) (map[string]string, error) {
+	keys, err := r.scanKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys match pattern '%s'", r.config.Key)
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	_, _ = pipe.Exec(ctx)
+
+	prefix := literalPrefix(r.config.Key)
+	values := make(map[string]string, len(keys))
+	for i, cmd := range cmds {
+		value, err := cmd.Result()
+		switch {
+		case err == redis.Nil, err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE"):
+			// Deleted since the scan, or not a string
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to GET key '%s': %w", keys[i], err)
+		}
+		if name := strings.TrimPrefix(keys[i], prefix); name != "" {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// scanKeys returns the keys matching the pattern, on every master of a
+// cluster.
+func (r *RedisReader) scanKeys(ctx context.Context) ([]string, error) {
+	scan := func(ctx context.Context, client redis.Cmdable) ([]string, error) {
+		var keys []string
+		iter := client.Scan(ctx, 0, r.config.Key, scanCount).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("failed to SCAN pattern '%s': %w", r.config.Key, err)
+		}
+		return keys, nil
+	}
+
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return scan(ctx, r.client)
+	}
+
+	var mu sync.Mutex
+	var keys []string
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		nodeKeys, err := scan(ctx, node)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, nodeKeys...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// literalPrefix returns the part of a glob pattern before its first special
+// character.
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
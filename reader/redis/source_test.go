@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/sower-proxy/feconf/reader"
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+func TestRedisReaderHashSource(t *testing.T) {
+	s := miniredis.RunT(t)
+	notifyConfig := withConfigCommand(t, s)
+	s.HSet("config:app", "name", "app", "db.host", "localhost", "db.port", "5432")
+
+	rr, err := NewRedisReader(fmt.Sprintf("redis://%s/config:app?source=hash", s.Addr()))
+	if err != nil {
+		t.Fatalf("NewRedisReader() error = %v", err)
+	}
+	defer rr.Close()
+
+	if !rr.Structured() {
+		t.Fatal("Structured() = false, want true")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	values, err := rr.ReadMap(ctx)
+	if err != nil {
+		t.Fatalf("ReadMap() error = %v", err)
+	}
+	want := map[string]any{"name": "app", "db": map[string]any{"host": "localhost", "port": "5432"}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("ReadMap() = %v, want %v", values, want)
+	}
+
+	events, err := rr.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if got := notifyConfig.value(); got != "Kh" {
+		t.Errorf("notify-keyspace-events = %q, want %q", got, "Kh")
+	}
+	expectValues(t, events, want)
+
+	s.HSet("config:app", "db.port", "6432")
+	s.Publish("__keyspace@0__:config:app", "hset")
+	expectValues(t, events, map[string]any{"name": "app", "db": map[string]any{"host": "localhost", "port": "6432"}})
+}
+
+func TestRedisReaderScanSource(t *testing.T) {
+	for _, scheme := range []string{"redis", "redis+cluster"} {
+		t.Run(scheme, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			notifyConfig := withConfigCommand(t, s)
+			_ = s.Set("app:config:name", "app")
+			_ = s.Set("app:config:db.host", "localhost")
+			_ = s.Set("other:key", "ignored")
+			s.HSet("app:config:meta", "owner", "ops")
+
+			rr, err := NewRedisReader(fmt.Sprintf("%s://%s/app:config:*?source=scan", scheme, s.Addr()))
+			if err != nil {
+				t.Fatalf("NewRedisReader() error = %v", err)
+			}
+			defer rr.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			want := map[string]any{"name": "app", "db": map[string]any{"host": "localhost"}}
+			values, err := rr.ReadMap(ctx)
+			if err != nil {
+				t.Fatalf("ReadMap() error = %v", err)
+			}
+			if !reflect.DeepEqual(values, want) {
+				t.Errorf("ReadMap() = %v, want %v", values, want)
+			}
+
+			events, err := rr.Subscribe(ctx)
+			if err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+			if got := notifyConfig.value(); got != "Kg$" {
+				t.Errorf("notify-keyspace-events = %q, want %q", got, "Kg$")
+			}
+			expectValues(t, events, want)
+
+			_ = s.Set("app:config:db.port", "5432")
+			s.Publish("__keyspace@0__:app:config:db.port", "set")
+			expectValues(t, events, map[string]any{"name": "app", "db": map[string]any{"host": "localhost", "port": "5432"}})
+		})
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		uri     string
+		wantErr bool
+	}{
+		{uri: "redis://localhost:6379/config:app?source=hash"},
+		{uri: "redis://localhost:6379/app:config:*?source=scan"},
+		{uri: "redis://localhost:6379/config:app?source=hash#field", wantErr: true},
+		{uri: "redis://localhost:6379/config:app?source=list", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			rr, err := NewRedisReader(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRedisReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if rr != nil {
+				_ = rr.Close()
+			}
+		})
+	}
+}
+
+// expectValues expects the next event to carry want as values decoded from JSON
+func expectValues(t *testing.T, events <-chan *reader.ReadEvent, want map[string]any) {
+	t.Helper()
+
+	event := readertest.ExpectValues(t, events, want)
+	if event.Meta.MIMEType != "application/json" {
+		t.Errorf("event MIME type = %s, want application/json", event.Meta.MIMEType)
+	}
+}
@@ -0,0 +1,226 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sower-proxy/feconf/reader"
+)
+
+const (
+	// SchemeRedisSentinel represents Redis Sentinel URI scheme
+	SchemeRedisSentinel reader.Scheme = "redis+sentinel"
+	// SchemeRedissSentinel represents Redis Sentinel URI scheme with TLS
+	SchemeRedissSentinel reader.Scheme = "rediss+sentinel"
+	// SchemeRedisCluster represents Redis Cluster URI scheme
+	SchemeRedisCluster reader.Scheme = "redis+cluster"
+	// SchemeRedissCluster represents Redis Cluster URI scheme with TLS
+	SchemeRedissCluster reader.Scheme = "rediss+cluster"
+
+	defaultRedisPort    = "6379"
+	defaultSentinelPort = "26379"
+)
+
+// Topology is the deployment a Redis reader connects to.
+type Topology string
+
+const (
+	// TopologyStandalone connects to a single Redis node
+	TopologyStandalone Topology = "standalone"
+	// TopologySentinel connects to the master of a Sentinel-managed group
+	TopologySentinel Topology = "sentinel"
+	// TopologyCluster connects to a Redis Cluster through seed nodes
+	TopologyCluster Topology = "cluster"
+)
+
+// topologyOf returns the topology of a Redis URI scheme.
+func topologyOf(scheme string) (Topology, bool) {
+	switch reader.Scheme(scheme) {
+	case SchemeRedis, SchemeRediss:
+		return TopologyStandalone, true
+	case SchemeRedisSentinel, SchemeRedissSentinel:
+		return TopologySentinel, true
+	case SchemeRedisCluster, SchemeRedissCluster:
+		return TopologyCluster, true
+	}
+	return "", false
+}
+
+// parseTopology sets the node addresses of config from the URI host list.
+// Hosts without a port use the Redis port, or the Sentinel port for Sentinel
+// addresses.
+func parseTopology(u *url.URL, config *RedisConfig) error {
+	topology, ok := topologyOf(u.Scheme)
+	if !ok {
+		return fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+	config.Topology = topology
+
+	defaultPort := defaultRedisPort
+	if topology == TopologySentinel {
+		defaultPort = defaultSentinelPort
+	}
+	config.Addrs = nil
+	for _, host := range reader.Hosts(u) {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), defaultPort)
+		}
+		config.Addrs = append(config.Addrs, host)
+	}
+
+	query := u.Query()
+	switch topology {
+	case TopologyStandalone:
+		if len(config.Addrs) > 1 {
+			return fmt.Errorf("multiple hosts require %s or %s scheme", SchemeRedisSentinel, SchemeRedisCluster)
+		}
+		config.Addr = "localhost:" + defaultRedisPort
+		if len(config.Addrs) == 1 {
+			config.Addr = config.Addrs[0]
+		}
+	case TopologySentinel:
+		config.MasterName = query.Get("master")
+		if config.MasterName == "" {
+			return fmt.Errorf("sentinel master name must be specified with master parameter")
+		}
+		config.SentinelPassword = query.Get("sentinel_password")
+		if len(config.Addrs) == 0 {
+			config.Addrs = []string{"localhost:" + defaultSentinelPort}
+		}
+	case TopologyCluster:
+		if len(config.Addrs) == 0 {
+			return fmt.Errorf("cluster seed nodes must be specified in host")
+		}
+	}
+	return nil
+}
+
+// newClient creates the client for the configured topology.
+func newClient(config *RedisConfig) redis.UniversalClient {
+	switch config.Topology {
+	case TopologySentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       config.MasterName,
+			SentinelAddrs:    config.Addrs,
+			SentinelPassword: config.SentinelPassword,
+			Password:         config.Password,
+			DB:               config.DB,
+			DialTimeout:      config.Timeout,
+			ReadTimeout:      config.Timeout,
+			WriteTimeout:     config.Timeout,
+			PoolSize:         config.PoolSize,
+			MinIdleConns:     config.MinIdleConns,
+			MaxRetries:       config.MaxRetries,
+			TLSConfig:        config.TLSConfig,
+		})
+	case TopologyCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        config.Addrs,
+			Password:     config.Password,
+			DialTimeout:  config.Timeout,
+			ReadTimeout:  config.Timeout,
+			WriteTimeout: config.Timeout,
+			PoolSize:     config.PoolSize,
+			MinIdleConns: config.MinIdleConns,
+			MaxRetries:   config.MaxRetries,
+			TLSConfig:    config.TLSConfig,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:         config.Addr,
+			Password:     config.Password,
+			DB:           config.DB,
+			DialTimeout:  config.Timeout,
+			ReadTimeout:  config.Timeout,
+			WriteTimeout: config.Timeout,
+			PoolSize:     config.PoolSize,
+			MinIdleConns: config.MinIdleConns,
+			MaxRetries:   config.MaxRetries,
+			TLSConfig:    config.TLSConfig,
+		})
+	}
+}
+
+// pubsubNode is a node to subscribe on for changes.
+type pubsubNode struct {
+	client redis.UniversalClient
+	addr   string
+}
+
+// keyspaceNodes returns the nodes publishing keyspace notifications of the
+// key, ordered by address. Notifications are node-local, so in a cluster it is
+// the master owning the key's slot, or every master for a key pattern; with
+// Sentinel it is the current master.
+func (r *RedisReader) keyspaceNodes(ctx context.Context) ([]pubsubNode, error) {
+	switch client := r.client.(type) {
+	case *redis.ClusterClient:
+		if r.config.Source != SourceScan {
+			node, err := client.MasterForKey(ctx, r.config.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find master of key '%s': %w", r.config.Key, err)
+			}
+			return []pubsubNode{{client: node, addr: node.Options().Addr}}, nil
+		}
+
+		var mu sync.Mutex
+		var nodes []pubsubNode
+		err := client.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			mu.Lock()
+			defer mu.Unlock()
+			nodes = append(nodes, pubsubNode{client: node, addr: node.Options().Addr})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cluster masters: %w", err)
+		}
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].addr < nodes[j].addr })
+		return nodes, nil
+	default:
+		if r.config.Topology != TopologySentinel {
+			return []pubsubNode{{client: r.client, addr: r.config.Addr}}, nil
+		}
+		addr, err := r.sentinelMaster(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []pubsubNode{{client: r.client, addr: addr}}, nil
+	}
+}
+
+// nodeAddrs identifies a set of nodes by their addresses.
+func nodeAddrs(nodes []pubsubNode) string {
+	addrs := make([]string, len(nodes))
+	for i, node := range nodes {
+		addrs[i] = node.addr
+	}
+	return strings.Join(addrs, ",")
+}
+
+// sentinelMaster asks the sentinels for the current master address.
+func (r *RedisReader) sentinelMaster(ctx context.Context) (string, error) {
+	var lastErr error
+	for _, addr := range r.config.Addrs {
+		sentinel := redis.NewSentinelClient(&redis.Options{
+			Addr:        addr,
+			Password:    r.config.SentinelPassword,
+			DialTimeout: r.config.Timeout,
+			ReadTimeout: r.config.Timeout,
+			TLSConfig:   r.config.TLSConfig,
+			MaxRetries:  -1,
+		})
+		master, err := sentinel.GetMasterAddrByName(ctx, r.config.MasterName).Result()
+		_ = sentinel.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return net.JoinHostPort(master[0], master[1]), nil
+	}
+	return "", fmt.Errorf("failed to get master '%s' from sentinels: %w", r.config.MasterName, lastErr)
+}
@@ -0,0 +1,297 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/sower-proxy/feconf/reader"
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+func TestParseTopology(t *testing.T) {
+	tests := []struct {
+		name       string
+		uri        string
+		topology   Topology
+		addrs      []string
+		masterName string
+		wantErr    bool
+	}{
+		{
+			name:     "standalone",
+			uri:      "redis://localhost:6379/config:app",
+			topology: TopologyStandalone,
+			addrs:    []string{"localhost:6379"},
+		},
+		{
+			name:       "sentinel with default ports",
+			uri:        "redis+sentinel://:secret@s1,s2:26380/config:app?master=mymaster",
+			topology:   TopologySentinel,
+			addrs:      []string{"s1:26379", "s2:26380"},
+			masterName: "mymaster",
+		},
+		{
+			name:     "cluster seeds",
+			uri:      "rediss+cluster://n1:7000,n2:7001,n3/config:app",
+			topology: TopologyCluster,
+			addrs:    []string{"n1:7000", "n2:7001", "n3:6379"},
+		},
+		{
+			name:    "sentinel without master",
+			uri:     "redis+sentinel://s1:26379/config:app",
+			wantErr: true,
+		},
+		{
+			name:    "cluster with db",
+			uri:     "redis+cluster://n1:7000/config:app?db=1",
+			wantErr: true,
+		},
+		{
+			name:    "standalone with several hosts",
+			uri:     "redis://n1:6379,n2:6379/config:app",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := reader.ParseURI(tt.uri)
+			if err != nil {
+				t.Fatalf("ParseURI() error = %v", err)
+			}
+
+			config := &RedisConfig{}
+			err = parseRedisURI(u, config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRedisURI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.Topology != tt.topology {
+				t.Errorf("Topology = %v, want %v", config.Topology, tt.topology)
+			}
+			if fmt.Sprint(config.Addrs) != fmt.Sprint(tt.addrs) {
+				t.Errorf("Addrs = %v, want %v", config.Addrs, tt.addrs)
+			}
+			if config.MasterName != tt.masterName {
+				t.Errorf("MasterName = %v, want %v", config.MasterName, tt.masterName)
+			}
+		})
+	}
+}
+
+func TestRedisReaderClusterSubscribe(t *testing.T) {
+	s := miniredis.RunT(t)
+	notifyConfig := withConfigCommand(t, s)
+	_ = s.Set("config:app", "v1")
+
+	rr, err := NewRedisReader(fmt.Sprintf("redis+cluster://%s/config:app", s.Addr()))
+	if err != nil {
+		t.Fatalf("NewRedisReader() error = %v", err)
+	}
+	defer rr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := rr.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	readertest.ExpectData(t, events, "v1")
+	if got := notifyConfig.value(); !strings.Contains(got, "K") {
+		t.Errorf("notify-keyspace-events = %q, want keyspace events enabled", got)
+	}
+
+	_ = s.Set("config:app", "v2")
+	s.Publish("__keyspace@0__:config:app", "set")
+	readertest.ExpectData(t, events, "v2")
+}
+
+func TestRedisReaderSentinelFailover(t *testing.T) {
+	master1 := miniredis.RunT(t)
+	master2 := miniredis.RunT(t)
+	config1 := withConfigCommand(t, master1)
+	config2 := withConfigCommand(t, master2)
+	_ = master1.Set("config:app", "v1")
+
+	sentinel := newSentinelStub(t, "mymaster", master1)
+
+	rr, err := NewRedisReader(fmt.Sprintf("redis+sentinel://%s/config:app?master=mymaster&health_interval=100ms&retry_delay=50ms", sentinel.Addr()))
+	if err != nil {
+		t.Fatalf("NewRedisReader() error = %v", err)
+	}
+	defer rr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := rr.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	readertest.ExpectData(t, events, "v1")
+	if got := config1.value(); !strings.Contains(got, "K") {
+		t.Errorf("master1 notify-keyspace-events = %q, want keyspace events enabled", got)
+	}
+
+	// Promote master2 and take master1 down
+	_ = master2.Set("config:app", "v2")
+	sentinel.failover(master1, master2)
+	master1.Close()
+
+	// The value changed during failover is read after resubscribing
+	readertest.ExpectData(t, events, "v2")
+	if got := config2.value(); !strings.Contains(got, "K") {
+		t.Errorf("master2 notify-keyspace-events = %q, want keyspace events enabled", got)
+	}
+
+	_ = master2.Set("config:app", "v3")
+	master2.Publish("__keyspace@0__:config:app", "set")
+	readertest.ExpectData(t, events, "v3")
+}
+
+// Without CONFIG, as on managed Redis, no keyspace events are delivered, so
+// cluster and sentinel readers must pick up changes by version polling.
+func TestRedisReaderTopologyPollingFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  func(s *miniredis.Miniredis) string
+	}{
+		{
+			name: "cluster",
+			uri: func(s *miniredis.Miniredis) string {
+				return fmt.Sprintf("redis+cluster://%s/config:app?poll_interval=20ms", s.Addr())
+			},
+		},
+		{
+			name: "sentinel",
+			uri: func(s *miniredis.Miniredis) string {
+				sentinel := newSentinelStub(t, "mymaster", s)
+				return fmt.Sprintf("redis+sentinel://%s/config:app?master=mymaster&poll_interval=20ms", sentinel.Addr())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			_ = s.Set("config:app", "v1")
+			_ = s.Set("config:app:version", "1")
+
+			rr, err := NewRedisReader(tt.uri(s))
+			if err != nil {
+				t.Fatalf("NewRedisReader() error = %v", err)
+			}
+			defer rr.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			mode, err := rr.watchMode(ctx)
+			if err != nil {
+				t.Fatalf("watchMode() error = %v", err)
+			}
+			if mode != WatchVersion {
+				t.Fatalf("watchMode() = %v, want %v", mode, WatchVersion)
+			}
+
+			events, err := rr.Subscribe(ctx)
+			if err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+			readertest.ExpectData(t, events, "v1")
+
+			// No keyspace event is published; the change is found by polling
+			_ = s.Set("config:app", "v2")
+			_ = s.Set("config:app:version", "2")
+			readertest.ExpectData(t, events, "v2")
+		})
+	}
+}
+
+// configStub serves the CONFIG GET/SET subset used for keyspace
+// notifications, which miniredis lacks.
+type configStub struct {
+	mu           sync.Mutex
+	notifyEvents string
+}
+
+func withConfigCommand(t *testing.T, s *miniredis.Miniredis) *configStub {
+	t.Helper()
+	stub := &configStub{}
+	err := s.Server().Register("CONFIG", func(c *server.Peer, cmd string, args []string) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+
+		switch {
+		case len(args) == 2 && strings.EqualFold(args[0], "GET"):
+			c.WriteStrings([]string{"notify-keyspace-events", stub.notifyEvents})
+		case len(args) == 3 && strings.EqualFold(args[0], "SET"):
+			stub.notifyEvents = args[2]
+			c.WriteOK()
+		default:
+			c.WriteError("ERR unsupported CONFIG command")
+		}
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return stub
+}
+
+func (s *configStub) value() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notifyEvents
+}
+
+// sentinelStub is a miniredis answering the SENTINEL queries of the failover
+// client for a single master.
+type sentinelStub struct {
+	*miniredis.Miniredis
+
+	mu         sync.Mutex
+	masterName string
+	master     *miniredis.Miniredis
+}
+
+func newSentinelStub(t *testing.T, masterName string, master *miniredis.Miniredis) *sentinelStub {
+	t.Helper()
+	stub := &sentinelStub{Miniredis: miniredis.RunT(t), masterName: masterName, master: master}
+	err := stub.Server().Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+
+		switch {
+		case len(args) == 2 && strings.EqualFold(args[0], "get-master-addr-by-name") && args[1] == stub.masterName:
+			c.WriteStrings([]string{stub.master.Host(), stub.master.Port()})
+		case len(args) == 2 && strings.EqualFold(args[0], "get-master-addr-by-name"):
+			c.WriteNull()
+		case len(args) == 2 && strings.EqualFold(args[0], "sentinels"):
+			c.WriteLen(0)
+		default:
+			c.WriteError("ERR unsupported SENTINEL command")
+		}
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return stub
+}
+
+// failover promotes master and announces it like a sentinel does.
+func (s *sentinelStub) failover(from, to *miniredis.Miniredis) {
+	s.mu.Lock()
+	s.master = to
+	s.mu.Unlock()
+
+	s.Publish("+switch-master", strings.Join([]string{s.masterName, from.Host(), from.Port(), to.Host(), to.Port()}, " "))
+}
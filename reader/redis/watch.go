@@ -0,0 +1,213 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sower-proxy/feconf/reader"
+)
+
+// DefaultPollInterval between version key reads, also the longest a stream
+// read blocks
+var DefaultPollInterval = 5 * time.Second
+
+// WatchMode is how a Redis reader detects changes of its key.
+type WatchMode string
+
+const (
+	// WatchAuto uses keyspace notifications, falling back to another mode when
+	// CONFIG is denied
+	WatchAuto WatchMode = "auto"
+	// WatchKeyspace subscribes to keyspace notifications of the key
+	WatchKeyspace WatchMode = "keyspace"
+	// WatchChannel subscribes to a channel the publisher announces changes on
+	WatchChannel WatchMode = "channel"
+	// WatchVersion polls a version key the publisher bumps on changes
+	WatchVersion WatchMode = "version"
+	// WatchStream reads a stream the publisher appends an entry to on changes
+	WatchStream WatchMode = "stream"
+)
+
+// parseWatchMode sets the watch mode of config and the names of its channel,
+// version key, checksum key and stream, which default to the key with a
+// :updates, :version, :checksum or :stream suffix.
+func parseWatchMode(query url.Values, config *RedisConfig) error {
+	config.Watch = WatchAuto
+	if watch := query.Get("watch"); watch != "" {
+		config.Watch = WatchMode(watch)
+	}
+	switch config.Watch {
+	case WatchAuto, WatchKeyspace, WatchChannel, WatchVersion, WatchStream:
+	default:
+		return fmt.Errorf("invalid watch mode %q, expected: auto, keyspace, channel, version or stream", config.Watch)
+	}
+
+	config.Channel = query.Get("channel")
+	config.VersionKey = query.Get("version_key")
+	config.Stream = query.Get("stream")
+	if config.Watch == WatchChannel && config.Channel == "" {
+		config.Channel = config.Key + ":updates"
+	}
+	if config.VersionKey == "" {
+		config.VersionKey = config.Key + ":version"
+	}
+	config.ChecksumKey = query.Get("checksum_key")
+	if config.ChecksumKey == "" {
+		config.ChecksumKey = config.Key + ":checksum"
+	}
+	if config.Watch == WatchStream && config.Stream == "" {
+		config.Stream = config.Key + ":stream"
+	}
+
+	var err error
+	config.PollInterval, err = reader.ParseDuration(query, "poll_interval", config.PollInterval)
+	return err
+}
+
+// watchMode resolves the watch mode to subscribe with. Keyspace notifications
+// are enabled when needed. If CONFIG is denied, as on most managed Redis, auto
+// mode falls back to the configured channel or stream, or to version polling,
+// while keyspace mode assumes notifications were enabled by the operator.
+func (r *RedisReader) watchMode(ctx context.Context) (WatchMode, error) {
+	mode := r.config.Watch
+	if mode != WatchAuto && mode != WatchKeyspace {
+		return mode, nil
+	}
+
+	nodes, err := r.keyspaceNodes(ctx)
+	for _, node := range nodes {
+		if err = r.ensureKeyspaceNotifications(ctx, node.client); err != nil {
+			break
+		}
+	}
+	switch {
+	case err == nil:
+		return WatchKeyspace, nil
+	case !isConfigDenied(err):
+		return "", fmt.Errorf("failed to enable keyspace notifications: %w", err)
+	case mode == WatchKeyspace:
+		return WatchKeyspace, nil
+	case r.config.Channel != "":
+		return WatchChannel, nil
+	case r.config.Stream != "":
+		return WatchStream, nil
+	default:
+		return WatchVersion, nil
+	}
+}
+
+// isConfigDenied reports whether err is a server reply rejecting CONFIG,
+// e.g. an unknown or renamed command, or a missing ACL permission. Other
+// errors, such as a failing connection, are not a reason to fall back.
+func isConfigDenied(err error) bool {
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) || errors.Is(err, redis.Nil) {
+		return false
+	}
+	message := redisErr.Error()
+	switch {
+	case strings.HasPrefix(message, "NOPERM"):
+		return true
+	case strings.Contains(strings.ToLower(message), "unknown command"):
+		return true
+	default:
+		return strings.HasPrefix(message, "ERR") && strings.Contains(strings.ToUpper(message), "CONFIG")
+	}
+}
+
+// pollVersion reads the version key every poll interval and reads the key when
+// the version changes. Without a version key the value itself is compared.
+func (s *subscription) pollVersion(ctx context.Context) (bool, error) {
+	r := s.reader
+	version, err := r.version(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !s.sync(ctx) {
+		return true, ctx.Err()
+	}
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-ticker.C:
+		}
+
+		current, err := r.version(ctx)
+		if err != nil {
+			return true, err
+		}
+		if current != "" && current == version {
+			continue
+		}
+		version = current
+		if !s.sync(ctx) {
+			return true, ctx.Err()
+		}
+	}
+}
+
+// version returns the content of the version key, or "" if it does not exist.
+func (r *RedisReader) version(ctx context.Context) (string, error) {
+	version, err := r.client.Get(ctx, r.config.VersionKey).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to GET version key '%s': %w", r.config.VersionKey, err)
+	}
+	return version, nil
+}
+
+// readStream blocks on the stream and reads the key for every batch of new
+// entries. The last seen entry ID is kept across reconnects, so entries added
+// meanwhile are not missed.
+func (s *subscription) readStream(ctx context.Context) (bool, error) {
+	r := s.reader
+	if s.streamID == "" {
+		last, err := r.client.XRevRangeN(ctx, r.config.Stream, "+", "-", 1).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to read stream '%s': %w", r.config.Stream, err)
+		}
+		s.streamID = "0-0"
+		if len(last) > 0 {
+			s.streamID = last[0].ID
+		}
+	}
+	if !s.sync(ctx) {
+		return true, ctx.Err()
+	}
+
+	for {
+		streams, err := r.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{r.config.Stream, s.streamID},
+			Block:   r.config.PollInterval,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return true, ctx.Err()
+			}
+			return true, fmt.Errorf("failed to read stream '%s': %w", r.config.Stream, err)
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				s.streamID = message.ID
+			}
+		}
+		if !s.notify(ctx) {
+			return true, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redis/v8"
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+func TestParseWatchMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    RedisConfig
+		wantErr bool
+	}{
+		{
+			name: "default",
+			uri:  "redis://localhost:6379/config:app",
+			want: RedisConfig{Watch: WatchAuto, VersionKey: "config:app:version"},
+		},
+		{
+			name: "channel with default name",
+			uri:  "redis://localhost:6379/config:app?watch=channel",
+			want: RedisConfig{Watch: WatchChannel, Channel: "config:app:updates", VersionKey: "config:app:version"},
+		},
+		{
+			name: "stream and version key",
+			uri:  "redis://localhost:6379/config:app?watch=stream&stream=changes&version_key=rev",
+			want: RedisConfig{Watch: WatchStream, Stream: "changes", VersionKey: "rev"},
+		},
+		{
+			name:    "unknown mode",
+			uri:     "redis://localhost:6379/config:app?watch=polling",
+			wantErr: true,
+		},
+		{
+			name:    "invalid poll interval",
+			uri:     "redis://localhost:6379/config:app?watch=version&poll_interval=0s",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, err := NewRedisReader(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRedisReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer rr.Close()
+
+			config := rr.config
+			if config.Watch != tt.want.Watch {
+				t.Errorf("Watch = %v, want %v", config.Watch, tt.want.Watch)
+			}
+			if config.Channel != tt.want.Channel {
+				t.Errorf("Channel = %v, want %v", config.Channel, tt.want.Channel)
+			}
+			if config.VersionKey != tt.want.VersionKey {
+				t.Errorf("VersionKey = %v, want %v", config.VersionKey, tt.want.VersionKey)
+			}
+			if config.Stream != tt.want.Stream {
+				t.Errorf("Stream = %v, want %v", config.Stream, tt.want.Stream)
+			}
+		})
+	}
+}
+
+// replyError is a Redis error reply.
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+
+func (replyError) RedisError() {}
+
+func TestIsConfigDenied(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"missing ACL permission", replyError("NOPERM User app has no permissions to run the 'config|set' command"), true},
+		{"unknown command", replyError("ERR unknown command `CONFIG`, with args beginning with: `GET`, "), true},
+		{"renamed command", replyError("ERR unknown command 'config'"), true},
+		{"config rejected", replyError("ERR CONFIG SET failed (possibly related to argument 'notify-keyspace-events')"), true},
+		{"wrapped denial", fmt.Errorf("enable notifications: %w", replyError("NOPERM no permissions")), true},
+		{"other reply", replyError("LOADING Redis is loading the dataset in memory"), false},
+		{"unrelated ERR", replyError("ERR max number of clients reached"), false},
+		{"nil reply", redis.Nil, false},
+		{"connection error", errors.New("dial tcp 127.0.0.1:6379: connect: connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConfigDenied(tt.err); got != tt.want {
+				t.Errorf("isConfigDenied(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisReaderWatchModeConfigError(t *testing.T) {
+	s := miniredis.RunT(t)
+	err := s.Server().Register("CONFIG", func(c *server.Peer, cmd string, args []string) {
+		c.WriteError("LOADING Redis is loading the dataset in memory")
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	rr, err := NewRedisReader(fmt.Sprintf("redis://%s/config:app", s.Addr()))
+	if err != nil {
+		t.Fatalf("NewRedisReader() error = %v", err)
+	}
+	defer rr.Close()
+
+	if mode, err := rr.watchMode(context.Background()); err == nil {
+		t.Errorf("watchMode() = %v, want error for a reply that is not a denial", mode)
+	}
+}
+
+// miniredis rejects CONFIG like managed Redis does, so auto mode falls back.
+func TestRedisReaderWatchFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		mode    WatchMode
+		publish func(s *miniredis.Miniredis)
+	}{
+		{
+			name:  "version polling",
+			query: "poll_interval=20ms",
+			mode:  WatchVersion,
+			publish: func(s *miniredis.Miniredis) {
+				_ = s.Set("config:app", "v2")
+				_ = s.Set("config:app:version", "2")
+			},
+		},
+		{
+			name:  "announcement channel",
+			query: "channel=config:app:updates",
+			mode:  WatchChannel,
+			publish: func(s *miniredis.Miniredis) {
+				_ = s.Set("config:app", "v2")
+				s.Publish("config:app:updates", "2")
+			},
+		},
+		{
+			name:  "stream",
+			query: "stream=config:app:stream&poll_interval=50ms",
+			mode:  WatchStream,
+			publish: func(s *miniredis.Miniredis) {
+				_ = s.Set("config:app", "v2")
+				_, _ = s.XAdd("config:app:stream", "*", []string{"version", "2"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			_ = s.Set("config:app", "v1")
+			_ = s.Set("config:app:version", "1")
+
+			rr, err := NewRedisReader(fmt.Sprintf("redis://%s/config:app?%s", s.Addr(), tt.query))
+			if err != nil {
+				t.Fatalf("NewRedisReader() error = %v", err)
+			}
+			defer rr.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			mode, err := rr.watchMode(ctx)
+			if err != nil {
+				t.Fatalf("watchMode() error = %v", err)
+			}
+			if mode != tt.mode {
+				t.Errorf("watchMode() = %v, want %v", mode, tt.mode)
+			}
+
+			events, err := rr.Subscribe(ctx)
+			if err != nil {
+				t.Fatalf("Subscribe() error = %v", err)
+			}
+			readertest.ExpectData(t, events, "v1")
+
+			// Let channel and stream subscriptions settle before publishing
+			time.Sleep(50 * time.Millisecond)
+			tt.publish(s)
+			readertest.ExpectData(t, events, "v2")
+		})
+	}
+}
+
+func TestRedisReaderVersionPollingWithoutVersionKey(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("config:app", "v1")
+
+	rr, err := NewRedisReader(fmt.Sprintf("redis://%s/config:app?watch=version&poll_interval=20ms", s.Addr()))
+	if err != nil {
+		t.Fatalf("NewRedisReader() error = %v", err)
+	}
+	defer rr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := rr.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	readertest.ExpectData(t, events, "v1")
+
+	_ = s.Set("config:app", "v2")
+	readertest.ExpectData(t, events, "v2")
+
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event for unchanged value: %s", string(event.Data))
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRedisReaderVersionPollingReportsErrors(t *testing.T) {
+	s := miniredis.RunT(t)
+	_ = s.Set("config:app", "v1")
+
+	rr, err := NewRedisReader(fmt.Sprintf("redis://%s/config:app?watch=version&poll_interval=20ms", s.Addr()))
+	if err != nil {
+		t.Fatalf("NewRedisReader() error = %v", err)
+	}
+	defer rr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := rr.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	readertest.ExpectData(t, events, "v1")
+
+	// A deleted key and a value of the wrong type are reported once each
+	s.Del("config:app")
+	readertest.ExpectError(t, events)
+	_, _ = s.Lpush("config:app", "v2")
+	readertest.ExpectError(t, events)
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event for unchanged error: %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.Del("config:app")
+	_ = s.Set("config:app", "v3")
+	for {
+		event := readertest.NextEvent(t, events)
+		if event.Error == nil {
+			if string(event.Data) != "v3" {
+				t.Errorf("event data = %s, want v3", string(event.Data))
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Resolver resolves a reference found in configuration values, e.g. the
+// secret/data/db#password of ${vault:secret/data/db#password}, to its value
+type Resolver func(ctx context.Context, ref string) (string, error)
+
+// resolverMap maps reference names to resolvers
+var resolverMap sync.Map
+
+// RegisterResolver registers the resolver of ${name:ref} references
+func RegisterResolver(name string, resolver Resolver) error {
+	if resolver == nil {
+		return fmt.Errorf("nil resolver")
+	}
+
+	_, loaded := resolverMap.LoadOrStore(name, resolver)
+	if loaded {
+		return fmt.Errorf("resolver \"%s\" already registered", name)
+	}
+
+	return nil
+}
+
+// GetResolver returns the resolver registered for name
+func GetResolver(name string) (Resolver, bool) {
+	value, exists := resolverMap.Load(name)
+	if !exists {
+		return nil, false
+	}
+	resolver, ok := value.(Resolver)
+	return resolver, ok
+}
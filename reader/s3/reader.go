@@ -0,0 +1,374 @@
+// Package s3 provides a configuration reader implementation for objects in
+// Amazon S3 and S3-compatible stores such as MinIO. It subscribes to changes by
+// polling the object with its ETag.
+package s3
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sower-proxy/feconf/reader"
+)
+
+const (
+	// SchemeS3 represents S3 URI scheme
+	SchemeS3 reader.Scheme = "s3"
+
+	// DefaultRegion is used when neither the URI nor the AWS configuration
+	// sets a region, e.g. for MinIO
+	DefaultRegion = "us-east-1"
+)
+
+var (
+	// DefaultTimeout for S3 requests
+	DefaultTimeout = 10 * time.Second
+	// DefaultPollInterval between checks of the object ETag
+	DefaultPollInterval = 30 * time.Second
+)
+
+// init registers the S3 reader
+func init() {
+	_ = reader.RegisterReader(SchemeS3, func(uri string) (reader.ConfReader, error) {
+		return NewS3Reader(uri)
+	})
+}
+
+// S3Config holds S3 reader configuration
+type S3Config struct {
+	Bucket       string
+	Key          string
+	VersionID    string // Pins the object to a version, which never changes
+	Region       string // Region, or empty for the AWS configuration
+	Endpoint     string // Endpoint of an S3-compatible store, e.g. http://minio:9000
+	PathStyle    bool   // Address the bucket in the path instead of the host
+	Profile      string // Profile of the shared AWS configuration files
+	TLSInsecure  bool
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// S3Reader implements ConfReader for configuration objects in S3
+type S3Reader struct {
+	uri         string
+	config      *S3Config
+	client      *s3.Client
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+	mu          sync.RWMutex
+	closed      bool
+}
+
+// object is a configuration object read from S3
+type object struct {
+	data      []byte
+	meta      reader.ContentMeta
+	etag      string
+	versionID string
+}
+
+// NewS3Reader creates a new S3 reader.
+// URI format: s3://bucket/path/to/key[?region=eu-west-1&endpoint=minio:9000&server_scheme=http&version_id=...]
+// Credentials are resolved by the AWS SDK default chain: environment
+// variables, web identity tokens, the shared configuration files of profile,
+// and container or instance roles.
+func NewS3Reader(uri string) (*S3Reader, error) {
+	u, err := reader.ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != string(SchemeS3) {
+		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s", u.Scheme, SchemeS3)
+	}
+
+	config, err := parseS3URI(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse S3 URI: %w", err)
+	}
+
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	closeCtx, closeCancel := context.WithCancel(context.Background())
+	return &S3Reader{
+		uri:         uri,
+		config:      config,
+		client:      client,
+		closeCtx:    closeCtx,
+		closeCancel: closeCancel,
+	}, nil
+}
+
+func parseS3URI(u *url.URL) (*S3Config, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("bucket must be specified in host")
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("key must be specified in path")
+	}
+
+	query := u.Query()
+	config := &S3Config{
+		Bucket:      u.Host,
+		Key:         key,
+		VersionID:   query.Get("version_id"),
+		Region:      query.Get("region"),
+		Profile:     query.Get("profile"),
+		TLSInsecure: query.Get("tls_insecure") == "true",
+	}
+
+	if endpoint := query.Get("endpoint"); endpoint != "" {
+		if !strings.Contains(endpoint, "://") {
+			serverScheme := query.Get("server_scheme")
+			if serverScheme == "" {
+				serverScheme = "https"
+			}
+			if serverScheme != "http" && serverScheme != "https" {
+				return nil, fmt.Errorf("unsupported server_scheme: %s", serverScheme)
+			}
+			endpoint = serverScheme + "://" + endpoint
+		}
+		if _, err := url.Parse(endpoint); err != nil {
+			return nil, fmt.Errorf("invalid endpoint: %w", err)
+		}
+		config.Endpoint = endpoint
+		// Most S3-compatible stores do not resolve buckets as subdomains
+		config.PathStyle = true
+	}
+
+	if pathStyleStr := query.Get("path_style"); pathStyleStr != "" {
+		pathStyle, err := strconv.ParseBool(pathStyleStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path_style format: %w", err)
+		}
+		config.PathStyle = pathStyle
+	}
+
+	var err error
+	if config.Timeout, err = reader.ParseDuration(query, "timeout", DefaultTimeout); err != nil {
+		return nil, err
+	}
+	if config.PollInterval, err = reader.ParseDuration(query, "poll_interval", DefaultPollInterval); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// newClient creates an S3 client with the AWS SDK default configuration,
+// overridden by the URI
+func newClient(config *S3Config) (*s3.Client, error) {
+	httpClient := awshttp.NewBuildableClient().WithTimeout(config.Timeout)
+	if config.TLSInsecure {
+		httpClient = httpClient.WithTransportOptions(func(transport *http.Transport) {
+			transport.TLSClientConfig = &tls.Config{
+				MinVersion:         tls.VersionTLS12,
+				InsecureSkipVerify: true,
+			}
+		})
+	}
+
+	options := []func(*awsconfig.LoadOptions) error{awsconfig.WithHTTPClient(httpClient)}
+	if config.Region != "" {
+		options = append(options, awsconfig.WithRegion(config.Region))
+	}
+	if config.Profile != "" {
+		options = append(options, awsconfig.WithSharedConfigProfile(config.Profile))
+	}
+
+	// Loading reads the environment and shared files, credentials are
+	// retrieved on the first request
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	if awsCfg.Region == "" {
+		awsCfg.Region = DefaultRegion
+	}
+
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+		o.UsePathStyle = config.PathStyle
+	}), nil
+}
+
+// Read reads configuration data from S3
+func (s *S3Reader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := s.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads configuration data and reports the Content-Type of the
+// object and the extension of its key
+func (s *S3Reader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
+	}
+
+	obj, err := s.get(ctx, "")
+	if err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
+	return obj.data, obj.meta, nil
+}
+
+// Subscribe reads the object and then polls it with If-None-Match, reporting
+// it whenever its ETag changes. A pinned version never changes and is not
+// polled.
+func (s *S3Reader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	obj, err := s.get(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	eventChan := make(chan *reader.ReadEvent, 1)
+	ctx, cancel := reader.WithClose(ctx, s.closeCtx)
+	go func() {
+		defer cancel()
+		s.watch(ctx, eventChan, obj)
+	}()
+
+	return eventChan, nil
+}
+
+// Close closes the reader and cleans up resources
+func (s *S3Reader) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+	s.closeCancel()
+	return nil
+}
+
+// watch reports the current object, then polls it every poll interval with
+// the ETag of the last object read. An unchanged object is reported again
+// only when an error was reported since it was read.
+func (s *S3Reader) watch(ctx context.Context, eventChan chan<- *reader.ReadEvent, obj *object) {
+	defer close(eventChan)
+
+	reporter := reader.NewReporter(eventChan)
+	if !reporter.Report(ctx, s.event(obj)) {
+		return
+	}
+
+	if s.config.VersionID != "" {
+		<-ctx.Done()
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.config.PollInterval):
+		}
+
+		next, err := s.get(ctx, obj.etag)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			reporter.Report(ctx, reader.NewReadEvent(s.uri, nil, err))
+			continue
+		}
+		if next != nil {
+			obj = next
+		}
+		reporter.Report(ctx, s.event(obj))
+	}
+}
+
+// get reads the object, or the pinned version of it. With an etag, nil is
+// returned while the object still has that ETag.
+func (s *S3Reader) get(ctx context.Context, etag string) (*object, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.config.Key),
+	}
+	if s.config.VersionID != "" {
+		input.VersionId = aws.String(s.config.VersionID)
+	}
+	if etag != "" {
+		input.IfNoneMatch = aws.String(etag)
+	}
+
+	output, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		var respErr *awshttp.ResponseError
+		var noSuchKey *types.NoSuchKey
+		switch {
+		case errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified:
+			return nil, nil
+		case errors.As(err, &noSuchKey):
+			return nil, fmt.Errorf("object 's3://%s/%s' not found", s.config.Bucket, s.config.Key)
+		default:
+			return nil, fmt.Errorf("failed to get object 's3://%s/%s': %w", s.config.Bucket, s.config.Key, err)
+		}
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object 's3://%s/%s': %w", s.config.Bucket, s.config.Key, err)
+	}
+
+	meta := reader.ParseContentType(aws.ToString(output.ContentType))
+	meta.Extension = filepath.Ext(s.config.Key)
+	return &object{
+		data:      data,
+		meta:      meta,
+		etag:      aws.ToString(output.ETag),
+		versionID: aws.ToString(output.VersionId),
+	}, nil
+}
+
+// event builds a read event of the object, with its version ID as revision,
+// or its ETag in buckets without versioning
+func (s *S3Reader) event(obj *object) *reader.ReadEvent {
+	revision := obj.versionID
+	if revision == "" || revision == "null" {
+		revision = strings.Trim(obj.etag, `"`)
+	}
+	return reader.NewReadEvent(s.uri, obj.data, nil).
+		WithMeta(obj.meta).
+		WithRevision(revision)
+}
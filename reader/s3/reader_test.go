@@ -0,0 +1,460 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+// fakeS3 is a stand-in for the S3 GetObject API of a versioned bucket and
+// the STS web identity API
+type fakeS3 struct {
+	*httptest.Server
+	t            *testing.T
+	mu           sync.Mutex
+	objects      map[string][]version
+	requests     []*http.Request
+	webIdentity  []string
+	stsAccessKey string
+}
+
+// version is a version of an object
+type version struct {
+	id          string
+	data        []byte
+	contentType string
+}
+
+func (v version) etag() string {
+	sum := md5.Sum(v.data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	t.Helper()
+
+	f := &fakeS3{t: t, objects: make(map[string][]version), stsAccessKey: "ASIAWEBIDENTITY"}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeS3) uri(path, query string) string {
+	return "s3://" + path + "?endpoint=" + strings.TrimPrefix(f.URL, "http://") + "&server_scheme=http&" + query
+}
+
+func (f *fakeS3) put(path, data, contentType string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := "v" + strconv.Itoa(len(f.objects[path])+1)
+	f.objects[path] = append(f.objects[path], version{id: id, data: []byte(data), contentType: contentType})
+	return id
+}
+
+func (f *fakeS3) delete(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.objects, path)
+}
+
+// received returns the requests received for the object path
+func (f *fakeS3) received(path string) []*http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var requests []*http.Request
+	for _, r := range f.requests {
+		if r.URL.Path == "/"+path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+func (f *fakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && r.URL.Path == "/" {
+		f.handleSTS(w, r)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r)
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") {
+		writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	versions := f.objects[strings.TrimPrefix(r.URL.Path, "/")]
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	current := versions[len(versions)-1]
+	if id := r.URL.Query().Get("versionId"); id != "" {
+		found := false
+		for _, v := range versions {
+			if v.id == id {
+				current, found = v, true
+			}
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, "NoSuchVersion")
+			return
+		}
+	}
+
+	w.Header().Set("ETag", current.etag())
+	w.Header().Set("x-amz-version-id", current.id)
+	if r.Header.Get("If-None-Match") == current.etag() {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if current.contentType != "" {
+		w.Header().Set("Content-Type", current.contentType)
+	}
+	_, _ = w.Write(current.data)
+}
+
+func (f *fakeS3) handleSTS(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "AssumeRoleWithWebIdentity" {
+		writeError(w, http.StatusBadRequest, "InvalidAction")
+		return
+	}
+
+	f.mu.Lock()
+	f.webIdentity = append(f.webIdentity, r.Form.Get("WebIdentityToken"))
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>%s</AccessKeyId>
+      <SecretAccessKey>web-secret</SecretAccessKey>
+      <SessionToken>web-session</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`, f.stsAccessKey, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// isolateAWS clears the AWS configuration of the environment and sets static
+// credentials when accessKey is set
+func isolateAWS(t *testing.T, accessKey string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_REGION", "AWS_DEFAULT_REGION", "AWS_ROLE_ARN", "AWS_WEB_IDENTITY_TOKEN_FILE",
+		"AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL_STS",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	if accessKey != "" {
+		t.Setenv("AWS_ACCESS_KEY_ID", accessKey)
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	}
+}
+
+func TestParseS3URI(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    S3Config
+		wantErr bool
+	}{
+		{
+			name: "aws",
+			uri:  "s3://configs/app/config.yaml?region=eu-west-1",
+			want: S3Config{Bucket: "configs", Key: "app/config.yaml", Region: "eu-west-1",
+				Timeout: DefaultTimeout, PollInterval: DefaultPollInterval},
+		},
+		{
+			name: "minio",
+			uri:  "s3://configs/app.json?endpoint=minio:9000&server_scheme=http&version_id=3&profile=ops&poll_interval=5s",
+			want: S3Config{Bucket: "configs", Key: "app.json", VersionID: "3", Endpoint: "http://minio:9000",
+				PathStyle: true, Profile: "ops", Timeout: DefaultTimeout, PollInterval: 5 * time.Second},
+		},
+		{
+			name: "endpoint URL with virtual hosts",
+			uri:  "s3://configs/app.json?endpoint=https://storage.example.com&path_style=false&timeout=3s",
+			want: S3Config{Bucket: "configs", Key: "app.json", Endpoint: "https://storage.example.com",
+				Timeout: 3 * time.Second, PollInterval: DefaultPollInterval},
+		},
+		{name: "missing key", uri: "s3://configs/", wantErr: true},
+		{name: "invalid path_style", uri: "s3://configs/app.json?path_style=maybe", wantErr: true},
+		{name: "invalid server_scheme", uri: "s3://configs/app.json?endpoint=minio&server_scheme=ftp", wantErr: true},
+		{name: "invalid poll_interval", uri: "s3://configs/app.json?poll_interval=0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := reader.ParseURI(tt.uri)
+			if err != nil {
+				t.Fatalf("Failed to parse URI: %v", err)
+			}
+			config, err := parseS3URI(u)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got config %+v", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse S3 URI: %v", err)
+			}
+			if !reflect.DeepEqual(*config, tt.want) {
+				t.Errorf("Expected config %+v, got %+v", tt.want, *config)
+			}
+		})
+	}
+}
+
+func TestS3ReaderRead(t *testing.T) {
+	isolateAWS(t, "AKIASTATIC")
+	server := newFakeS3(t)
+	server.put("configs/app/config.yaml", "name: app\n", "application/x-yaml")
+
+	r, err := NewS3Reader(server.uri("configs/app/config.yaml", "region=eu-west-1"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	data, meta, err := r.ReadMeta(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != "name: app\n" {
+		t.Errorf("Expected object data, got %q", data)
+	}
+	if meta.MIMEType != "application/x-yaml" || meta.Extension != ".yaml" {
+		t.Errorf("Expected YAML metadata, got %+v", meta)
+	}
+
+	requests := server.received("configs/app/config.yaml")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 path-style request, got %d", len(requests))
+	}
+	auth := requests[0].Header.Get("Authorization")
+	if !strings.Contains(auth, "Credential=AKIASTATIC/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+		t.Errorf("Expected request signed for eu-west-1, got %q", auth)
+	}
+
+	missing, err := NewS3Reader(server.uri("configs/missing.yaml", ""))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer missing.Close()
+	if _, err := missing.Read(t.Context()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Failed to close reader: %v", err)
+	}
+	if _, err := r.Read(t.Context()); err == nil {
+		t.Error("Expected error after close")
+	}
+}
+
+func TestS3ReaderReadVersion(t *testing.T) {
+	isolateAWS(t, "AKIASTATIC")
+	server := newFakeS3(t)
+	first := server.put("configs/app.json", `{"version":1}`, "application/json")
+	server.put("configs/app.json", `{"version":2}`, "application/json")
+
+	r, err := NewS3Reader(server.uri("configs/app.json", "version_id="+first+"&poll_interval=10ms"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	data, err := r.Read(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != `{"version":1}` {
+		t.Errorf("Expected pinned version, got %s", data)
+	}
+
+	events, err := r.Subscribe(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	event := readertest.NextEvent(t, events)
+	if string(event.Data) != `{"version":1}` || event.Revision != first {
+		t.Errorf("Expected pinned version %s, got %s at %s", first, event.Data, event.Revision)
+	}
+
+	// A pinned version is not polled
+	time.Sleep(50 * time.Millisecond)
+	if requests := server.received("configs/app.json"); len(requests) != 2 {
+		t.Errorf("Expected no polling of a pinned version, got %d requests", len(requests))
+	}
+}
+
+func TestS3ReaderSubscribe(t *testing.T) {
+	isolateAWS(t, "AKIASTATIC")
+	server := newFakeS3(t)
+	first := server.put("configs/app.yaml", "name: first\n", "")
+
+	r, err := NewS3Reader(server.uri("configs/app.yaml", "poll_interval=20ms"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	events, err := r.Subscribe(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	event := readertest.NextEvent(t, events)
+	if string(event.Data) != "name: first\n" || event.Revision != first {
+		t.Errorf("Expected first version, got %s at %s", event.Data, event.Revision)
+	}
+	if event.Meta.Extension != ".yaml" {
+		t.Errorf("Expected .yaml extension, got %+v", event.Meta)
+	}
+
+	second := server.put("configs/app.yaml", "name: second\n", "")
+	event = readertest.NextEvent(t, events)
+	if string(event.Data) != "name: second\n" || event.Revision != second {
+		t.Errorf("Expected second version, got %s at %s", event.Data, event.Revision)
+	}
+
+	var conditional bool
+	for _, req := range server.received("configs/app.yaml") {
+		if req.Header.Get("If-None-Match") != "" {
+			conditional = true
+		}
+	}
+	if !conditional {
+		t.Error("Expected polling with If-None-Match")
+	}
+
+	server.delete("configs/app.yaml")
+	event = readertest.NextEvent(t, events)
+	if event.Error == nil || !strings.Contains(event.Error.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", event.Error)
+	}
+
+	// The restored object has the same ETag, so it is not downloaded again
+	// but reported once to recover from the error
+	server.put("configs/app.yaml", "name: second\n", "")
+	readertest.ExpectData(t, events, "name: second\n")
+	readertest.ExpectNoEvent(t, events, 100*time.Millisecond)
+	requests := server.received("configs/app.yaml")
+	for _, req := range requests[1:] {
+		if req.Header.Get("If-None-Match") == "" {
+			t.Error("Expected only conditional polls after the first read")
+			break
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Failed to close reader: %v", err)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected event channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Timeout waiting for event channel to close")
+	}
+}
+
+func TestS3ReaderSharedCredentials(t *testing.T) {
+	isolateAWS(t, "")
+	server := newFakeS3(t)
+	server.put("configs/app.json", `{}`, "")
+
+	credentials := "[ops]\naws_access_key_id = AKIAPROFILE\naws_secret_access_key = secret\n"
+	if err := os.WriteFile(os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), []byte(credentials), 0o600); err != nil {
+		t.Fatalf("Failed to write credentials: %v", err)
+	}
+	if err := os.WriteFile(os.Getenv("AWS_CONFIG_FILE"), []byte("[profile ops]\nregion = ap-south-1\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	r, err := NewS3Reader(server.uri("configs/app.json", "profile=ops"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := r.Read(t.Context()); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	auth := server.received("configs/app.json")[0].Header.Get("Authorization")
+	if !strings.Contains(auth, "Credential=AKIAPROFILE/") || !strings.Contains(auth, "/ap-south-1/s3/") {
+		t.Errorf("Expected request signed with the ops profile, got %q", auth)
+	}
+}
+
+func TestS3ReaderWebIdentity(t *testing.T) {
+	isolateAWS(t, "")
+	server := newFakeS3(t)
+	server.put("configs/app.json", `{}`, "")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("service-account-jwt"), 0o600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/config-reader")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+
+	r, err := NewS3Reader(server.uri("configs/app.json", "region=us-west-2"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := r.Read(t.Context()); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+
+	server.mu.Lock()
+	webIdentity := server.webIdentity
+	server.mu.Unlock()
+	if !reflect.DeepEqual(webIdentity, []string{"service-account-jwt"}) {
+		t.Errorf("Expected role assumed with the token file, got %v", webIdentity)
+	}
+
+	req := server.received("configs/app.json")[0]
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "Credential="+server.stsAccessKey+"/") {
+		t.Errorf("Expected request signed with assumed role credentials, got %q", auth)
+	}
+	if token := req.Header.Get("X-Amz-Security-Token"); token != "web-session" {
+		t.Errorf("Expected session token, got %q", token)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Scheme represents protocol type
@@ -60,7 +61,10 @@ func ParseURI(uri string) (*url.URL, error) {
 
 	u, err := url.Parse(uri)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URI format: %w", err)
+		var ok bool
+		if u, ok = parseMultiHostURI(uri); !ok {
+			return nil, fmt.Errorf("invalid URI format: %w", err)
+		}
 	}
 
 	if _, exists := schemeReaderMap.Load(Scheme(u.Scheme)); !exists {
@@ -69,3 +73,81 @@ func ParseURI(uri string) (*url.URL, error) {
 
 	return u, nil
 }
+
+// parseMultiHostURI parses URIs whose authority lists several comma-separated
+// hosts, such as nacos://10.0.0.1:8848,10.0.0.2:8848/group/dataId, which
+// url.Parse rejects. The full host list is kept in the returned URL's Host.
+func parseMultiHostURI(uri string) (*url.URL, bool) {
+	schemeEnd := strings.Index(uri, "://")
+	if schemeEnd < 0 {
+		return nil, false
+	}
+	authorityStart := schemeEnd + len("://")
+	authorityEnd := len(uri)
+	if i := strings.IndexAny(uri[authorityStart:], "/?#"); i >= 0 {
+		authorityEnd = authorityStart + i
+	}
+
+	hostStart := authorityStart
+	if i := strings.LastIndex(uri[authorityStart:authorityEnd], "@"); i >= 0 {
+		hostStart = authorityStart + i + 1
+	}
+	hosts := uri[hostStart:authorityEnd]
+	first, _, found := strings.Cut(hosts, ",")
+	if !found {
+		return nil, false
+	}
+
+	u, err := url.Parse(uri[:hostStart] + first + uri[authorityEnd:])
+	if err != nil {
+		return nil, false
+	}
+	for _, host := range strings.Split(hosts, ",") {
+		if _, err := url.Parse("//" + host); err != nil {
+			return nil, false
+		}
+	}
+	u.Host = hosts
+	return u, true
+}
+
+// Hosts returns the comma-separated hosts of u, each with its optional port.
+func Hosts(u *url.URL) []string {
+	var hosts []string
+	for _, host := range strings.Split(u.Host, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// ParseDuration parses the duration of a query parameter, which must be
+// positive, returning defaultValue when it is not set
+func ParseDuration(query url.Values, key string, defaultValue time.Duration) (time.Duration, error) {
+	return parseDuration(query, key, defaultValue, false)
+}
+
+// ParseNonNegativeDuration parses the duration of a query parameter like
+// ParseDuration, but also accepts zero, e.g. to disable a timeout or delay
+func ParseNonNegativeDuration(query url.Values, key string, defaultValue time.Duration) (time.Duration, error) {
+	return parseDuration(query, key, defaultValue, true)
+}
+
+func parseDuration(query url.Values, key string, defaultValue time.Duration, allowZero bool) (time.Duration, error) {
+	value := query.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s format: %w", key, err)
+	}
+	if duration < 0 || (duration == 0 && !allowZero) {
+		if allowZero {
+			return 0, fmt.Errorf("%s must be non-negative", key)
+		}
+		return 0, fmt.Errorf("%s must be positive", key)
+	}
+	return duration, nil
+}
@@ -0,0 +1,46 @@
+package reader
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		allowZero bool
+		want      time.Duration
+		wantErr   bool
+	}{
+		{name: "default", want: time.Minute},
+		{name: "valid", value: "5s", want: 5 * time.Second},
+		{name: "invalid", value: "soon", wantErr: true},
+		{name: "zero", value: "0s", wantErr: true},
+		{name: "negative", value: "-1s", wantErr: true},
+		{name: "zero allowed", value: "0s", allowZero: true, want: 0},
+		{name: "negative with zero allowed", value: "-1s", allowZero: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			if tt.value != "" {
+				query.Set("timeout", tt.value)
+			}
+			parse := ParseDuration
+			if tt.allowZero {
+				parse = ParseNonNegativeDuration
+			}
+
+			got, err := parse(query, "timeout", time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package reader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MergeValues deep merges src into dst. Nested maps are merged key by key,
+// any other value in src replaces the one in dst.
+func MergeValues(dst, src map[string]any) map[string]any {
+	if dst == nil {
+		dst = make(map[string]any, len(src))
+	}
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			dst[key] = MergeValues(dstMap, srcMap)
+			continue
+		}
+		if srcIsMap {
+			value = MergeValues(nil, srcMap)
+		}
+		dst[key] = value
+	}
+	return dst
+}
+
+// ExpandPaths turns slash-separated key paths into nested maps, e.g. db/host
+// into {"db": {"host": ...}}. Empty segments are ignored. A path that is both
+// a value and a parent of other paths is an error.
+func ExpandPaths(flat map[string]string) (map[string]any, error) {
+	return expandKeys(flat, func(key string) []string {
+		var parts []string
+		for _, part := range strings.Split(key, "/") {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return parts
+	})
+}
+
+// ExpandDotted turns dotted keys into nested maps, e.g. db.host into
+// {"db": {"host": ...}}. A key that is both a value and a parent of other
+// keys is an error.
+func ExpandDotted(flat map[string]string) (map[string]any, error) {
+	return expandKeys(flat, func(key string) []string {
+		return strings.Split(key, ".")
+	})
+}
+
+// expandKeys nests the values of flat under the parts split from their keys,
+// in key order so conflicts are reported deterministically
+func expandKeys(flat map[string]string, split func(key string) []string) (map[string]any, error) {
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]any)
+	for _, key := range keys {
+		parts := split(key)
+		if len(parts) == 0 {
+			continue
+		}
+
+		current := values
+		for _, part := range parts[:len(parts)-1] {
+			next, exists := current[part]
+			if !exists {
+				child := make(map[string]any)
+				current[part] = child
+				current = child
+				continue
+			}
+			child, ok := next.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key '%s' conflicts with a value of its parent", key)
+			}
+			current = child
+		}
+
+		last := parts[len(parts)-1]
+		if _, exists := current[last]; exists {
+			return nil, fmt.Errorf("key '%s' conflicts with nested keys", key)
+		}
+		current[last] = flat[key]
+	}
+	return values, nil
+}
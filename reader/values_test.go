@@ -0,0 +1,94 @@
+package reader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		flat    map[string]string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "nested paths",
+			flat: map[string]string{"name": "app", "db/host": "localhost", "db//port": "5432", "/db/pool/size/": "10"},
+			want: map[string]any{
+				"name": "app",
+				"db": map[string]any{
+					"host": "localhost",
+					"port": "5432",
+					"pool": map[string]any{"size": "10"},
+				},
+			},
+		},
+		{
+			name: "empty path",
+			flat: map[string]string{"": "folder", "name": "app"},
+			want: map[string]any{"name": "app"},
+		},
+		{
+			name:    "value and parent",
+			flat:    map[string]string{"db": "postgres", "db/host": "localhost"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandPaths(tt.flat)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandDotted(t *testing.T) {
+	tests := []struct {
+		name    string
+		flat    map[string]string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "nested keys",
+			flat: map[string]string{"name": "app", "db.host": "localhost", "db.port": "5432", "db.pool.size": "10"},
+			want: map[string]any{
+				"name": "app",
+				"db": map[string]any{
+					"host": "localhost",
+					"port": "5432",
+					"pool": map[string]any{"size": "10"},
+				},
+			},
+		},
+		{
+			name:    "value and parent",
+			flat:    map[string]string{"db": "postgres", "db.host": "localhost"},
+			wantErr: true,
+		},
+		{
+			name:    "parent and value",
+			flat:    map[string]string{"db.host": "localhost", "db.host.name": "primary"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandDotted(tt.flat)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandDotted() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandDotted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tokenHeader     = "X-Vault-Token"
+	namespaceHeader = "X-Vault-Namespace"
+
+	renewSelfPath  = "auth/token/renew-self"
+	lookupSelfPath = "auth/token/lookup-self"
+	leaseRenewPath = "sys/leases/renew"
+)
+
+// apiResponse is a response of the Vault API
+type apiResponse struct {
+	LeaseID       string          `json:"lease_id"`
+	Renewable     bool            `json:"renewable"`
+	LeaseDuration int64           `json:"lease_duration"`
+	Data          json.RawMessage `json:"data"`
+	Auth          *authInfo       `json:"auth"`
+}
+
+// authInfo is the token issued by a login or renewal
+type authInfo struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int64  `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+// apiError is an error response of the Vault API
+type apiError struct {
+	status int
+	errors []string
+}
+
+func (e *apiError) Error() string {
+	if len(e.errors) == 0 {
+		return fmt.Sprintf("unexpected HTTP status %d", e.status)
+	}
+	return fmt.Sprintf("unexpected HTTP status %d: %s", e.status, strings.Join(e.errors, "; "))
+}
+
+// hasStatus reports whether err is an error response with the status
+func hasStatus(err error, status int) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.status == status
+}
+
+// client sends requests to the Vault API, logging in and renewing its token
+// in the background
+type client struct {
+	config      *VaultConfig
+	http        *http.Client
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+
+	mu        sync.Mutex
+	token     string
+	ttl       time.Duration
+	renewable bool
+	expiresAt time.Time
+	renewAt   time.Time
+	renewed   chan struct{}
+	renewOnce sync.Once
+}
+
+func newClient(config *VaultConfig) *client {
+	var tlsConfig *tls.Config
+	if config.TLSInsecure {
+		tlsConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true,
+		}
+	}
+
+	closeCtx, closeCancel := context.WithCancel(context.Background())
+	return &client{
+		config: config,
+		http: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		closeCtx:    closeCtx,
+		closeCancel: closeCancel,
+		renewed:     make(chan struct{}, 1),
+	}
+}
+
+func (c *client) close() {
+	c.closeCancel()
+	c.http.CloseIdleConnections()
+}
+
+// do sends a request with the current token. A 403 response to a token of a
+// login is retried once after logging in again.
+func (c *client) do(ctx context.Context, method, path string, payload any) (*apiResponse, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := c.currentToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.send(ctx, method, path, token, payload)
+		if hasStatus(err, http.StatusForbidden) && c.config.Auth != AuthToken && attempt == 0 {
+			c.invalidateToken(token)
+			continue
+		}
+		return resp, err
+	}
+}
+
+// send sends a request to the API path with token
+func (c *client) send(ctx context.Context, method, path, token string, payload any) (*apiResponse, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.Address+"/v1/"+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set(tokenHeader, token)
+	}
+	if c.config.Namespace != "" {
+		req.Header.Set(namespaceHeader, c.config.Namespace)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		var result struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(data, &result)
+		return nil, &apiError{status: resp.StatusCode, errors: result.Errors}
+	}
+
+	var result apiResponse
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode Vault response: %w", err)
+		}
+	}
+	return &result, nil
+}
+
+// currentToken returns a valid token, logging in when there is none or it
+// expired. Token files are read on every request, so that tokens rotated by
+// an agent are picked up.
+func (c *client) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config.Auth == AuthToken && c.config.TokenFile != "" {
+		data, err := os.ReadFile(c.config.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if c.token != "" && (c.expiresAt.IsZero() || time.Now().Before(c.expiresAt)) {
+		return c.token, nil
+	}
+
+	if c.config.Auth == AuthToken {
+		if c.config.Token == "" {
+			return "", fmt.Errorf("no Vault token configured")
+		}
+		if c.token == "" {
+			c.lookupSelf(ctx)
+			return c.token, nil
+		}
+		return "", fmt.Errorf("vault token expired")
+	}
+
+	if err := c.login(ctx); err != nil {
+		return "", err
+	}
+	return c.token, nil
+}
+
+// invalidateToken drops token so the next request logs in again, unless it
+// was already replaced
+func (c *client) invalidateToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == token {
+		c.token = ""
+	}
+}
+
+// lookupSelf sets the configured token and schedules its renewal when it is
+// renewable. Tokens that cannot be looked up are used without renewal.
+func (c *client) lookupSelf(ctx context.Context) {
+	c.token = c.config.Token
+
+	resp, err := c.send(ctx, http.MethodGet, lookupSelfPath, c.token, nil)
+	if err != nil {
+		return
+	}
+	var data struct {
+		TTL       int64 `json:"ttl"`
+		Renewable bool  `json:"renewable"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil || !data.Renewable || data.TTL <= 0 {
+		return
+	}
+	c.setToken(&authInfo{ClientToken: c.token, LeaseDuration: data.TTL, Renewable: true})
+}
+
+// login logs in with the AppRole or Kubernetes auth method
+func (c *client) login(ctx context.Context) error {
+	payload := map[string]string{}
+	switch c.config.Auth {
+	case AuthAppRole:
+		payload["role_id"] = c.config.RoleID
+		payload["secret_id"] = c.config.SecretID
+		if c.config.SecretIDFile != "" {
+			data, err := os.ReadFile(c.config.SecretIDFile)
+			if err != nil {
+				return fmt.Errorf("failed to read secret_id file: %w", err)
+			}
+			payload["secret_id"] = strings.TrimSpace(string(data))
+		}
+	case AuthKubernetes:
+		// Projected service account tokens are rotated, read it on every login
+		jwt, err := os.ReadFile(c.config.JWTFile)
+		if err != nil {
+			return fmt.Errorf("failed to read service account token: %w", err)
+		}
+		payload["role"] = c.config.Role
+		payload["jwt"] = strings.TrimSpace(string(jwt))
+	default:
+		return fmt.Errorf("unsupported auth method: %s", c.config.Auth)
+	}
+
+	resp, err := c.send(ctx, http.MethodPost, "auth/"+c.config.AuthMount+"/login", "", payload)
+	if err != nil {
+		return fmt.Errorf("failed to login to Vault with %s: %w", c.config.Auth, err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return fmt.Errorf("failed to login to Vault with %s: no token issued", c.config.Auth)
+	}
+	c.ttl = time.Duration(resp.Auth.LeaseDuration) * time.Second
+	c.setToken(resp.Auth)
+	return nil
+}
+
+// setToken records a token and schedules its renewal at two thirds of its
+// TTL
+func (c *client) setToken(auth *authInfo) {
+	c.token = auth.ClientToken
+	c.renewable = auth.Renewable
+	c.expiresAt, c.renewAt = time.Time{}, time.Time{}
+	if auth.LeaseDuration > 0 {
+		ttl := time.Duration(auth.LeaseDuration) * time.Second
+		now := time.Now()
+		c.expiresAt = now.Add(ttl)
+		c.renewAt = now.Add(ttl * 2 / 3)
+	}
+
+	c.renewOnce.Do(func() { go c.renewLoop() })
+	select {
+	case c.renewed <- struct{}{}:
+	default:
+	}
+}
+
+// renewLoop renews the token when it is due until the client is closed
+func (c *client) renewLoop() {
+	for {
+		c.mu.Lock()
+		renewAt := c.renewAt
+		c.mu.Unlock()
+
+		// Without a renewal due, wait for the next token
+		timer := time.NewTimer(time.Until(renewAt))
+		due := timer.C
+		if renewAt.IsZero() {
+			due = nil
+		}
+
+		select {
+		case <-c.closeCtx.Done():
+			timer.Stop()
+			return
+		case <-c.renewed:
+		case <-due:
+			c.renew(c.closeCtx)
+		}
+		timer.Stop()
+	}
+}
+
+// renew renews a renewable token. Tokens near their maximum TTL, whose
+// renewal is capped below a third of the login TTL, and tokens that cannot be
+// renewed are replaced by logging in again.
+func (c *client) renew(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.renewable && c.token != "" {
+		resp, err := c.send(ctx, http.MethodPost, renewSelfPath, c.token, map[string]any{})
+		if err == nil && resp.Auth != nil {
+			ttl := time.Duration(resp.Auth.LeaseDuration) * time.Second
+			if c.config.Auth == AuthToken || ttl >= c.ttl/3 {
+				resp.Auth.ClientToken = c.token
+				c.setToken(resp.Auth)
+				return
+			}
+		}
+	}
+
+	if c.config.Auth != AuthToken {
+		if err := c.login(ctx); err == nil {
+			return
+		}
+	}
+
+	// Retry until the token expires, after which requests log in again
+	c.renewAt = time.Now().Add(c.config.RetryDelay)
+	if !c.expiresAt.IsZero() && c.renewAt.After(c.expiresAt) {
+		c.renewAt = time.Time{}
+	}
+}
+
+// renewLease renews the lease of a secret and returns its new duration
+func (c *client) renewLease(ctx context.Context, leaseID string) (time.Duration, error) {
+	resp, err := c.do(ctx, http.MethodPut, leaseRenewPath, map[string]string{"lease_id": leaseID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	return time.Duration(resp.LeaseDuration) * time.Second, nil
+}
@@ -0,0 +1,539 @@
+// Package vault provides a configuration reader implementation for
+// HashiCorp Vault KV secrets engines, and resolves ${vault:path#field}
+// references in the values of other sources.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader"
+)
+
+const (
+	// SchemeVault represents Vault URI scheme
+	SchemeVault reader.Scheme = "vault"
+
+	// DefaultPort is the Vault API port
+	DefaultPort = "8200"
+
+	// AuthToken authenticates with a token from the URI or the environment
+	AuthToken = "token"
+	// AuthAppRole logs in with a role_id and secret_id
+	AuthAppRole = "approle"
+	// AuthKubernetes logs in with the service account token of the pod
+	AuthKubernetes = "kubernetes"
+
+	// DefaultJWTFile is the service account token used by Kubernetes auth
+	DefaultJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	// EnvAddress, EnvToken and EnvNamespace name the environment variables
+	// used when the URI sets no address, token or namespace, as in the Vault
+	// CLI
+	EnvAddress   = "VAULT_ADDR"
+	EnvToken     = "VAULT_TOKEN"
+	EnvNamespace = "VAULT_NAMESPACE"
+)
+
+var (
+	// DefaultTimeout for Vault requests
+	DefaultTimeout = 10 * time.Second
+	// DefaultPollInterval between checks of the secret version
+	DefaultPollInterval = 30 * time.Second
+	// DefaultRetryDelay before retrying a failed token renewal
+	DefaultRetryDelay = 5 * time.Second
+)
+
+// init registers the Vault reader
+func init() {
+	_ = reader.RegisterReader(SchemeVault, func(uri string) (reader.ConfReader, error) {
+		return NewVaultReader(uri)
+	})
+}
+
+// VaultConfig holds Vault reader configuration
+type VaultConfig struct {
+	Address      string // Base URL of the API, e.g. https://vault:8200
+	Path         string // API path of the secret, e.g. secret/data/app
+	Field        string // Field of the secret to read as a document
+	KVVersion    int
+	Namespace    string
+	Auth         string
+	AuthMount    string
+	Token        string
+	TokenFile    string // File holding the token, read on every request
+	RoleID       string
+	SecretID     string
+	SecretIDFile string
+	Role         string
+	JWTFile      string
+	TLSInsecure  bool
+	Timeout      time.Duration
+	PollInterval time.Duration
+	RetryDelay   time.Duration
+}
+
+// VaultReader implements ConfReader for Vault secrets
+type VaultReader struct {
+	uri    string
+	config *VaultConfig
+	client *client
+	mu     sync.RWMutex
+	closed bool
+}
+
+// secret is a secret read from a KV secrets engine
+type secret struct {
+	values        map[string]any
+	version       int
+	leaseID       string
+	leaseDuration time.Duration
+	renewable     bool
+}
+
+// NewVaultReader creates a new Vault reader.
+// URI format: vault://host:port/{mount}/data/{path}[#field]?auth=approle&role_id=...&secret_id_file=...
+// The path is the API path of the secret: KV v2 paths contain the data
+// segment, other paths are read as KV v1 unless kv_version is set. Without a
+// field, all fields of the secret are read as values.
+func NewVaultReader(uri string) (*VaultReader, error) {
+	u, err := reader.ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != string(SchemeVault) {
+		return nil, fmt.Errorf("unsupported scheme: %s, expected: %s", u.Scheme, SchemeVault)
+	}
+
+	config, err := parseVaultURI(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Vault URI: %w", err)
+	}
+
+	return &VaultReader{
+		uri:    uri,
+		config: config,
+		client: newClient(config),
+	}, nil
+}
+
+func parseVaultURI(u *url.URL) (*VaultConfig, error) {
+	query := u.Query()
+	config, err := parseClientConfig(u.Host, query)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Path = strings.Trim(u.Path, "/")
+	if config.Path == "" {
+		return nil, fmt.Errorf("secret path must be specified in path")
+	}
+	config.Field = u.Fragment
+
+	config.KVVersion = kvVersion(config.Path)
+	if versionStr := query.Get("kv_version"); versionStr != "" {
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || (version != 1 && version != 2) {
+			return nil, fmt.Errorf("invalid kv_version: %s, expected: 1 or 2", versionStr)
+		}
+		config.KVVersion = version
+	}
+	if config.KVVersion == 2 && metadataPath(config.Path) == "" {
+		return nil, fmt.Errorf("KV v2 path must contain the data segment, e.g. secret/data/app")
+	}
+
+	return config, nil
+}
+
+// parseClientConfig parses the address, namespace, authentication and
+// timeouts of a Vault URI. Without a host, the address is taken from
+// VAULT_ADDR.
+func parseClientConfig(host string, query url.Values) (*VaultConfig, error) {
+	config := &VaultConfig{
+		Namespace:    query.Get("namespace"),
+		Auth:         query.Get("auth"),
+		AuthMount:    query.Get("auth_mount"),
+		Token:        query.Get("token"),
+		TokenFile:    query.Get("token_file"),
+		RoleID:       query.Get("role_id"),
+		SecretID:     query.Get("secret_id"),
+		SecretIDFile: query.Get("secret_id_file"),
+		Role:         query.Get("role"),
+		JWTFile:      query.Get("jwt_file"),
+		TLSInsecure:  query.Get("tls_insecure") == "true",
+	}
+
+	if host != "" {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, DefaultPort)
+		}
+		serverScheme := query.Get("server_scheme")
+		if serverScheme == "" {
+			serverScheme = "https"
+		}
+		if serverScheme != "http" && serverScheme != "https" {
+			return nil, fmt.Errorf("unsupported server_scheme: %s", serverScheme)
+		}
+		config.Address = serverScheme + "://" + host
+	} else if config.Address = strings.TrimSuffix(os.Getenv(EnvAddress), "/"); config.Address == "" {
+		return nil, fmt.Errorf("vault address must be specified in host or %s", EnvAddress)
+	}
+
+	if config.Namespace == "" {
+		config.Namespace = os.Getenv(EnvNamespace)
+	}
+
+	switch config.Auth {
+	case "", AuthToken:
+		config.Auth = AuthToken
+		if config.Token == "" && config.TokenFile == "" {
+			config.Token = os.Getenv(EnvToken)
+		}
+	case AuthAppRole:
+		if config.RoleID == "" {
+			return nil, fmt.Errorf("role_id is required for approle auth")
+		}
+		if config.SecretID == "" && config.SecretIDFile == "" {
+			return nil, fmt.Errorf("secret_id or secret_id_file is required for approle auth")
+		}
+	case AuthKubernetes:
+		if config.Role == "" {
+			return nil, fmt.Errorf("role is required for kubernetes auth")
+		}
+		if config.JWTFile == "" {
+			config.JWTFile = DefaultJWTFile
+		}
+	default:
+		return nil, fmt.Errorf("unsupported auth method: %s, expected: %s, %s or %s", config.Auth, AuthToken, AuthAppRole, AuthKubernetes)
+	}
+	if config.AuthMount == "" {
+		config.AuthMount = config.Auth
+	}
+
+	var err error
+	if config.Timeout, err = reader.ParseDuration(query, "timeout", DefaultTimeout); err != nil {
+		return nil, err
+	}
+	if config.PollInterval, err = reader.ParseDuration(query, "poll_interval", DefaultPollInterval); err != nil {
+		return nil, err
+	}
+	if config.RetryDelay, err = reader.ParseDuration(query, "retry_delay", DefaultRetryDelay); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// kvVersion detects the KV version of a path: KV v2 paths have a data
+// segment after the mount
+func kvVersion(path string) int {
+	if metadataPath(path) != "" {
+		return 2
+	}
+	return 1
+}
+
+// metadataPath returns the metadata path of a KV v2 data path, e.g.
+// secret/metadata/app for secret/data/app, or "" without a data segment
+func metadataPath(path string) string {
+	mount, rest, found := strings.Cut(path, "/data/")
+	if !found || mount == "" || rest == "" {
+		return ""
+	}
+	return mount + "/metadata/" + rest
+}
+
+// Read reads configuration data from Vault
+func (v *VaultReader) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := v.ReadMeta(ctx)
+	return data, err
+}
+
+// ReadMeta reads the field and reports its extension, or the JSON encoding
+// of the whole secret
+func (v *VaultReader) ReadMeta(ctx context.Context) ([]byte, reader.ContentMeta, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.closed {
+		return nil, reader.ContentMeta{}, fmt.Errorf("reader is closed")
+	}
+
+	s, err := v.client.readSecret(ctx, v.config.Path, v.config.KVVersion)
+	if err != nil {
+		return nil, reader.ContentMeta{}, err
+	}
+	content := v.content(s)
+	return content.data, content.meta, content.err
+}
+
+// Structured implements reader.MapReader. Without a field, the fields of the
+// secret are read as values.
+func (v *VaultReader) Structured() bool {
+	return v.config.Field == ""
+}
+
+// ReadMap implements reader.MapReader
+func (v *VaultReader) ReadMap(ctx context.Context) (map[string]any, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	s, err := v.client.readSecret(ctx, v.config.Path, v.config.KVVersion)
+	if err != nil {
+		return nil, err
+	}
+	content := v.content(s)
+	return content.values, content.err
+}
+
+// Subscribe reads the secret and then polls it every poll_interval. KV v2
+// secrets are read again only when the current version in their metadata
+// changes. Renewable leases are renewed in the background, and secrets whose
+// lease cannot be renewed are read again before it expires.
+func (v *VaultReader) Subscribe(ctx context.Context) (<-chan *reader.ReadEvent, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.closed {
+		return nil, fmt.Errorf("reader is closed")
+	}
+
+	s, err := v.client.readSecret(ctx, v.config.Path, v.config.KVVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	eventChan := make(chan *reader.ReadEvent, 1)
+	ctx, cancel := reader.WithClose(ctx, v.client.closeCtx)
+	go func() {
+		defer cancel()
+		v.watch(ctx, eventChan, s)
+	}()
+
+	return eventChan, nil
+}
+
+// Close closes the reader and cleans up resources
+func (v *VaultReader) Close() error {
+	if v == nil {
+		return nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.closed {
+		return nil
+	}
+
+	v.closed = true
+	v.client.close()
+	return nil
+}
+
+// watch reports the current secret, then polls its version and renews its
+// lease, reporting the secret whenever it changes
+func (v *VaultReader) watch(ctx context.Context, eventChan chan<- *reader.ReadEvent, s *secret) {
+	defer close(eventChan)
+
+	reporter := reader.NewReporter(eventChan)
+	reporter.Report(ctx, v.content(s).event(v.uri))
+
+	version := s.version
+	leaseID, leaseRenewAt := s.leaseID, leaseRenewal(s.leaseDuration)
+	renewable := s.renewable
+	for {
+		wait := v.config.PollInterval
+		leaseDue := leaseID != "" && time.Until(leaseRenewAt) < wait
+		if leaseDue {
+			// A renewal that is already past due is retried after the retry
+			// delay, so a failing Vault is not polled in a tight loop
+			wait = time.Until(leaseRenewAt)
+			if wait <= 0 {
+				wait = v.config.RetryDelay
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if leaseDue && renewable {
+			if duration, err := v.client.renewLease(ctx, leaseID); err == nil && duration > 0 {
+				leaseRenewAt = leaseRenewal(duration)
+				continue
+			}
+		}
+
+		// Compare the current version first, which needs no access to the
+		// secret data. Without access to the metadata, the data is read.
+		if !leaseDue && v.config.KVVersion == 2 {
+			current, err := v.client.currentVersion(ctx, metadataPath(v.config.Path))
+			if err == nil && current == version {
+				continue
+			}
+		}
+
+		next, err := v.client.readSecret(ctx, v.config.Path, v.config.KVVersion)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if leaseDue {
+				leaseRenewAt = time.Now().Add(v.config.RetryDelay)
+			}
+			reporter.Report(ctx, content{err: err}.event(v.uri))
+			continue
+		}
+		version = next.version
+		leaseID, leaseRenewAt, renewable = next.leaseID, leaseRenewal(next.leaseDuration), next.renewable
+		reporter.Report(ctx, v.content(next).event(v.uri))
+	}
+}
+
+// leaseRenewal returns when a lease of duration is renewed, at two thirds of
+// its duration
+func leaseRenewal(duration time.Duration) time.Time {
+	return time.Now().Add(duration * 2 / 3)
+}
+
+// readSecret reads a KV secret
+func (c *client) readSecret(ctx context.Context, path string, kvVersion int) (*secret, error) {
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if hasStatus(err, http.StatusNotFound) {
+		return nil, fmt.Errorf("secret '%s' not found", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret '%s': %w", path, err)
+	}
+
+	s := &secret{
+		leaseID:       resp.LeaseID,
+		leaseDuration: time.Duration(resp.LeaseDuration) * time.Second,
+		renewable:     resp.Renewable,
+	}
+	if kvVersion == 1 {
+		if err := json.Unmarshal(resp.Data, &s.values); err != nil {
+			return nil, fmt.Errorf("failed to decode secret '%s': %w", path, err)
+		}
+		return s, nil
+	}
+
+	var data struct {
+		Data     map[string]any `json:"data"`
+		Metadata struct {
+			Version int `json:"version"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode secret '%s': %w", path, err)
+	}
+	// Deleted versions have no data
+	if data.Data == nil {
+		return nil, fmt.Errorf("secret '%s' is deleted", path)
+	}
+	s.values, s.version = data.Data, data.Metadata.Version
+	return s, nil
+}
+
+// currentVersion reads the current version of a KV v2 secret from its
+// metadata
+func (c *client) currentVersion(ctx context.Context, path string) (int, error) {
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read metadata '%s': %w", path, err)
+	}
+	var data struct {
+		CurrentVersion int `json:"current_version"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return 0, fmt.Errorf("failed to decode metadata '%s': %w", path, err)
+	}
+	return data.CurrentVersion, nil
+}
+
+// content is the configuration read from a secret
+type content struct {
+	data     []byte
+	meta     reader.ContentMeta
+	values   map[string]any
+	revision string
+	err      error
+}
+
+// content returns the field of the secret, or all of its fields as values.
+// The revision is the version of KV v2 secrets.
+func (v *VaultReader) content(s *secret) content {
+	var revision string
+	if s.version > 0 {
+		revision = strconv.Itoa(s.version)
+	}
+
+	if v.config.Field != "" {
+		value, err := fieldValue(s, v.config.Path, v.config.Field)
+		if err != nil {
+			return content{err: err}
+		}
+		return content{
+			data:     []byte(value),
+			meta:     reader.ContentMeta{Extension: filepath.Ext(v.config.Field)},
+			revision: revision,
+		}
+	}
+
+	data, err := json.Marshal(s.values)
+	if err != nil {
+		return content{err: fmt.Errorf("failed to encode values: %w", err)}
+	}
+	return content{
+		data:     data,
+		meta:     reader.ContentMeta{MIMEType: "application/json", Extension: ".json"},
+		values:   s.values,
+		revision: revision,
+	}
+}
+
+// fieldValue returns a field of a secret, JSON encoding values that are not
+// strings
+func fieldValue(s *secret, path, field string) (string, error) {
+	value, ok := s.values[field]
+	if !ok {
+		return "", fmt.Errorf("field '%s' not found in secret '%s'", field, path)
+	}
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode field '%s' of secret '%s': %w", field, path, err)
+	}
+	return string(data), nil
+}
+
+// event builds a read event of the content
+func (c content) event(uri string) *reader.ReadEvent {
+	if c.err != nil {
+		return reader.NewReadEvent(uri, nil, c.err)
+	}
+	return reader.NewReadEvent(uri, c.data, nil).
+		WithMeta(c.meta).
+		WithValues(c.values).
+		WithRevision(c.revision)
+}
@@ -0,0 +1,553 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sower-proxy/feconf/reader/internal/readertest"
+)
+
+// fakeVault is a stand-in for the Vault API with a KV v2 engine at secret/,
+// a KV v1 engine at kv/ and dynamic secrets at database/creds/
+type fakeVault struct {
+	*httptest.Server
+	mu sync.Mutex
+	// tokens maps valid tokens to their TTL in seconds
+	tokens   map[string]int64
+	issued   int
+	kv2      map[string][]map[string]any
+	kv1      map[string]map[string]any
+	lease    fakeLease
+	requests []string
+	logins   []map[string]string
+}
+
+// fakeLease is the lease of database/creds/app
+type fakeLease struct {
+	duration  int64
+	renewable bool
+	username  string
+	failing   bool
+}
+
+func newFakeVault(t *testing.T) *fakeVault {
+	t.Helper()
+
+	f := &fakeVault{
+		tokens: map[string]int64{"root": 0},
+		kv2:    make(map[string][]map[string]any),
+		kv1:    make(map[string]map[string]any),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeVault) uri(path string) string {
+	return "vault://" + strings.TrimPrefix(f.URL, "http://") + path
+}
+
+// put writes a new version of a KV v2 secret, or deletes the current one
+func (f *fakeVault) put(path string, data map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.kv2[path] = append(f.kv2[path], data)
+}
+
+func (f *fakeVault) count(prefix string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for _, request := range f.requests {
+		if strings.HasPrefix(request, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeVault) respond(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeVault) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	f.requests = append(f.requests, r.Method+" "+path)
+
+	if strings.HasSuffix(path, "/login") {
+		var login map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&login)
+		login["mount"] = strings.TrimSuffix(strings.TrimPrefix(path, "auth/"), "/login")
+		f.logins = append(f.logins, login)
+		if login["secret_id"] == "wrong" {
+			f.respond(w, http.StatusBadRequest, map[string]any{"errors": []string{"invalid secret id"}})
+			return
+		}
+		f.issued++
+		token := fmt.Sprintf("token-%d", f.issued)
+		f.tokens[token] = 1
+		f.respond(w, http.StatusOK, map[string]any{
+			"auth": map[string]any{"client_token": token, "lease_duration": 1, "renewable": true},
+		})
+		return
+	}
+
+	token := r.Header.Get(tokenHeader)
+	ttl, ok := f.tokens[token]
+	if !ok {
+		f.respond(w, http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+	if namespace := r.Header.Get(namespaceHeader); namespace != "" && namespace != "team" {
+		f.respond(w, http.StatusForbidden, map[string]any{"errors": []string{"unknown namespace"}})
+		return
+	}
+
+	switch {
+	case path == renewSelfPath:
+		f.respond(w, http.StatusOK, map[string]any{
+			"auth": map[string]any{"client_token": token, "lease_duration": ttl, "renewable": true},
+		})
+	case path == lookupSelfPath:
+		f.respond(w, http.StatusOK, map[string]any{"data": map[string]any{"ttl": ttl, "renewable": ttl > 0}})
+	case path == leaseRenewPath:
+		if !f.lease.renewable {
+			f.respond(w, http.StatusBadRequest, map[string]any{"errors": []string{"lease is not renewable"}})
+			return
+		}
+		f.respond(w, http.StatusOK, map[string]any{"lease_id": "database/creds/app/1", "lease_duration": f.lease.duration, "renewable": true})
+	case path == "database/creds/app" && f.lease.failing:
+		f.respond(w, http.StatusInternalServerError, map[string]any{"errors": []string{"internal error"}})
+	case path == "database/creds/app":
+		f.respond(w, http.StatusOK, map[string]any{
+			"lease_id":       "database/creds/app/1",
+			"lease_duration": f.lease.duration,
+			"renewable":      f.lease.renewable,
+			"data":           map[string]any{"username": f.lease.username, "password": "secret"},
+		})
+	case strings.HasPrefix(path, "secret/metadata/"):
+		versions, ok := f.kv2[strings.TrimPrefix(path, "secret/metadata/")]
+		if !ok {
+			f.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+		f.respond(w, http.StatusOK, map[string]any{"data": map[string]any{"current_version": len(versions)}})
+	case strings.HasPrefix(path, "secret/data/"):
+		versions, ok := f.kv2[strings.TrimPrefix(path, "secret/data/")]
+		if !ok {
+			f.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+		version := len(versions)
+		data := versions[version-1]
+		status := http.StatusOK
+		if data == nil {
+			status = http.StatusNotFound
+		}
+		f.respond(w, status, map[string]any{"data": map[string]any{
+			"data":     data,
+			"metadata": map[string]any{"version": version},
+		}})
+	case strings.HasPrefix(path, "kv/"):
+		data, ok := f.kv1[strings.TrimPrefix(path, "kv/")]
+		if !ok {
+			f.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+		f.respond(w, http.StatusOK, map[string]any{"lease_duration": 2764800, "data": data})
+	default:
+		f.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+	}
+}
+
+func eventually(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestParseVaultURI(t *testing.T) {
+	tests := []struct {
+		name      string
+		uri       string
+		env       map[string]string
+		check     func(t *testing.T, config *VaultConfig)
+		wantError bool
+	}{
+		{
+			name: "kv v2 with default port and token from environment",
+			uri:  "vault://vault/secret/data/app#config.yaml",
+			env:  map[string]string{EnvToken: "env-token"},
+			check: func(t *testing.T, config *VaultConfig) {
+				want := VaultConfig{Address: "https://vault:8200", Path: "secret/data/app", Field: "config.yaml", KVVersion: 2, Auth: AuthToken, Token: "env-token"}
+				got := VaultConfig{Address: config.Address, Path: config.Path, Field: config.Field, KVVersion: config.KVVersion, Auth: config.Auth, Token: config.Token}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("config = %+v, want %+v", got, want)
+				}
+			},
+		},
+		{
+			name: "kv v1 with address from environment",
+			uri:  "vault:///kv/app?namespace=team",
+			env:  map[string]string{EnvAddress: "http://127.0.0.1:8200/"},
+			check: func(t *testing.T, config *VaultConfig) {
+				if config.Address != "http://127.0.0.1:8200" || config.KVVersion != 1 || config.Namespace != "team" {
+					t.Errorf("config = %+v", config)
+				}
+			},
+		},
+		{
+			name: "approle with custom mount",
+			uri:  "vault://vault:8200/secret/data/app?auth=approle&auth_mount=ci&role_id=role&secret_id_file=/run/secret-id",
+			check: func(t *testing.T, config *VaultConfig) {
+				if config.Auth != AuthAppRole || config.AuthMount != "ci" || config.RoleID != "role" || config.SecretIDFile != "/run/secret-id" {
+					t.Errorf("config = %+v", config)
+				}
+			},
+		},
+		{
+			name: "kubernetes with default service account token",
+			uri:  "vault://vault:8200/secret/data/app?auth=kubernetes&role=app",
+			check: func(t *testing.T, config *VaultConfig) {
+				if config.AuthMount != AuthKubernetes || config.JWTFile != DefaultJWTFile {
+					t.Errorf("config = %+v", config)
+				}
+			},
+		},
+		{
+			name:      "missing address",
+			uri:       "vault:///secret/data/app",
+			wantError: true,
+		},
+		{
+			name:      "missing path",
+			uri:       "vault://vault:8200/",
+			wantError: true,
+		},
+		{
+			name:      "kv v2 without data segment",
+			uri:       "vault://vault:8200/secret/app?kv_version=2",
+			wantError: true,
+		},
+		{
+			name:      "approle without secret id",
+			uri:       "vault://vault:8200/secret/data/app?auth=approle&role_id=role",
+			wantError: true,
+		},
+		{
+			name:      "kubernetes without role",
+			uri:       "vault://vault:8200/secret/data/app?auth=kubernetes",
+			wantError: true,
+		},
+		{
+			name:      "unsupported auth",
+			uri:       "vault://vault:8200/secret/data/app?auth=ldap",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{EnvAddress, EnvToken, EnvNamespace} {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			r, err := NewVaultReader(tt.uri)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("NewVaultReader() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			defer r.Close()
+			tt.check(t, r.config)
+		})
+	}
+}
+
+func TestVaultReaderReadKV2(t *testing.T) {
+	server := newFakeVault(t)
+	server.put("app", map[string]any{"name": "app", "config.yaml": "port: 8080\n"})
+
+	r, err := NewVaultReader(server.uri("/secret/data/app?server_scheme=http&token=root&namespace=team"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if !r.Structured() {
+		t.Error("Expected reader without field to be structured")
+	}
+	values, err := r.ReadMap(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read values: %v", err)
+	}
+	if values["name"] != "app" {
+		t.Errorf("Expected name app, got %v", values)
+	}
+
+	field, err := NewVaultReader(server.uri("/secret/data/app?server_scheme=http&token=root#config.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer field.Close()
+
+	data, meta, err := field.ReadMeta(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read field: %v", err)
+	}
+	if string(data) != "port: 8080\n" || meta.Extension != ".yaml" {
+		t.Errorf("ReadMeta() = %q, %+v", data, meta)
+	}
+
+	missing, err := NewVaultReader(server.uri("/secret/data/app?server_scheme=http&token=root#missing"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer missing.Close()
+	if _, err := missing.Read(context.Background()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected field not found error, got %v", err)
+	}
+
+	denied, err := NewVaultReader(server.uri("/secret/data/app?server_scheme=http&token=wrong"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer denied.Close()
+	if _, err := denied.Read(context.Background()); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected permission denied error, got %v", err)
+	}
+}
+
+func TestVaultReaderReadKV1(t *testing.T) {
+	server := newFakeVault(t)
+	server.kv1["app"] = map[string]any{"name": "app", "replicas": 3}
+
+	r, err := NewVaultReader(server.uri("/kv/app?server_scheme=http&token=root#replicas"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	data, err := r.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != "3" {
+		t.Errorf("Expected JSON encoded field 3, got %s", data)
+	}
+}
+
+func TestVaultReaderSubscribe(t *testing.T) {
+	server := newFakeVault(t)
+	server.put("app", map[string]any{"name": "v1"})
+
+	r, err := NewVaultReader(server.uri("/secret/data/app?server_scheme=http&token=root&poll_interval=50ms"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	events, err := r.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if event := readertest.NextEvent(t, events); event.Revision != "1" || event.Values["name"] != "v1" {
+		t.Errorf("Expected version 1, got %s: %v", event.Revision, event.Values)
+	}
+
+	// Unchanged versions are detected from the metadata alone
+	eventually(t, func() bool { return server.count("GET secret/metadata/app") >= 3 }, "Expected metadata polls")
+	if reads := server.count("GET secret/data/app"); reads != 1 {
+		t.Errorf("Expected 1 data read before a new version, got %d", reads)
+	}
+
+	server.put("app", map[string]any{"name": "v2"})
+	if event := readertest.NextEvent(t, events); event.Revision != "2" || event.Values["name"] != "v2" {
+		t.Errorf("Expected version 2, got %s: %v", event.Revision, event.Values)
+	}
+
+	server.put("app", nil)
+	if event := readertest.NextEvent(t, events); event.Error == nil || !strings.Contains(event.Error.Error(), "not found") {
+		t.Errorf("Expected error after the secret was deleted, got %v", event.Error)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Failed to close reader: %v", err)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected event channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Timeout waiting for event channel to close")
+	}
+}
+
+func TestVaultReaderAppRole(t *testing.T) {
+	server := newFakeVault(t)
+	server.put("app", map[string]any{"name": "app"})
+
+	secretIDFile := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(secretIDFile, []byte("secret-id\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret_id file: %v", err)
+	}
+
+	r, err := NewVaultReader(server.uri("/secret/data/app?server_scheme=http&auth=approle&role_id=role&secret_id_file=" + secretIDFile))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := r.ReadMap(context.Background()); err != nil {
+		t.Fatalf("Failed to read values: %v", err)
+	}
+	server.mu.Lock()
+	login := server.logins[0]
+	server.mu.Unlock()
+	if login["mount"] != "approle" || login["role_id"] != "role" || login["secret_id"] != "secret-id" {
+		t.Errorf("Unexpected login %v", login)
+	}
+
+	// The token of one second is renewed in the background
+	eventually(t, func() bool { return server.count("POST "+renewSelfPath) > 0 }, "Expected token renewal")
+
+	// A revoked token is replaced by logging in again
+	server.mu.Lock()
+	for token := range server.tokens {
+		if token != "root" {
+			delete(server.tokens, token)
+		}
+	}
+	server.mu.Unlock()
+	if _, err := r.ReadMap(context.Background()); err != nil {
+		t.Fatalf("Failed to read values after the token was revoked: %v", err)
+	}
+	if logins := server.count("POST auth/approle/login"); logins < 2 {
+		t.Errorf("Expected a second login, got %d", logins)
+	}
+
+	wrong, err := NewVaultReader(server.uri("/secret/data/app?server_scheme=http&auth=approle&role_id=role&secret_id=wrong"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer wrong.Close()
+	if _, err := wrong.Read(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid secret id") {
+		t.Errorf("Expected login error, got %v", err)
+	}
+}
+
+func TestVaultReaderKubernetes(t *testing.T) {
+	server := newFakeVault(t)
+	server.put("app", map[string]any{"name": "app"})
+
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtFile, []byte("service-account-jwt"), 0o600); err != nil {
+		t.Fatalf("Failed to write service account token: %v", err)
+	}
+
+	r, err := NewVaultReader(server.uri("/secret/data/app?server_scheme=http&auth=kubernetes&role=app&jwt_file=" + jwtFile))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := r.ReadMap(context.Background()); err != nil {
+		t.Fatalf("Failed to read values: %v", err)
+	}
+	server.mu.Lock()
+	login := server.logins[0]
+	server.mu.Unlock()
+	if login["mount"] != "kubernetes" || login["role"] != "app" || login["jwt"] != "service-account-jwt" {
+		t.Errorf("Unexpected login %v", login)
+	}
+}
+
+func TestVaultReaderLease(t *testing.T) {
+	server := newFakeVault(t)
+	server.lease = fakeLease{duration: 1, renewable: true, username: "user-1"}
+
+	r, err := NewVaultReader(server.uri("/database/creds/app?server_scheme=http&token=root"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	events, err := r.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	readertest.NextEvent(t, events)
+
+	// Renewable leases are renewed before they expire
+	eventually(t, func() bool { return server.count("PUT "+leaseRenewPath) > 0 }, "Expected lease renewal")
+
+	// Credentials whose lease cannot be renewed are read again
+	server.mu.Lock()
+	server.lease = fakeLease{duration: 1, renewable: false, username: "user-2"}
+	server.mu.Unlock()
+	for {
+		event := readertest.NextEvent(t, events)
+		if event.Error == nil && event.Values["username"] == "user-2" {
+			break
+		}
+	}
+}
+
+func TestVaultReaderLeaseReadError(t *testing.T) {
+	server := newFakeVault(t)
+	server.lease = fakeLease{duration: 1, renewable: false, username: "user-1"}
+
+	r, err := NewVaultReader(server.uri("/database/creds/app?server_scheme=http&token=root&retry_delay=100ms"))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	events, err := r.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	readertest.NextEvent(t, events)
+
+	server.mu.Lock()
+	server.lease.failing = true
+	server.mu.Unlock()
+
+	if event := readertest.NextEvent(t, events); event.Error == nil {
+		t.Fatal("Expected error event")
+	}
+
+	// Reads of a lease that is past due are retried after the retry delay
+	reads := server.count("GET database/creds/app")
+	time.Sleep(time.Second)
+	if n := server.count("GET database/creds/app") - reads; n > 15 {
+		t.Errorf("Expected reads to be retried after the retry delay, got %d reads in 1s", n)
+	}
+}
@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/sower-proxy/feconf/reader"
+)
+
+// ReferenceName is the name of ${vault:path#field} references
+const ReferenceName = "vault"
+
+var (
+	referenceMu     sync.Mutex
+	referenceClient *client
+)
+
+// init registers the resolver of Vault references
+func init() {
+	_ = reader.RegisterResolver(ReferenceName, resolveReference)
+}
+
+// SetReferenceURI configures the server and authentication used to resolve
+// ${vault:path#field} references, e.g.
+// vault://vault:8200?auth=kubernetes&role=app. Any path of the URI is
+// ignored. By default VAULT_ADDR, VAULT_NAMESPACE and VAULT_TOKEN are used.
+func SetReferenceURI(uri string) error {
+	u, err := reader.ParseURI(uri)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != string(SchemeVault) {
+		return fmt.Errorf("unsupported scheme: %s, expected: %s", u.Scheme, SchemeVault)
+	}
+
+	config, err := parseClientConfig(u.Host, u.Query())
+	if err != nil {
+		return fmt.Errorf("failed to parse Vault URI: %w", err)
+	}
+
+	referenceMu.Lock()
+	defer referenceMu.Unlock()
+
+	if referenceClient != nil {
+		referenceClient.close()
+	}
+	referenceClient = newClient(config)
+	return nil
+}
+
+// resolveReference resolves a path#field reference to the field of the
+// secret at the API path, e.g. secret/data/db#password
+func resolveReference(ctx context.Context, ref string) (string, error) {
+	path, field, found := strings.Cut(ref, "#")
+	path = strings.Trim(path, "/")
+	if !found || path == "" || field == "" {
+		return "", fmt.Errorf("invalid Vault reference %q, expected: path#field", ref)
+	}
+
+	c, err := defaultReferenceClient()
+	if err != nil {
+		return "", err
+	}
+	s, err := c.readSecret(ctx, path, kvVersion(path))
+	if err != nil {
+		return "", err
+	}
+	return fieldValue(s, path, field)
+}
+
+// defaultReferenceClient returns the client configured by SetReferenceURI,
+// or creates one from the environment
+func defaultReferenceClient() (*client, error) {
+	referenceMu.Lock()
+	defer referenceMu.Unlock()
+
+	if referenceClient == nil {
+		config, err := parseClientConfig("", url.Values{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure Vault references: %w", err)
+		}
+		referenceClient = newClient(config)
+	}
+	return referenceClient, nil
+}
@@ -0,0 +1,75 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sower-proxy/feconf"
+	_ "github.com/sower-proxy/feconf/decoder/yaml"
+	_ "github.com/sower-proxy/feconf/reader/file"
+)
+
+func TestResolveReference(t *testing.T) {
+	server := newFakeVault(t)
+	server.put("db", map[string]any{"username": "app", "password": "s3cret", "port": 5432})
+
+	t.Cleanup(func() {
+		referenceMu.Lock()
+		defer referenceMu.Unlock()
+		if referenceClient != nil {
+			referenceClient.close()
+			referenceClient = nil
+		}
+	})
+	if err := SetReferenceURI(server.uri("?server_scheme=http&token=root")); err != nil {
+		t.Fatalf("Failed to configure references: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+database:
+  dsn: postgres://${vault:secret/data/db#username}:${vault:secret/data/db#password}@db:5432/app
+  port: ${vault:secret/data/db#port}
+  literal: $${vault:secret/data/db#password}
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	type Config struct {
+		Database struct {
+			DSN     string `json:"dsn"`
+			Port    int    `json:"port"`
+			Literal string `json:"literal"`
+		} `json:"database"`
+	}
+	var config Config
+	if err := feconf.Load(&config, "file://"+path); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Database.DSN != "postgres://app:s3cret@db:5432/app" {
+		t.Errorf("Expected resolved DSN, got %s", config.Database.DSN)
+	}
+	if config.Database.Port != 5432 {
+		t.Errorf("Expected port 5432, got %d", config.Database.Port)
+	}
+	if config.Database.Literal != "${vault:secret/data/db#password}" {
+		t.Errorf("Expected escaped reference to be kept, got %s", config.Database.Literal)
+	}
+
+	if err := os.WriteFile(path, []byte("password: ${vault:secret/data/db#missing}\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	var missing struct {
+		Password string `json:"password"`
+	}
+	if err := feconf.Load(&missing, "file://"+path); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected error for missing field, got %v", err)
+	}
+
+	if _, err := resolveReference(t.Context(), "secret/data/db"); err == nil {
+		t.Error("Expected error for reference without field")
+	}
+}